@@ -0,0 +1,686 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/alerts"
+	"github.com/shindakun/bskyarchive/internal/archiver"
+	"github.com/shindakun/bskyarchive/internal/auth"
+	"github.com/shindakun/bskyarchive/internal/config"
+	"github.com/shindakun/bskyarchive/internal/exporter"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// Exit codes for headless commands
+const (
+	exitOK       = 0 // Success
+	exitError    = 1 // Command failed
+	exitUsage    = 2 // Invalid command or arguments
+	exitNoResult = 3 // Nothing matched (empty search or export)
+	exitProblems = 4 // verify found integrity problems
+)
+
+// commandEnv holds the dependencies shared by headless commands
+type commandEnv struct {
+	cfg            *config.Config
+	db             *sql.DB
+	worker         *archiver.Worker
+	alerts         *alerts.Notifier
+	sessionManager *auth.SessionManager
+	appPasswords   *auth.AppPasswordManager
+	stdin          io.Reader
+	stdout         io.Writer
+	stderr         io.Writer
+}
+
+// newCommandEnv builds the archiver worker with persisted OAuth and app-password sessions
+// so commands can act on behalf of accounts that signed in through the web interface or `login`
+func newCommandEnv(cfg *config.Config, db *sql.DB) (*commandEnv, error) {
+	sessionStore, err := auth.NewSQLiteSessionStore(db, cfg.OAuth.SessionSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	appPasswords, err := auth.NewAppPasswordManager(db, cfg.OAuth.SessionSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cookie settings are irrelevant here; only database session records are written
+	sessionManager := auth.InitSessions(cfg.OAuth.SessionSecret, cfg.OAuth.SessionMaxAge, false, http.SameSiteLaxMode, db)
+	sessionManager.SetAccessPolicy(auth.NewAccessPolicy(cfg.Access.Admins, cfg.Access.Allowlist))
+	oauthManager := auth.InitOAuth(cfg.GetBaseURL(), cfg.OAuth.Scopes, sessionManager, sessionStore)
+	sessions := auth.NewSessionSource(oauthManager, appPasswords)
+
+	return &commandEnv{
+		cfg:            cfg,
+		db:             db,
+		worker:         archiver.NewWorker(db, cfg.Archive.MediaPath, cfg.RateLimit.RequestsPerWindow, cfg.RateLimit.WindowDuration, sessions),
+		alerts:         alerts.New(db, cfg.Alerts, log.New(os.Stderr, "", 0)),
+		sessionManager: sessionManager,
+		appPasswords:   appPasswords,
+		stdin:          os.Stdin,
+		stdout:         os.Stdout,
+		stderr:         os.Stderr,
+	}, nil
+}
+
+// command is a headless subcommand
+type command struct {
+	name        string
+	description string
+	run         func(ctx context.Context, env *commandEnv, args []string) int
+}
+
+var commands = []command{
+	{"login", "Sign in with an app password (no browser needed)", runLogin},
+	{"sync", "Run an archive operation for signed-in accounts", runSync},
+	{"export", "Export posts to a directory", runExport},
+	{"search", "Search archived posts", runSearch},
+	{"stats", "Show archive statistics", runStats},
+	{"verify", "Check database, search index and media files", runVerify},
+	{"revoke-sessions", "Sign out every user", runRevokeSessions},
+}
+
+// runCommand dispatches a headless subcommand and returns its exit code
+func runCommand(name string, args []string, env *commandEnv) int {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return cmd.run(ctx, env, args)
+		}
+	}
+
+	fmt.Fprintf(env.stderr, "Unknown command: %s\n\n", name)
+	printUsage(env.stderr)
+	return exitUsage
+}
+
+// printUsage lists the available commands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: bskyarchive [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Without a command, bskyarchive starts the web server.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'bskyarchive <command> -h' for command flags.")
+}
+
+// newFlagSet creates a flag set that reports errors instead of exiting
+func newFlagSet(env *commandEnv, name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.stderr, "Usage: bskyarchive %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and returns an exit code if the command should stop
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// resolveDID returns the requested DID, or the only archived account if none was given
+func resolveDID(env *commandEnv, did string) (string, error) {
+	if did != "" {
+		return did, nil
+	}
+
+	dids, err := storage.ListArchivedDIDs(env.db)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(dids) {
+	case 0:
+		return "", fmt.Errorf("archive is empty; run 'bskyarchive sync' first")
+	case 1:
+		return dids[0], nil
+	default:
+		return "", fmt.Errorf("archive contains %d accounts; choose one with --did (%s)", len(dids), strings.Join(dids, ", "))
+	}
+}
+
+// progressLine prints progress updates, rewriting a single line when attached to a terminal
+type progressLine struct {
+	w   io.Writer
+	tty bool
+}
+
+func newProgressLine(w io.Writer) *progressLine {
+	p := &progressLine{w: w}
+	if f, ok := w.(*os.File); ok {
+		if info, err := f.Stat(); err == nil {
+			p.tty = info.Mode()&os.ModeCharDevice != 0
+		}
+	}
+	return p
+}
+
+// Update prints the current progress
+func (p *progressLine) Update(format string, args ...interface{}) {
+	if p.tty {
+		fmt.Fprintf(p.w, "\r\033[K"+format, args...)
+		return
+	}
+	fmt.Fprintf(p.w, format+"\n", args...)
+}
+
+// Done ends the progress line
+func (p *progressLine) Done() {
+	if p.tty {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// runLogin signs in with an app password read from BSKY_APP_PASSWORD or stdin
+func runLogin(ctx context.Context, env *commandEnv, args []string) int {
+	fs := newFlagSet(env, "login", "--handle user.bsky.social")
+	handle := fs.String("handle", "", "Bluesky handle, DID or account email")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *handle == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	password := os.Getenv("BSKY_APP_PASSWORD")
+	if password == "" {
+		fmt.Fprint(env.stderr, "App password: ")
+		line, err := bufio.NewReader(env.stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+		password = strings.TrimSpace(line)
+	}
+	if password == "" {
+		fmt.Fprintln(env.stderr, "Error: app password is required (set BSKY_APP_PASSWORD or enter it on stdin)")
+		return exitUsage
+	}
+
+	login, err := env.appPasswords.Login(ctx, *handle, password)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Sign-in failed: %v\n", err)
+		return exitError
+	}
+
+	if _, err := env.sessionManager.SaveSessionRecord(login.DID, login.Handle, login.Handle, login.SessionID); err != nil {
+		if errors.Is(err, auth.ErrLoginNotAllowed) {
+			_ = env.appPasswords.Discard(login.SessionID)
+			fmt.Fprintf(env.stderr, "Sign-in failed: %s is not on the access allowlist\n", login.Handle)
+			return exitError
+		}
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(env.stdout, "Signed in as %s (%s)\n", login.Handle, login.DID)
+	return exitOK
+}
+
+// runSync archives posts for signed-in accounts and waits for completion
+func runSync(ctx context.Context, env *commandEnv, args []string) int {
+	fs := newFlagSet(env, "sync", "[flags]")
+	did := fs.String("did", "", "Only sync this account (default: every signed-in account)")
+	opType := fs.String("type", string(models.OperationTypeIncremental), "Operation type: incremental, initial or refresh")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	operationType := models.OperationType(*opType)
+	switch operationType {
+	case models.OperationTypeIncremental, models.OperationTypeInitial, models.OperationTypeRefresh:
+	default:
+		fmt.Fprintf(env.stderr, "Invalid --type %q\n", *opType)
+		return exitUsage
+	}
+
+	sessions, err := storage.ListActiveSessions(env.db)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	var targets []models.Session
+	for _, session := range sessions {
+		if *did == "" || session.DID == *did {
+			targets = append(targets, session)
+		}
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(env.stderr, "Error: no signed-in accounts to sync; run 'bskyarchive login' or sign in through the web interface first")
+		return exitError
+	}
+
+	code := exitOK
+	for _, session := range targets {
+		if err := syncAccount(ctx, env, session, operationType); err != nil {
+			fmt.Fprintf(env.stderr, "Sync failed for %s: %v\n", session.Handle, err)
+			code = exitError
+		}
+		if ctx.Err() != nil {
+			return exitError
+		}
+	}
+
+	// The worker's completion hook is not set for commands; check before exiting instead
+	if err := env.alerts.Check(ctx); err != nil {
+		fmt.Fprintf(env.stderr, "Warning: saved search check failed: %v\n", err)
+	}
+
+	return code
+}
+
+// syncAccount starts an archive operation and reports progress until it finishes
+func syncAccount(ctx context.Context, env *commandEnv, session models.Session, operationType models.OperationType) error {
+	operationID, err := env.worker.StartArchive(ctx, session.DID, session.AccessToken, operationType)
+	if err != nil {
+		return err
+	}
+
+	progress := newProgressLine(env.stderr)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	lastCount := int64(-1)
+	for {
+		select {
+		case <-ctx.Done():
+			// Leave the operation in a terminal state so later runs are not blocked
+			if op, err := storage.GetOperation(env.db, operationID); err == nil {
+				now := time.Now()
+				op.Status = models.OperationStatusCancelled
+				op.ErrorMessage = "cancelled by user"
+				op.CompletedAt = &now
+				_ = storage.UpdateOperation(env.db, op)
+			}
+			progress.Done()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		op, err := storage.GetOperation(env.db, operationID)
+		if err != nil {
+			progress.Done()
+			return err
+		}
+
+		if op.ProgressCurrent != lastCount {
+			progress.Update("%s: %d posts archived", session.Handle, op.ProgressCurrent)
+			lastCount = op.ProgressCurrent
+		}
+
+		switch op.Status {
+		case models.OperationStatusCompleted:
+			progress.Done()
+			fmt.Fprintf(env.stdout, "%s: sync completed, %d posts archived\n", session.Handle, op.ProgressCurrent)
+			return nil
+		case models.OperationStatusFailed, models.OperationStatusCancelled:
+			progress.Done()
+			return fmt.Errorf("%s: %s", op.Status, op.ErrorMessage)
+		}
+	}
+}
+
+// parseDate parses a YYYY-MM-DD date flag
+func parseDate(name, value string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q (expected YYYY-MM-DD)", name, value)
+	}
+	return t, nil
+}
+
+// runExport exports posts for an account using the same pipeline as the web interface
+func runExport(ctx context.Context, env *commandEnv, args []string) int {
+	fs := newFlagSet(env, "export", "[flags]")
+	format := fs.String("format", string(models.ExportFormatJSON), "Export format: json, csv, html, jsonl, sqlite, markdown, activitypub, feed, epub, template or warc")
+	since := fs.String("since", "", "Only export posts created on or after this date (YYYY-MM-DD)")
+	until := fs.String("until", "", "Only export posts created on or before this date (YYYY-MM-DD)")
+	did := fs.String("did", "", "Account to export (default: the only archived account)")
+	includeMedia := fs.Bool("media", false, "Copy media files into the export")
+	companions := fs.Bool("companions", false, "With jsonl, also write media.jsonl, profiles.jsonl and operations.jsonl")
+	byDay := fs.Bool("by-day", false, "With markdown, write one note per day instead of one per post")
+	noReplies := fs.Bool("no-replies", false, "With feed, leave replies out of the feeds")
+	columns := fs.String("columns", "", "With csv, comma-separated columns in order, e.g. Handle,Text,PostURL (default: the standard 15)")
+	delimiter := fs.String("delimiter", "", "With csv, the field delimiter: comma, tab or semicolon")
+	noBOM := fs.Bool("no-bom", false, "With csv, leave out the UTF-8 byte order mark")
+	templateName := fs.String("template", "", "With template, the template to export with, e.g. posts.xml for posts.xml.tmpl")
+	outputDir := fs.String("output", "./exports", "Base directory for exports")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var dateRange *models.DateRange
+	if *since != "" || *until != "" {
+		dateRange = &models.DateRange{}
+		if *since != "" {
+			start, err := parseDate("since", *since)
+			if err != nil {
+				fmt.Fprintln(env.stderr, err)
+				return exitUsage
+			}
+			dateRange.StartDate = start
+		}
+		if *until != "" {
+			end, err := parseDate("until", *until)
+			if err != nil {
+				fmt.Fprintln(env.stderr, err)
+				return exitUsage
+			}
+			// Include the whole end day
+			dateRange.EndDate = end.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+		}
+		if err := dateRange.Validate(); err != nil {
+			fmt.Fprintf(env.stderr, "Invalid date range: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	accountDID, err := resolveDID(env, *did)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	templatesDir, baseURL := "", ""
+	if env.cfg != nil {
+		templatesDir = env.cfg.Export.TemplatesDir
+		baseURL = env.cfg.GetBaseURL()
+	}
+
+	var csvColumns []string
+	if *columns != "" {
+		for _, column := range strings.Split(*columns, ",") {
+			csvColumns = append(csvColumns, strings.TrimSpace(column))
+		}
+	}
+
+	opts := models.ExportOptions{
+		Format:         models.ExportFormat(*format),
+		OutputDir:      *outputDir,
+		IncludeMedia:   *includeMedia,
+		Companions:     *companions,
+		MarkdownByDay:  *byDay,
+		ExcludeReplies: *noReplies,
+		CSVColumns:     csvColumns,
+		CSVDelimiter:   *delimiter,
+		CSVNoBOM:       *noBOM,
+		Template:       *templateName,
+		TemplatesDir:   templatesDir,
+		BaseURL:        baseURL,
+		DID:            accountDID,
+		DateRange:      dateRange,
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(env.stderr, "Invalid export options: %v\n", err)
+		return exitUsage
+	}
+
+	job := &models.ExportJob{
+		ID:        time.Now().Format("2006-01-02_15-04-05"),
+		Options:   opts,
+		CreatedAt: time.Now(),
+		Progress: models.ExportProgress{
+			Status: models.ExportStatusQueued,
+		},
+	}
+
+	progressChan := make(chan models.ExportProgress, 100)
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- exporter.Run(env.db, job, progressChan)
+	}()
+
+	progress := newProgressLine(env.stderr)
+	var last models.ExportProgress
+	for p := range progressChan {
+		if p.Status == models.ExportStatusRunning && p != last {
+			if opts.IncludeMedia && p.MediaTotal > 0 {
+				progress.Update("Exporting: %d / %d posts, %d / %d media files", p.PostsProcessed, p.PostsTotal, p.MediaCopied, p.MediaTotal)
+			} else {
+				progress.Update("Exporting: %d / %d posts", p.PostsProcessed, p.PostsTotal)
+			}
+		}
+		last = p
+	}
+	progress.Done()
+
+	if err := <-errChan; err != nil {
+		fmt.Fprintf(env.stderr, "Export failed: %v\n", err)
+		return exitError
+	}
+
+	if last.PostsTotal == 0 {
+		fmt.Fprintln(env.stderr, "No posts matched the selected criteria")
+		fmt.Fprintln(env.stdout, job.ExportDir)
+		return exitNoResult
+	}
+
+	fmt.Fprintf(env.stderr, "Exported %d posts and %d media files\n", last.PostsTotal, job.Progress.MediaCopied)
+	fmt.Fprintln(env.stdout, job.ExportDir)
+	return exitOK
+}
+
+// runSearch prints posts matching a full-text query
+func runSearch(ctx context.Context, env *commandEnv, args []string) int {
+	fs := newFlagSet(env, "search", "[flags] query")
+	did := fs.String("did", "", "Account to search (default: the only archived account)")
+	limit := fs.Int("limit", 20, "Maximum number of results (1-100)")
+	sort := fs.String("sort", string(models.SearchSortNewest), "Result order: newest, oldest, likes, reposts or relevance")
+	asJSON := fs.Bool("json", false, "Print results as JSON")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	searchSort := models.SearchSort(*sort)
+	switch searchSort {
+	case models.SearchSortNewest, models.SearchSortOldest, models.SearchSortLikes, models.SearchSortReposts, models.SearchSortRelevance:
+	default:
+		fmt.Fprintf(env.stderr, "Invalid --sort %q\n", *sort)
+		return exitUsage
+	}
+
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	accountDID, err := resolveDID(env, *did)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	result, err := storage.SearchPostsForDIDs(env.db, []string{accountDID}, query, searchSort, *limit, 0)
+	var queryErr *storage.QueryError
+	if errors.As(err, &queryErr) {
+		fmt.Fprintf(env.stderr, "Error: %s\n", queryErr.Message)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Search failed: %v\n", err)
+		return exitError
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(env.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+	} else {
+		for _, post := range result.Posts {
+			fmt.Fprintf(env.stdout, "%s  %s\n", post.CreatedAt.Format("2006-01-02 15:04"), post.URI)
+			for _, line := range strings.Split(strings.TrimSpace(post.Text), "\n") {
+				fmt.Fprintf(env.stdout, "    %s\n", line)
+			}
+			fmt.Fprintln(env.stdout)
+		}
+		fmt.Fprintf(env.stderr, "%d of %d matching posts (%s)\n", len(result.Posts), result.Total, result.Elapsed)
+	}
+
+	if result.Total == 0 {
+		return exitNoResult
+	}
+	return exitOK
+}
+
+// runStats prints archive statistics for each account
+func runStats(ctx context.Context, env *commandEnv, args []string) int {
+	fs := newFlagSet(env, "stats", "[flags]")
+	did := fs.String("did", "", "Only show this account (default: every archived account)")
+	asJSON := fs.Bool("json", false, "Print statistics as JSON")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	dids := []string{*did}
+	if *did == "" {
+		var err error
+		dids, err = storage.ListArchivedDIDs(env.db)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+
+	var statuses []*models.ArchiveStatus
+	for _, accountDID := range dids {
+		status, err := storage.GetArchiveStatus(env.db, accountDID)
+		if err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+		statuses = append(statuses, status)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(env.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(statuses); err != nil {
+			fmt.Fprintf(env.stderr, "Error: %v\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	if len(statuses) == 0 {
+		fmt.Fprintln(env.stdout, "Archive is empty")
+		return exitOK
+	}
+
+	for _, status := range statuses {
+		name := status.DID
+		if profile, err := storage.GetLatestProfile(env.db, status.DID); err == nil && profile != nil {
+			name = fmt.Sprintf("%s (%s)", profile.Handle, status.DID)
+		}
+
+		fmt.Fprintln(env.stdout, name)
+		fmt.Fprintf(env.stdout, "  Posts:            %d\n", status.TotalPosts)
+		fmt.Fprintf(env.stdout, "  Replies:          %d\n", status.RepliesCount)
+		fmt.Fprintf(env.stdout, "  Posts with media: %d\n", status.PostsWithMedia)
+		fmt.Fprintf(env.stdout, "  Media files:      %d\n", status.TotalMedia)
+		fmt.Fprintf(env.stdout, "  Archive size:     %.2f MB\n", status.ArchiveSizeMB())
+		if status.OldestPost != nil {
+			fmt.Fprintf(env.stdout, "  Oldest post:      %s\n", status.OldestPost.Format("2006-01-02"))
+		}
+		if status.NewestPost != nil {
+			fmt.Fprintf(env.stdout, "  Newest post:      %s\n", status.NewestPost.Format("2006-01-02"))
+		}
+		if status.LastSuccessfulAt != nil {
+			fmt.Fprintf(env.stdout, "  Last sync:        %s\n", status.LastSuccessfulAt.Format("2006-01-02 15:04"))
+		} else {
+			fmt.Fprintln(env.stdout, "  Last sync:        never")
+		}
+		fmt.Fprintln(env.stdout)
+	}
+
+	return exitOK
+}
+
+// runVerify checks the database, search index and media files
+func runVerify(ctx context.Context, env *commandEnv, args []string) int {
+	fs := newFlagSet(env, "verify", "[flags]")
+	checkHashes := fs.Bool("hashes", false, "Re-hash every media file (slow for large archives)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	fmt.Fprintln(env.stderr, "Checking database and search index...")
+	problems, err := storage.VerifyDatabase(env.db)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintln(env.stderr, "Checking media files...")
+	mediaProblems, err := storage.VerifyMedia(env.db, *checkHashes)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Error: %v\n", err)
+		return exitError
+	}
+	problems = append(problems, mediaProblems...)
+
+	if len(problems) == 0 {
+		fmt.Fprintln(env.stdout, "OK: no problems found")
+		return exitOK
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(env.stdout, problem)
+	}
+	fmt.Fprintf(env.stderr, "%d problem(s) found\n", len(problems))
+	return exitProblems
+}
+
+// runRevokeSessions deletes every login session and its persisted OAuth session
+func runRevokeSessions(ctx context.Context, env *commandEnv, args []string) int {
+	fs := newFlagSet(env, "revoke-sessions", "")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	revoked, err := storage.RevokeAllSessions(env.db)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Failed to revoke sessions: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(env.stdout, "Revoked %d session(s)\n", revoked)
+	return exitOK
+}
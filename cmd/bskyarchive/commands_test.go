@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// setupCommandTest creates a database with posts for one account
+func setupCommandTest(t *testing.T) (*commandEnv, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	insertCommandTestPosts(t, db, "did:plc:alice")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	return &commandEnv{db: db, stdout: stdout, stderr: stderr}, stdout, stderr
+}
+
+func insertCommandTestPosts(t *testing.T, db *sql.DB, did string) {
+	t.Helper()
+
+	texts := []string{"hello world", "archiving is fun", "another hello"}
+	for i, text := range texts {
+		created := time.Date(2024, time.January, i+1, 12, 0, 0, 0, time.UTC)
+		post := &models.Post{
+			URI:       fmt.Sprintf("at://%s/app.bsky.feed.post/%d", did, i),
+			CID:       fmt.Sprintf("cid%d", i),
+			DID:       did,
+			Text:      text,
+			CreatedAt: created,
+			IndexedAt: created,
+		}
+		if err := storage.SavePost(db, post); err != nil {
+			t.Fatalf("Failed to save post: %v", err)
+		}
+	}
+}
+
+func TestRunCommand_Unknown(t *testing.T) {
+	env, _, stderr := setupCommandTest(t)
+
+	if code := runCommand("bogus", nil, env); code != exitUsage {
+		t.Errorf("Expected exit code %d, got %d", exitUsage, code)
+	}
+	if !strings.Contains(stderr.String(), "Unknown command") {
+		t.Errorf("Expected unknown command message, got %q", stderr.String())
+	}
+}
+
+func TestRunCommand_Search(t *testing.T) {
+	env, stdout, _ := setupCommandTest(t)
+
+	if code := runCommand("search", []string{"hello"}, env); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d", exitOK, code)
+	}
+	if strings.Count(stdout.String(), "at://") != 2 {
+		t.Errorf("Expected 2 results, got:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := runCommand("search", []string{"nothingmatches"}, env); code != exitNoResult {
+		t.Errorf("Expected exit code %d for no results, got %d", exitNoResult, code)
+	}
+
+	if code := runCommand("search", nil, env); code != exitUsage {
+		t.Errorf("Expected exit code %d without a query, got %d", exitUsage, code)
+	}
+
+	if code := runCommand("search", []string{"before:soon"}, env); code != exitUsage {
+		t.Errorf("Expected exit code %d for an invalid query, got %d", exitUsage, code)
+	}
+}
+
+func TestRunCommand_SearchRequiresDIDWithMultipleAccounts(t *testing.T) {
+	env, _, stderr := setupCommandTest(t)
+	insertCommandTestPosts(t, env.db, "did:plc:bob")
+
+	if code := runCommand("search", []string{"hello"}, env); code != exitError {
+		t.Errorf("Expected exit code %d, got %d", exitError, code)
+	}
+	if !strings.Contains(stderr.String(), "--did") {
+		t.Errorf("Expected hint to use --did, got %q", stderr.String())
+	}
+
+	if code := runCommand("search", []string{"--did", "did:plc:bob", "hello"}, env); code != exitOK {
+		t.Errorf("Expected exit code %d with --did, got %d", exitOK, code)
+	}
+}
+
+func TestRunCommand_Stats(t *testing.T) {
+	env, stdout, _ := setupCommandTest(t)
+
+	if code := runCommand("stats", nil, env); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d", exitOK, code)
+	}
+	if !strings.Contains(stdout.String(), "Posts:            3") {
+		t.Errorf("Expected post count in output, got:\n%s", stdout.String())
+	}
+}
+
+func TestRunCommand_Export(t *testing.T) {
+	env, stdout, _ := setupCommandTest(t)
+	outputDir := t.TempDir()
+
+	code := runCommand("export", []string{"--format", "json", "--since", "2024-01-02", "--output", outputDir}, env)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d", exitOK, code)
+	}
+
+	exportDir := strings.TrimSpace(stdout.String())
+	data, err := os.ReadFile(filepath.Join(exportDir, "posts.json"))
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	if strings.Contains(string(data), "hello world") || !strings.Contains(string(data), "another hello") {
+		t.Errorf("Export did not honor --since:\n%s", data)
+	}
+
+	if code := runCommand("export", []string{"--format", "xml"}, env); code != exitUsage {
+		t.Errorf("Expected exit code %d for invalid format, got %d", exitUsage, code)
+	}
+}
+
+func TestRunCommand_Verify(t *testing.T) {
+	env, stdout, _ := setupCommandTest(t)
+
+	if code := runCommand("verify", nil, env); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", exitOK, code, stdout.String())
+	}
+
+	// A media record without a file is reported as a problem
+	_, err := env.db.Exec(`INSERT INTO media (hash, post_uri, mime_type, file_path, size_bytes)
+		VALUES (?, 'at://did:plc:alice/app.bsky.feed.post/0', 'image/jpeg', ?, 10)`,
+		strings.Repeat("a", 64), filepath.Join(t.TempDir(), "missing.jpg"))
+	if err != nil {
+		t.Fatalf("Failed to insert media: %v", err)
+	}
+
+	stdout.Reset()
+	if code := runCommand("verify", nil, env); code != exitProblems {
+		t.Errorf("Expected exit code %d, got %d", exitProblems, code)
+	}
+	if !strings.Contains(stdout.String(), "missing file") {
+		t.Errorf("Expected missing file problem, got:\n%s", stdout.String())
+	}
+}
@@ -11,9 +11,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/shindakun/bskyarchive/internal/alerts"
 	"github.com/shindakun/bskyarchive/internal/archiver"
 	"github.com/shindakun/bskyarchive/internal/auth"
 	"github.com/shindakun/bskyarchive/internal/config"
+	"github.com/shindakun/bskyarchive/internal/scheduler"
 	"github.com/shindakun/bskyarchive/internal/storage"
 	"github.com/shindakun/bskyarchive/internal/web/handlers"
 	webmiddleware "github.com/shindakun/bskyarchive/internal/web/middleware"
@@ -22,7 +24,20 @@ import (
 func main() {
 	// Initialize logger
 	logger := log.New(os.Stdout, "[bskyarchive] ", log.LstdFlags|log.Lshortfile)
-	logger.Println("Starting Bluesky Archive Tool...")
+
+	// A first argument selects a headless command instead of the web server
+	command := ""
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+		if command == "help" || command == "-h" || command == "--help" {
+			printUsage(os.Stdout)
+			return
+		}
+		// Keep stdout clean for command output
+		logger.SetOutput(os.Stderr)
+	} else {
+		logger.Println("Starting Bluesky Archive Tool...")
+	}
 
 	// Load configuration
 	configPath := os.Getenv("CONFIG_PATH")
@@ -45,6 +60,25 @@ func main() {
 	defer db.Close()
 	logger.Println("Database initialized successfully")
 
+	rebuilt, err := storage.ConfigureSearchIndex(db, cfg.Search.Tokenizer)
+	if err != nil {
+		logger.Fatalf("Failed to configure search index: %v", err)
+	}
+	if rebuilt {
+		logger.Println("Search index rebuilt for the search.tokenizer setting")
+	}
+
+	// Headless commands run against the archive and exit without starting the server
+	if command != "" {
+		env, err := newCommandEnv(cfg, db)
+		if err != nil {
+			logger.Fatalf("Failed to initialize command: %v", err)
+		}
+		code := runCommand(command, os.Args[2:], env)
+		db.Close()
+		os.Exit(code)
+	}
+
 	// Initialize session manager with cookie security configuration
 	// Determine cookie Secure flag based on configuration and BASE_URL
 	cookieSecure := false
@@ -80,12 +114,30 @@ func main() {
 	logger.Printf("Session manager initialized (Secure=%v, SameSite=%v, MaxAge=%ds)",
 		cookieSecure, sameSiteMode, cfg.OAuth.SessionMaxAge)
 
+	// Restrict sign-in to the allowlist and grant the admin role
+	sessionManager.SetAccessPolicy(auth.NewAccessPolicy(cfg.Access.Admins, cfg.Access.Allowlist))
+	if len(cfg.Access.Allowlist) > 0 {
+		logger.Printf("Sign-in restricted to %d allowlisted accounts (%d admins)", len(cfg.Access.Allowlist), len(cfg.Access.Admins))
+	}
+
+	// Persist bskyoauth sessions (encrypted) so logins survive restarts
+	sessionStore, err := auth.NewSQLiteSessionStore(db, cfg.OAuth.SessionSecret)
+	if err != nil {
+		logger.Fatalf("Failed to initialize OAuth session store: %v", err)
+	}
+
 	// Initialize OAuth manager
 	baseURL := cfg.GetBaseURL()
-	oauthManager := auth.InitOAuth(baseURL, cfg.OAuth.Scopes, sessionManager)
+	oauthManager := auth.InitOAuth(baseURL, cfg.OAuth.Scopes, sessionManager, sessionStore)
 	logger.Printf("OAuth manager initialized with base URL: %s", baseURL)
 	logger.Printf("OAuth scopes: %v", cfg.OAuth.Scopes)
 
+	// App-password login works without a public BASE_URL (credentials stored encrypted)
+	appPasswords, err := auth.NewAppPasswordManager(db, cfg.OAuth.SessionSecret)
+	if err != nil {
+		logger.Fatalf("Failed to initialize app password login: %v", err)
+	}
+
 	// Initialize router
 	r := chi.NewRouter()
 
@@ -114,11 +166,55 @@ func main() {
 		logger.Println("WARNING: CSRF protection is DISABLED")
 	}
 
-	// Initialize archiver worker with OAuth manager for bskyoauth session access
-	worker := archiver.NewWorker(db, cfg.Archive.MediaPath, 300, 5*time.Minute, oauthManager)
+	// Initialize archiver worker with OAuth and app-password sessions
+	worker := archiver.NewWorker(db, cfg.Archive.MediaPath, 300, 5*time.Minute, auth.NewSessionSource(oauthManager, appPasswords))
+
+	// Check saved searches for new matches after every archive run
+	notifier := alerts.New(db, cfg.Alerts, logger)
+	worker.SetCompletionHook(func(did string) {
+		if err := notifier.Check(context.Background()); err != nil {
+			logger.Printf("Warning: saved search check failed: %v", err)
+		}
+	})
+
+	// Start scheduled sync if configured; otherwise clear stale next-run times from the dashboard
+	if cfg.Schedule.Enabled {
+		sched, err := scheduler.New(db, worker, cfg.Schedule, logger)
+		if err != nil {
+			logger.Fatalf("Failed to initialize scheduler: %v", err)
+		}
+		sched.Start()
+		defer sched.Stop()
+		if cfg.Schedule.Cron != "" {
+			logger.Printf("Scheduled sync enabled (cron=%q, jitter=%s)", cfg.Schedule.Cron, cfg.Schedule.Jitter)
+		} else {
+			logger.Printf("Scheduled sync enabled (interval=%s, jitter=%s)", cfg.Schedule.Interval, cfg.Schedule.Jitter)
+		}
+	} else if err := storage.ClearScheduleState(db); err != nil {
+		logger.Printf("Warning: %v", err)
+	}
+
+	// Keep watched_accounts in line with the config and archive watched accounts in the background
+	watchActors := make([]string, 0, len(cfg.Watchlist.Accounts))
+	for _, account := range cfg.Watchlist.Accounts {
+		watchActors = append(watchActors, account.Actor)
+	}
+	if err := storage.SyncWatchedAccounts(db, watchActors); err != nil {
+		logger.Printf("Warning: %v", err)
+	}
+	if len(watchActors) > 0 {
+		watchlist, err := scheduler.NewWatchlist(db, worker, cfg.Watchlist, logger)
+		if err != nil {
+			logger.Fatalf("Failed to initialize watchlist: %v", err)
+		}
+		watchlist.Start()
+		defer watchlist.Stop()
+		logger.Printf("Watchlist enabled for %d public accounts", len(watchActors))
+	}
 
 	// Initialize handlers
-	h := handlers.New(db, sessionManager, oauthManager, worker, logger)
+	h := handlers.New(db, sessionManager, oauthManager, appPasswords, worker, logger)
+	h.SetExportTemplatesDir(cfg.Export.TemplatesDir)
 
 	// Public routes
 	r.Get("/", h.Landing)
@@ -133,6 +229,7 @@ func main() {
 	// Auth routes
 	r.Route("/auth", func(r chi.Router) {
 		r.HandleFunc("/login", h.Login) // OAuth login - handles GET (form) and POST (handle submission), exempt from CSRF
+		r.Post("/login/app-password", h.LoginAppPassword) // App-password login (CSRF protected)
 		r.Get("/logout", h.Logout)
 	})
 
@@ -140,10 +237,24 @@ func main() {
 	r.Group(func(r chi.Router) {
 		r.Use(webmiddleware.RequireAuth(sessionManager))
 		r.Get("/dashboard", h.Dashboard)
+		r.Get("/accounts", h.Accounts)
+		r.Post("/accounts/switch", h.SwitchAccount)
+		r.Post("/accounts/link", h.LinkAccount)
+		r.Post("/accounts/unlink", h.UnlinkAccount)
 		r.Get("/archive", h.Archive)
 		r.Post("/archive/start", h.ArchiveStart)
 		r.Get("/archive/status", h.ArchiveStatus)
 		r.Get("/browse", h.Browse)
+		r.Get("/post/{did}/{rkey}", h.Post)
+		r.Get("/post/{did}/{rkey}/json", h.PostJSON)
+		r.Get("/searches", h.SavedSearches)
+		r.Post("/searches", h.SaveSearch)
+		r.Get("/searches/{id}", h.SavedSearch)
+		r.Post("/searches/{id}/delete", h.DeleteSavedSearch)
+		r.Get("/feed/{format:atom|rss}", h.Feed)
+		r.Get("/feeds", h.Feeds)
+		r.Post("/feeds", h.CreateFeedToken)
+		r.Post("/feeds/{token}/delete", h.DeleteFeedToken)
 		r.Get("/export", h.ExportPage)
 		r.Post("/export/start", h.StartExport)
 		r.Get("/export/progress/{job_id}", h.ExportProgress)
@@ -151,8 +262,19 @@ func main() {
 		r.Get("/export/download/*", h.DownloadExport)
 		r.Delete("/export/delete/*", h.DeleteExport) // DELETE requires CSRF token (automatically applied)
 		r.Get("/media/{hash}", h.ServeMedia)
+
+		// Admin routes (require the admin role)
+		r.Group(func(r chi.Router) {
+			r.Use(webmiddleware.RequireAdmin(sessionManager))
+			r.Get("/admin", h.Admin)
+			r.Post("/admin/users/revoke", h.RevokeUser)
+		})
 	})
 
+	// Feeds opened with a secret feed token (for feed readers without a session)
+	r.Get("/feeds/{token}/{format:atom|rss}", h.TokenFeed)
+	r.Get("/feeds/{token}/media/{hash}", h.TokenFeedMedia)
+
 	// Static files
 	r.Get("/static/*", h.ServeStatic)
 
@@ -0,0 +1,137 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/config"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// webhookPostLimit caps the posts sent in one webhook call; new_matches has the full count
+const webhookPostLimit = 20
+
+// WebhookPayload is the JSON body POSTed to the webhook for a saved search with new matches
+type WebhookPayload struct {
+	Search     models.SavedSearch `json:"search"`
+	NewMatches int                `json:"new_matches"`
+	Posts      []models.Post      `json:"posts"` // Newest matches first
+}
+
+// Notifier checks saved searches for new matches after archive runs
+// New matches are counted for the dashboard and optionally sent to a webhook
+type Notifier struct {
+	db         *sql.DB
+	webhookURL string
+	client     *http.Client
+	logger     *log.Logger
+	mu         sync.Mutex // One check at a time so matches are never counted twice
+}
+
+// New creates a notifier from the alerts configuration
+func New(db *sql.DB, cfg config.AlertsConfig, logger *log.Logger) *Notifier {
+	return &Notifier{
+		db:         db,
+		webhookURL: cfg.WebhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+	}
+}
+
+// Check looks for posts archived since the last check that match each saved search
+// A failing search or webhook is logged and does not stop the others
+func (n *Notifier) Check(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	searches, err := storage.ListAllSavedSearches(n.db)
+	if err != nil {
+		return err
+	}
+	if len(searches) == 0 {
+		return nil
+	}
+
+	// Posts archived while checking are left for the next check
+	maxRowID, err := storage.MaxPostRowID(n.db)
+	if err != nil {
+		return err
+	}
+
+	for i := range searches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := n.checkSearch(ctx, &searches[i], maxRowID); err != nil {
+			n.logger.Printf("Warning: saved search %d: %v", searches[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// checkSearch records new matches for one saved search and delivers them to the webhook
+func (n *Notifier) checkSearch(ctx context.Context, search *models.SavedSearch, maxRowID int64) error {
+	if maxRowID <= search.CheckedRowID {
+		return nil
+	}
+
+	dids, err := storage.AccountDIDs(n.db, search.AccountID)
+	if err != nil {
+		return err
+	}
+
+	posts, total, err := storage.NewSavedSearchMatches(n.db, dids, search.Query, search.CheckedRowID, maxRowID, webhookPostLimit)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.RecordSavedSearchCheck(n.db, search.ID, maxRowID, total); err != nil {
+		return err
+	}
+	if total == 0 {
+		return nil
+	}
+
+	search.NewMatches += total
+	n.logger.Printf("Saved search %q has %d new matches", search.DisplayName(), total)
+
+	if n.webhookURL == "" {
+		return nil
+	}
+	return n.deliver(ctx, WebhookPayload{Search: *search, NewMatches: total, Posts: posts})
+}
+
+// deliver POSTs a payload to the webhook
+func (n *Notifier) deliver(ctx context.Context, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
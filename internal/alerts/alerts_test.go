@@ -0,0 +1,87 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/config"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+func TestNotifierCheck(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:alerted"
+	_, err = db.Exec(`INSERT INTO sessions (id, account_id, did, handle, access_token, refresh_token, expires_at)
+		VALUES ('account-1', 'account-1', ?, 'alerted.test', 'token', 'refresh', ?)`, did, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+
+	search, err := storage.SaveSearch(db, "account-1", "Launches", "launch")
+	if err != nil {
+		t.Fatalf("SaveSearch failed: %v", err)
+	}
+
+	payloads := make(chan WebhookPayload, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	notifier := New(db, config.AlertsConfig{WebhookURL: server.URL}, log.New(io.Discard, "", 0))
+
+	for i, text := range []string{"Launch day!", "Lunch"} {
+		post := &models.Post{URI: "at://" + did + "/app.bsky.feed.post/" + string(rune('a'+i)), CID: "cid", DID: did, Text: text, CreatedAt: time.Now(), IndexedAt: time.Now()}
+		if err := storage.SavePost(db, post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+
+	if err := notifier.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	select {
+	case payload := <-payloads:
+		if payload.Search.ID != search.ID || payload.NewMatches != 1 || len(payload.Posts) != 1 || payload.Posts[0].Text != "Launch day!" {
+			t.Errorf("Unexpected payload: %+v", payload)
+		}
+	default:
+		t.Fatal("Expected a webhook call")
+	}
+
+	// A second check finds nothing new and sends nothing
+	if err := notifier.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	select {
+	case payload := <-payloads:
+		t.Errorf("Unexpected second webhook call: %+v", payload)
+	default:
+	}
+
+	saved, err := storage.GetSavedSearch(db, "account-1", search.ID)
+	if err != nil {
+		t.Fatalf("GetSavedSearch failed: %v", err)
+	}
+	if saved.NewMatches != 1 {
+		t.Errorf("Expected 1 new match for the dashboard, got %d", saved.NewMatches)
+	}
+}
@@ -1,9 +1,15 @@
 package archiver
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bluesky-social/indigo/atproto/identity"
 	"github.com/bluesky-social/indigo/atproto/syntax"
@@ -11,15 +17,34 @@ import (
 	"github.com/shindakun/bskyoauth"
 )
 
+// BskySessionRefresher defines the interface for refreshing and persisting bskyoauth sessions
+// Implemented by auth.OAuthManager
+type BskySessionRefresher interface {
+	RefreshAccessToken(ctx context.Context, sessionID string) (*bskyoauth.Session, error)
+	UpdateBskySession(sessionID string, session *bskyoauth.Session) error
+}
+
 // ATProtoClient wraps the indigo XRPC client with DPoP authentication
+// Sessions without a DPoP key (app-password logins) use Bearer authentication instead
 type ATProtoClient struct {
-	session *bskyoauth.Session
-	client  *xrpc.Client
+	session   *bskyoauth.Session
+	client    *xrpc.Client
+	sessionID string
+	refresher BskySessionRefresher
+	transport *sessionTransport
 }
 
 // NewATProtoClientFromSession creates a new AT Protocol client from a bskyoauth session
 // This properly sets up DPoP transport for secure token usage
 func NewATProtoClientFromSession(ctx context.Context, session *bskyoauth.Session) (*ATProtoClient, error) {
+	return NewATProtoClientWithRefresh(ctx, "", session, nil)
+}
+
+// NewATProtoClientWithRefresh creates a new AT Protocol client that transparently refreshes
+// expired access tokens and tracks DPoP nonces for long-running operations
+// sessionID is the bskyoauth session ID used to refresh and persist the session
+// If refresher is nil, the client behaves like NewATProtoClientFromSession
+func NewATProtoClientWithRefresh(ctx context.Context, sessionID string, session *bskyoauth.Session, refresher BskySessionRefresher) (*ATProtoClient, error) {
 	// Resolve PDS endpoint for the user
 	dir := identity.DefaultDirectory()
 	atid, err := syntax.ParseAtIdentifier(session.DID)
@@ -32,29 +57,52 @@ func NewATProtoClientFromSession(ctx context.Context, session *bskyoauth.Session
 		return nil, fmt.Errorf("failed to lookup identity: %w", err)
 	}
 
-	pdsHost := ident.PDSEndpoint()
+	return newATProtoClient(ident.PDSEndpoint(), sessionID, session, refresher), nil
+}
+
+// newATProtoClient builds the client for a known PDS host without identity resolution
+func newATProtoClient(pdsHost, sessionID string, session *bskyoauth.Session, refresher BskySessionRefresher) *ATProtoClient {
+	c := &ATProtoClient{
+		session:   session,
+		sessionID: sessionID,
+		refresher: refresher,
+	}
 
-	// Create DPoP transport - this is critical for DPoP-bound tokens!
-	transport := bskyoauth.NewDPoPTransport(
-		http.DefaultTransport,
-		session.DPoPKey,      // Private key for signing requests
-		session.AccessToken,
-		session.DPoPNonce,    // Nonce from server
-	)
+	// Wrap the DPoP transport so expired tokens and nonce changes are handled per request
+	c.transport = &sessionTransport{
+		underlying: http.DefaultTransport,
+		client:     c,
+	}
+	c.transport.setSession(session)
 
 	httpClient := &http.Client{
-		Transport: transport,
+		Transport: c.transport,
 	}
 
-	xrpcClient := &xrpc.Client{
+	c.client = &xrpc.Client{
 		Host:   pdsHost,
 		Client: httpClient,
 	}
 
+	return c
+}
+
+// DefaultPublicAppView is the unauthenticated Bluesky AppView used for public accounts
+const DefaultPublicAppView = "https://public.api.bsky.app"
+
+// NewPublicClient creates an unauthenticated client for public AppView endpoints
+// (getAuthorFeed, getProfile); it has no session and never refreshes tokens
+func NewPublicClient(host string) *ATProtoClient {
+	if host == "" {
+		host = DefaultPublicAppView
+	}
+
 	return &ATProtoClient{
-		session: session,
-		client:  xrpcClient,
-	}, nil
+		client: &xrpc.Client{
+			Host:   host,
+			Client: &http.Client{Timeout: 30 * time.Second},
+		},
+	}
 }
 
 // GetClient returns the underlying XRPC client for direct use
@@ -63,20 +111,26 @@ func (c *ATProtoClient) GetClient() *xrpc.Client {
 }
 
 // GetSession returns the bskyoauth session
+// Public clients have no session and return nil
 func (c *ATProtoClient) GetSession() *bskyoauth.Session {
+	if c.transport == nil {
+		return nil
+	}
+	c.transport.mu.Lock()
+	defer c.transport.mu.Unlock()
 	return c.session
 }
 
 // UpdateSession updates the session (e.g., after token refresh) and recreates the client
 func (c *ATProtoClient) UpdateSession(ctx context.Context, newSession *bskyoauth.Session) error {
 	// Recreate client with new session
-	newClient, err := NewATProtoClientFromSession(ctx, newSession)
+	newClient, err := NewATProtoClientWithRefresh(ctx, c.sessionID, newSession, c.refresher)
 	if err != nil {
 		return err
 	}
 
-	c.session = newClient.session
-	c.client = newClient.client
+	c.transport.setSession(newClient.session)
+	c.client.Host = newClient.client.Host
 	return nil
 }
 
@@ -100,3 +154,199 @@ func (c *ATProtoClient) Ping(ctx context.Context) error {
 
 	return nil
 }
+
+// sessionTransport wraps the bskyoauth DPoP transport (or a Bearer transport) for an ATProtoClient
+// It retries requests once after a DPoP nonce challenge or an expired access token,
+// refreshing the token through the client's BskySessionRefresher when needed
+type sessionTransport struct {
+	underlying http.RoundTripper
+	client     *ATProtoClient
+
+	mu   sync.Mutex
+	dpop http.RoundTripper
+}
+
+// setSession replaces the client's session and rebuilds the DPoP transport from it
+// in one step, so requests never see a transport for another session
+func (t *sessionTransport) setSession(session *bskyoauth.Session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.client.session = session
+	t.dpop = newAuthTransport(t.underlying, session)
+}
+
+// setNonce records a nonce the server asked for and rebuilds the DPoP transport with it
+func (t *sessionTransport) setNonce(nonce string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.client.session.DPoPNonce = nonce
+	t.dpop = newAuthTransport(t.underlying, t.client.session)
+}
+
+// newAuthTransport returns a DPoP transport for OAuth sessions, or a Bearer transport
+// for sessions without a DPoP key (created with an app password)
+func newAuthTransport(underlying http.RoundTripper, session *bskyoauth.Session) http.RoundTripper {
+	if session.DPoPKey == nil {
+		return &bearerTransport{underlying: underlying, token: session.AccessToken}
+	}
+	return bskyoauth.NewDPoPTransport(underlying, session.DPoPKey, session.AccessToken, session.DPoPNonce)
+}
+
+// bearerTransport adds a Bearer access token to each request
+type bearerTransport struct {
+	underlying http.RoundTripper
+	token      string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.underlying.RoundTrip(req)
+}
+
+// current returns the active DPoP transport and the session it was built with
+func (t *sessionTransport) current() (http.RoundTripper, *bskyoauth.Session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dpop, t.client.session
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Buffer the body so the request can be replayed after a refresh
+	var body []byte
+	if req.Body != nil && req.GetBody == nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		body = b
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	dpop, session := t.current()
+	resp, err := dpop.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.saveNonce(dpop)
+
+	if resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusBadRequest {
+		return resp, nil
+	}
+
+	// Inspect the error body to decide whether a retry can succeed
+	respBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	switch {
+	case isNonceError(respBody) && resp.Header.Get("DPoP-Nonce") != "":
+		// The server rotated its nonce; rebuild with it and retry
+		t.setNonce(resp.Header.Get("DPoP-Nonce"))
+	case (resp.StatusCode == http.StatusUnauthorized || isExpiredTokenError(respBody)) && t.client.refresher != nil:
+		if err := t.refresh(req.Context(), session.AccessToken); err != nil {
+			log.Printf("Warning: failed to refresh access token for %s: %v", session.DID, err)
+			return resp, nil
+		}
+	default:
+		return resp, nil
+	}
+
+	retryReq, err := cloneRequest(req, body)
+	if err != nil {
+		return resp, nil
+	}
+
+	dpop, _ = t.current()
+	retryResp, err := dpop.RoundTrip(retryReq)
+	if err != nil {
+		return nil, err
+	}
+	t.saveNonce(dpop)
+
+	return retryResp, nil
+}
+
+// refresh obtains a new access token unless another request already refreshed it
+func (t *sessionTransport) refresh(ctx context.Context, staleToken string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// A concurrent request may already have refreshed the token
+	if t.client.session.AccessToken != staleToken {
+		return nil
+	}
+
+	newSession, err := t.client.refresher.RefreshAccessToken(ctx, t.client.sessionID)
+	if err != nil {
+		return err
+	}
+
+	// Keep the most recent nonce if the refresh response did not supply one
+	if newSession.DPoPNonce == "" {
+		newSession.DPoPNonce = t.client.session.DPoPNonce
+	}
+
+	t.client.session = newSession
+	t.dpop = newAuthTransport(t.underlying, newSession)
+
+	log.Printf("Refreshed access token for %s", newSession.DID)
+	return nil
+}
+
+// saveNonce records the latest DPoP nonce seen by the transport and persists it
+func (t *sessionTransport) saveNonce(rt http.RoundTripper) {
+	dpop, ok := rt.(bskyoauth.DPoPTransport)
+	if !ok {
+		return
+	}
+
+	nonce := dpop.GetNonce()
+	if nonce == "" {
+		return
+	}
+
+	t.mu.Lock()
+	changed := t.client.session.DPoPNonce != nonce
+	if changed {
+		t.client.session.DPoPNonce = nonce
+	}
+	session := t.client.session
+	t.mu.Unlock()
+
+	if changed && t.client.refresher != nil && t.client.sessionID != "" {
+		if err := t.client.refresher.UpdateBskySession(t.client.sessionID, session); err != nil {
+			log.Printf("Warning: failed to persist DPoP nonce: %v", err)
+		}
+	}
+}
+
+// isNonceError reports whether an error response asks for a new DPoP nonce
+func isNonceError(body []byte) bool {
+	return strings.Contains(string(body), "use_dpop_nonce")
+}
+
+// isExpiredTokenError reports whether a PDS rejected a Bearer token as expired
+// PDS implementations answer expired app-password tokens with 400 ExpiredToken rather than 401
+func isExpiredTokenError(body []byte) bool {
+	return strings.Contains(string(body), "ExpiredToken")
+}
+
+// cloneRequest copies a request for a retry, restoring its body
+func cloneRequest(req *http.Request, body []byte) (*http.Request, error) {
+	retryReq := req.Clone(req.Context())
+	switch {
+	case body != nil:
+		retryReq.Body = io.NopCloser(bytes.NewReader(body))
+	case req.GetBody != nil:
+		b, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retryReq.Body = b
+	}
+	return retryReq, nil
+}
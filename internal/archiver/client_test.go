@@ -0,0 +1,247 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/shindakun/bskyoauth"
+)
+
+// fakeRefresher records refresh and update calls
+type fakeRefresher struct {
+	mu        sync.Mutex
+	refreshes int
+	updated   *bskyoauth.Session
+	session   *bskyoauth.Session
+	err       error
+}
+
+func (f *fakeRefresher) RefreshAccessToken(ctx context.Context, sessionID string) (*bskyoauth.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refreshes++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.session, nil
+}
+
+func (f *fakeRefresher) UpdateBskySession(sessionID string, session *bskyoauth.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := *session
+	f.updated = &copied
+	return nil
+}
+
+// newTestSession creates a bskyoauth session with a fresh DPoP key
+func newTestSession(t *testing.T, token string) *bskyoauth.Session {
+	t.Helper()
+
+	key, err := bskyoauth.GenerateDPoPKey()
+	if err != nil {
+		t.Fatalf("Failed to generate DPoP key: %v", err)
+	}
+
+	return &bskyoauth.Session{
+		DID:          "did:plc:test123",
+		AccessToken:  token,
+		RefreshToken: "refresh-token",
+		DPoPKey:      key,
+	}
+}
+
+// newTokenServer returns a server that only accepts the given access token
+func newTokenServer(t *testing.T, validToken string) (*httptest.Server, *int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		w.Header().Set("DPoP-Nonce", "nonce-1")
+		if r.Header.Get("Authorization") != "DPoP "+validToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_token","message":"token has expired"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestSessionTransport_RefreshesExpiredToken(t *testing.T) {
+	server, requests := newTokenServer(t, "new-token")
+
+	session := newTestSession(t, "old-token")
+	refreshed := *session
+	refreshed.AccessToken = "new-token"
+	refresher := &fakeRefresher{session: &refreshed}
+
+	client := newATProtoClient(server.URL, "session-id", session, refresher)
+
+	resp, err := client.GetClient().Client.Get(server.URL + "/xrpc/app.bsky.feed.getAuthorFeed")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected status 200 after refresh, got %d: %s", resp.StatusCode, body)
+	}
+
+	if refresher.refreshes != 1 {
+		t.Errorf("Expected 1 refresh, got %d", refresher.refreshes)
+	}
+	if *requests != 2 {
+		t.Errorf("Expected 2 requests (original + retry), got %d", *requests)
+	}
+	if got := client.GetSession().AccessToken; got != "new-token" {
+		t.Errorf("Expected client session to use refreshed token, got %q", got)
+	}
+}
+
+func TestSessionTransport_ConcurrentRefresh(t *testing.T) {
+	server, _ := newTokenServer(t, "new-token")
+
+	session := newTestSession(t, "old-token")
+	refreshed := *session
+	refreshed.AccessToken = "new-token"
+	refresher := &fakeRefresher{session: &refreshed}
+
+	client := newATProtoClient(server.URL, "session-id", session, refresher)
+
+	// Requests that all start with the expired token share a single refresh
+	var wg sync.WaitGroup
+	statuses := make([]int, 8)
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.GetClient().Client.Get(server.URL + "/xrpc/app.bsky.feed.getAuthorFeed")
+			if err != nil {
+				t.Errorf("Request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("Request %d: expected status 200, got %d", i, status)
+		}
+	}
+	if refresher.refreshes != 1 {
+		t.Errorf("Expected 1 refresh, got %d", refresher.refreshes)
+	}
+}
+
+func TestSessionTransport_PersistsNonce(t *testing.T) {
+	server, _ := newTokenServer(t, "token")
+
+	refresher := &fakeRefresher{}
+	client := newATProtoClient(server.URL, "session-id", newTestSession(t, "token"), refresher)
+
+	resp, err := client.GetClient().Client.Get(server.URL + "/xrpc/app.bsky.actor.getProfile")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if refresher.refreshes != 0 {
+		t.Errorf("Expected no refresh for a valid token, got %d", refresher.refreshes)
+	}
+	if refresher.updated == nil || refresher.updated.DPoPNonce != "nonce-1" {
+		t.Errorf("Expected nonce-1 to be persisted, got %+v", refresher.updated)
+	}
+	if got := client.GetSession().DPoPNonce; got != "nonce-1" {
+		t.Errorf("Expected client session nonce nonce-1, got %q", got)
+	}
+}
+
+func TestSessionTransport_RefreshFailureReturnsOriginalError(t *testing.T) {
+	server, requests := newTokenServer(t, "new-token")
+
+	refresher := &fakeRefresher{err: fmt.Errorf("refresh token expired")}
+	client := newATProtoClient(server.URL, "session-id", newTestSession(t, "old-token"), refresher)
+
+	resp, err := client.GetClient().Client.Get(server.URL + "/xrpc/app.bsky.feed.getAuthorFeed")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 when refresh fails, got %d", resp.StatusCode)
+	}
+	if *requests != 1 {
+		t.Errorf("Expected no retry when refresh fails, got %d requests", *requests)
+	}
+}
+
+func TestSessionTransport_NoRefresherPassesThrough(t *testing.T) {
+	server, requests := newTokenServer(t, "new-token")
+
+	client := newATProtoClient(server.URL, "", newTestSession(t, "old-token"), nil)
+
+	resp, err := client.GetClient().Client.Get(server.URL + "/xrpc/app.bsky.feed.getAuthorFeed")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without refresher, got %d", resp.StatusCode)
+	}
+	if *requests != 1 {
+		t.Errorf("Expected a single request without refresher, got %d", *requests)
+	}
+}
+
+func TestSessionTransport_BearerRefreshesExpiredToken(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("DPoP") != "" {
+			t.Errorf("Unexpected DPoP header for app password session")
+		}
+		if r.Header.Get("Authorization") != "Bearer new-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"ExpiredToken","message":"Token has expired"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	t.Cleanup(server.Close)
+
+	session := &bskyoauth.Session{DID: "did:plc:test123", AccessToken: "old-token"}
+	refresher := &fakeRefresher{session: &bskyoauth.Session{DID: "did:plc:test123", AccessToken: "new-token"}}
+	client := newATProtoClient(server.URL, "app-password:session", session, refresher)
+
+	resp, err := client.GetClient().Client.Get(server.URL + "/xrpc/app.bsky.feed.getAuthorFeed")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 after refresh, got %d", resp.StatusCode)
+	}
+	if refresher.refreshes != 1 || requests != 2 {
+		t.Errorf("Expected 1 refresh and 2 requests, got %d and %d", refresher.refreshes, requests)
+	}
+}
@@ -24,12 +24,18 @@ type ProfileResult struct {
 
 // FetchPosts retrieves posts from an author's feed with pagination
 func FetchPosts(ctx context.Context, client *ATProtoClient, actor, cursor string, limit int64) (*PostsResult, error) {
+	return FetchAuthorFeed(ctx, client, actor, "", cursor, limit)
+}
+
+// FetchAuthorFeed retrieves posts from an author's feed with an optional feed filter
+// (posts_with_replies, posts_no_replies, posts_with_media, posts_and_author_threads)
+func FetchAuthorFeed(ctx context.Context, client *ATProtoClient, actor, filter, cursor string, limit int64) (*PostsResult, error) {
 	if limit <= 0 || limit > 100 {
 		limit = 50 // Default batch size
 	}
 
-	// Call app.bsky.feed.getAuthorFeed using DPoP-authenticated client
-	output, err := bsky.FeedGetAuthorFeed(ctx, client.GetClient(), actor, cursor, "", false, limit)
+	// Call app.bsky.feed.getAuthorFeed (DPoP-authenticated, or unauthenticated for public clients)
+	output, err := bsky.FeedGetAuthorFeed(ctx, client.GetClient(), actor, cursor, filter, false, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch author feed: %w", err)
 	}
@@ -101,6 +107,18 @@ func convertFeedViewPostToPost(feedPost *bsky.FeedDefs_FeedViewPost) (*models.Po
 						post.CreatedAt = t
 					}
 				}
+				if langs, ok := recMap["langs"].([]interface{}); ok {
+					for _, lang := range langs {
+						if l, ok := lang.(string); ok {
+							post.Langs = append(post.Langs, l)
+						}
+					}
+				}
+				if facets, ok := recMap["facets"].([]interface{}); ok && len(facets) > 0 {
+					if facetsJSON, err := json.Marshal(facets); err == nil {
+						post.Facets = facetsJSON
+					}
+				}
 				// Check if it's a reply
 				if reply, ok := recMap["reply"].(map[string]interface{}); ok {
 					post.IsReply = true
@@ -91,6 +91,7 @@ func DownloadMedia(mediaPath, url, postURI, mimeType, altText string, width, hei
 			Width:     width,
 			Height:    height,
 			AltText:   altText,
+			SourceURL: url,
 			CreatedAt: time.Now(),
 		}
 		return &DownloadMediaResult{
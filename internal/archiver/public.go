@@ -0,0 +1,108 @@
+package archiver
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// PublicArchiveResult summarizes an archive run for a public account
+type PublicArchiveResult struct {
+	DID      string
+	Handle   string
+	Saved    int // Posts saved or updated during the run
+	NewPosts int // Posts that were not archived before
+}
+
+// ArchivePublicAccount archives a public account through an unauthenticated client (see NewPublicClient)
+// Only posts authored by the account are stored, so reposts never end up in another DID's archive
+// Incremental runs stop after a page with nothing new; otherwise the whole feed is fetched
+// Runs synchronously and does not create an archive operation, since the account has no session
+func (w *Worker) ArchivePublicAccount(ctx context.Context, client *ATProtoClient, actor, filter string, incremental bool) (*PublicArchiveResult, error) {
+	if err := w.rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	// The profile lookup also resolves a handle to its DID
+	profile, err := FetchProfile(ctx, client, actor)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Profile.DID == "" {
+		return nil, fmt.Errorf("could not resolve %s", actor)
+	}
+	if err := storage.SaveProfile(w.db, &profile.Profile); err != nil {
+		log.Printf("Warning: failed to save profile for %s: %v", actor, err)
+	}
+
+	result := &PublicArchiveResult{
+		DID:    profile.Profile.DID,
+		Handle: profile.Profile.Handle,
+	}
+
+	var cursor string
+	batchSize := int64(50)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		if err := w.rateLimiter.Wait(ctx); err != nil {
+			return result, err
+		}
+
+		page, err := FetchAuthorFeed(ctx, client, result.DID, filter, cursor, batchSize)
+		if err != nil {
+			return result, err
+		}
+
+		ownPosts, alreadyArchived := 0, 0
+		for _, post := range page.Posts {
+			// Skip reposts of other accounts
+			if post.DID != result.DID {
+				continue
+			}
+			ownPosts++
+
+			exists, err := storage.PostExists(w.db, post.URI)
+			if err != nil {
+				return result, err
+			}
+			if exists {
+				alreadyArchived++
+			}
+
+			if err := storage.SavePost(w.db, &post); err != nil {
+				log.Printf("Warning: failed to save post %s: %v", post.URI, err)
+				continue
+			}
+
+			if post.HasMedia && post.EmbedData != nil {
+				if err := w.downloadPostMedia(ctx, &post); err != nil {
+					log.Printf("Warning: failed to download media for post %s: %v", post.URI, err)
+				}
+			}
+
+			result.Saved++
+			if !exists {
+				result.NewPosts++
+			}
+		}
+
+		if page.Cursor == "" || len(page.Posts) == 0 {
+			break
+		}
+
+		if incremental && ownPosts > 0 && alreadyArchived == ownPosts {
+			break
+		}
+
+		cursor = page.Cursor
+	}
+
+	w.runCompletionHook(result.DID)
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package archiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// newFakeAppView serves getProfile and a two-page getAuthorFeed for did:plc:watched
+// The first page contains a repost from another account
+func newFakeAppView(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+
+	feedRequests := 0
+	feedPost := func(did, rkey, text string) map[string]interface{} {
+		return map[string]interface{}{
+			"post": map[string]interface{}{
+				"uri":       fmt.Sprintf("at://%s/app.bsky.feed.post/%s", did, rkey),
+				"cid":       "bafy" + rkey,
+				"author":    map[string]interface{}{"did": did, "handle": "someone.test"},
+				"indexedAt": "2024-03-01T12:00:00Z",
+				"record": map[string]interface{}{
+					"$type":     "app.bsky.feed.post",
+					"text":      text,
+					"createdAt": "2024-03-01T12:00:00Z",
+				},
+			},
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Public requests must not be authenticated")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/xrpc/app.bsky.actor.getProfile":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"did":    "did:plc:watched",
+				"handle": "official.test",
+			})
+		case "/xrpc/app.bsky.feed.getAuthorFeed":
+			feedRequests++
+			if r.URL.Query().Get("actor") != "did:plc:watched" {
+				t.Errorf("Unexpected actor %q", r.URL.Query().Get("actor"))
+			}
+			if r.URL.Query().Get("cursor") == "" {
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"feed": []interface{}{
+						feedPost("did:plc:watched", "p3", "third"),
+						feedPost("did:plc:other", "r1", "reposted"),
+						feedPost("did:plc:watched", "p2", "second"),
+					},
+					"cursor": "page2",
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"feed": []interface{}{feedPost("did:plc:watched", "p1", "first")},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &feedRequests
+}
+
+func TestArchivePublicAccount(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	server, feedRequests := newFakeAppView(t)
+	worker := NewWorker(db, t.TempDir(), 100, time.Minute, nil)
+	client := NewPublicClient(server.URL)
+
+	result, err := worker.ArchivePublicAccount(context.Background(), client, "official.test", "", false)
+	if err != nil {
+		t.Fatalf("ArchivePublicAccount failed: %v", err)
+	}
+	if result.DID != "did:plc:watched" || result.Handle != "official.test" {
+		t.Errorf("Unexpected identity: %+v", result)
+	}
+	if result.NewPosts != 3 || *feedRequests != 2 {
+		t.Errorf("Expected 3 new posts over 2 pages, got %d over %d", result.NewPosts, *feedRequests)
+	}
+
+	// Reposts are not stored under either account
+	if exists, _ := storage.PostExists(db, "at://did:plc:other/app.bsky.feed.post/r1"); exists {
+		t.Error("Repost from another account should not be archived")
+	}
+
+	// Incremental runs stop after the first page when nothing is new
+	*feedRequests = 0
+	result, err = worker.ArchivePublicAccount(context.Background(), client, "official.test", "", true)
+	if err != nil {
+		t.Fatalf("Incremental ArchivePublicAccount failed: %v", err)
+	}
+	if result.NewPosts != 0 || *feedRequests != 1 {
+		t.Errorf("Expected no new posts from 1 page, got %d from %d", result.NewPosts, *feedRequests)
+	}
+}
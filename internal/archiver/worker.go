@@ -25,6 +25,7 @@ type Worker struct {
 	mediaPath         string
 	rateLimiter       *RateLimiter
 	bskySessionGetter BskySessionGetter
+	completionHook    func(did string)
 }
 
 // NewWorker creates a new archive worker
@@ -37,6 +38,19 @@ func NewWorker(db *sql.DB, mediaPath string, requestsPerWindow int, windowDurati
 	}
 }
 
+// SetCompletionHook registers a function called after each successful archive run,
+// including watchlist runs; it runs on the archiving goroutine
+func (w *Worker) SetCompletionHook(hook func(did string)) {
+	w.completionHook = hook
+}
+
+// runCompletionHook calls the completion hook if one is set
+func (w *Worker) runCompletionHook(did string) {
+	if w.completionHook != nil {
+		w.completionHook(did)
+	}
+}
+
 // StartArchive initiates a new archive operation for a user
 // bskyoauthSessionID is the session ID from bskyoauth library
 func (w *Worker) StartArchive(ctx context.Context, did, bskyoauthSessionID string, operationType models.OperationType) (string, error) {
@@ -101,8 +115,18 @@ func (w *Worker) archiveWorker(ctx context.Context, operationID, did, bskyoauthS
 		return
 	}
 
+	// Attach token refresh when the session source supports it so long runs survive token expiry
+	refresher, _ := w.bskySessionGetter.(BskySessionRefresher)
+	if refresher != nil && bskySession.IsAccessTokenExpired(5*time.Minute) {
+		if refreshed, err := refresher.RefreshAccessToken(ctx, bskyoauthSessionID); err != nil {
+			log.Printf("Warning: failed to refresh access token before archive: %v", err)
+		} else {
+			bskySession = refreshed
+		}
+	}
+
 	// Create AT Protocol client with DPoP authentication
-	client, err := NewATProtoClientFromSession(ctx, bskySession)
+	client, err := NewATProtoClientWithRefresh(ctx, bskyoauthSessionID, bskySession, refresher)
 	if err != nil {
 		log.Printf("Failed to create AT Protocol client: %v", err)
 		operation.Status = models.OperationStatusFailed
@@ -156,7 +180,15 @@ func (w *Worker) archiveWorker(ctx context.Context, operationID, did, bskyoauthS
 		}
 
 		// Process each post
+		alreadyArchived := 0
 		for _, post := range result.Posts {
+			// Incremental runs track how much of the page is already archived
+			if operation.Type == models.OperationTypeIncremental {
+				if exists, err := storage.PostExists(w.db, post.URI); err == nil && exists {
+					alreadyArchived++
+				}
+			}
+
 			// Save post
 			if err := storage.SavePost(w.db, &post); err != nil {
 				log.Printf("Warning: failed to save post %s: %v", post.URI, err)
@@ -184,6 +216,12 @@ func (w *Worker) archiveWorker(ctx context.Context, operationID, did, bskyoauthS
 			break
 		}
 
+		// Incremental runs stop once a full page contains nothing new
+		if operation.Type == models.OperationTypeIncremental && alreadyArchived == len(result.Posts) {
+			log.Printf("Incremental archive %s reached previously archived posts", operationID)
+			break
+		}
+
 		cursor = result.Cursor
 	}
 
@@ -199,6 +237,7 @@ func (w *Worker) archiveWorker(ctx context.Context, operationID, did, bskyoauthS
 	}
 
 	log.Printf("Archive operation %s completed: %d posts archived", operationID, totalPosts)
+	w.runCompletionHook(did)
 }
 
 // fetchProfile fetches and saves the user's profile
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrLoginNotAllowed is returned when an identity is not on the access allowlist
+var ErrLoginNotAllowed = errors.New("this account is not allowed to sign in")
+
+// AccessPolicy decides who may sign in and who has the admin role
+// A nil policy allows everyone and grants no admins
+type AccessPolicy struct {
+	admins    map[string]bool
+	allowlist map[string]bool
+}
+
+// NewAccessPolicy creates a policy from admin DIDs and an allowlist of DIDs or handles
+// An empty allowlist allows any account; admins are always allowed
+func NewAccessPolicy(admins, allowlist []string) *AccessPolicy {
+	p := &AccessPolicy{
+		admins:    make(map[string]bool, len(admins)),
+		allowlist: make(map[string]bool, len(allowlist)),
+	}
+	for _, did := range admins {
+		p.admins[strings.TrimSpace(did)] = true
+	}
+	for _, entry := range allowlist {
+		p.allowlist[normalizeAccessEntry(entry)] = true
+	}
+	return p
+}
+
+// IsAdmin reports whether a DID has the admin role
+func (p *AccessPolicy) IsAdmin(did string) bool {
+	if p == nil {
+		return false
+	}
+	return p.admins[did]
+}
+
+// CanLogin reports whether an identity may sign in
+// Handles are matched case-insensitively, with or without a leading @
+func (p *AccessPolicy) CanLogin(did, handle string) bool {
+	if p == nil || len(p.allowlist) == 0 || p.admins[did] {
+		return true
+	}
+	return p.allowlist[did] || (handle != "" && p.allowlist[normalizeAccessEntry(handle)])
+}
+
+// normalizeAccessEntry trims and lowercases handles; DIDs are kept as-is
+func normalizeAccessEntry(entry string) string {
+	entry = strings.TrimPrefix(strings.TrimSpace(entry), "@")
+	if strings.HasPrefix(entry, "did:") {
+		return entry
+	}
+	return strings.ToLower(entry)
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	policy := NewAccessPolicy([]string{"did:plc:admin"}, []string{"did:plc:alice", "@Bob.Test"})
+
+	tests := []struct {
+		name   string
+		did    string
+		handle string
+		want   bool
+	}{
+		{"allowed DID", "did:plc:alice", "alice.test", true},
+		{"allowed handle", "did:plc:bob", "bob.test", true},
+		{"handle case", "did:plc:bob", "BOB.test", true},
+		{"admin always allowed", "did:plc:admin", "admin.test", true},
+		{"not listed", "did:plc:eve", "eve.test", false},
+		{"handle is DID", "did:plc:eve", "did:plc:eve", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.CanLogin(tt.did, tt.handle); got != tt.want {
+				t.Errorf("CanLogin(%q, %q) = %v, want %v", tt.did, tt.handle, got, tt.want)
+			}
+		})
+	}
+
+	if !policy.IsAdmin("did:plc:admin") || policy.IsAdmin("did:plc:alice") {
+		t.Error("Unexpected admin role")
+	}
+
+	// Empty allowlist and nil policy allow anyone
+	if !NewAccessPolicy(nil, nil).CanLogin("did:plc:eve", "eve.test") {
+		t.Error("Expected empty allowlist to allow anyone")
+	}
+	var none *AccessPolicy
+	if !none.CanLogin("did:plc:eve", "eve.test") || none.IsAdmin("did:plc:admin") {
+		t.Error("Expected nil policy to allow anyone without admins")
+	}
+}
+
+func TestSessionManager_AccessPolicy(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	sm := InitSessions("test-secret-that-is-at-least-32-chars", 3600, false, http.SameSiteLaxMode, db)
+	sm.SetAccessPolicy(NewAccessPolicy([]string{"did:plc:admin"}, []string{"alice.test"}))
+
+	if _, err := sm.SaveSessionRecord("did:plc:eve", "eve.test", "Eve", "oauth-eve"); !errors.Is(err, ErrLoginNotAllowed) {
+		t.Fatalf("Expected ErrLoginNotAllowed, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := sm.SaveSession(rec, httptest.NewRequest(http.MethodGet, "/", nil), "did:plc:alice", "alice.test", "Alice", "oauth-alice", ""); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	if _, err := sm.GetSession(withCookies(rec)); err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if sm.IsAdmin("did:plc:alice") || !sm.IsAdmin("did:plc:admin") {
+		t.Error("Unexpected admin role")
+	}
+
+	// Removing alice from the allowlist signs her out on the next request
+	sm.SetAccessPolicy(NewAccessPolicy([]string{"did:plc:admin"}, []string{"bob.test"}))
+	if _, err := sm.GetSession(withCookies(rec)); !errors.Is(err, ErrLoginNotAllowed) {
+		t.Errorf("Expected ErrLoginNotAllowed, got %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluesky-social/indigo/api/atproto"
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
+	"github.com/bluesky-social/indigo/xrpc"
+	"github.com/google/uuid"
+	"github.com/shindakun/bskyarchive/internal/storage"
+	"github.com/shindakun/bskyoauth"
+)
+
+// appPasswordSessionPrefix marks app-password session IDs stored in sessions.access_token
+const appPasswordSessionPrefix = "app-password:"
+
+// defaultEntryway is used to log in with an email address, which cannot be resolved to a PDS
+const defaultEntryway = "https://bsky.social"
+
+// IsAppPasswordSession reports whether a session ID belongs to an app-password login
+func IsAppPasswordSession(sessionID string) bool {
+	return strings.HasPrefix(sessionID, appPasswordSessionPrefix)
+}
+
+// AppPasswordManager logs in with Bluesky app passwords via com.atproto.server.createSession
+// The credential is stored encrypted so sessions can be re-created when the refresh token expires.
+// This path needs no public BASE_URL, so it works for local and headless use.
+type AppPasswordManager struct {
+	db         *sql.DB
+	encryptor  *Encryptor
+	httpClient *http.Client
+
+	// resolvePDS finds the PDS host for an identifier (overridable in tests)
+	resolvePDS func(ctx context.Context, identifier string) (string, error)
+
+	mu sync.Mutex
+}
+
+// appPasswordCredential is the encrypted payload stored for an app-password session
+type appPasswordCredential struct {
+	DID        string `json:"did"`
+	Handle     string `json:"handle"`
+	Identifier string `json:"identifier"`
+	Password   string `json:"password"`
+	PDS        string `json:"pds"`
+	AccessJwt  string `json:"access_jwt"`
+	RefreshJwt string `json:"refresh_jwt"`
+}
+
+// AppPasswordLogin is the result of a successful app-password login
+type AppPasswordLogin struct {
+	SessionID string
+	DID       string
+	Handle    string
+}
+
+// NewAppPasswordManager creates an app-password manager encrypted with a key derived from secret
+func NewAppPasswordManager(db *sql.DB, secret string) (*AppPasswordManager, error) {
+	encryptor, err := NewEncryptor(secret, "app-password")
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppPasswordManager{
+		db:         db,
+		encryptor:  encryptor,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		resolvePDS: resolvePDS,
+	}, nil
+}
+
+// Login creates a session with an app password and stores the encrypted credential
+// identifier is a handle, DID or account email address
+func (m *AppPasswordManager) Login(ctx context.Context, identifier, password string) (*AppPasswordLogin, error) {
+	identifier = strings.TrimPrefix(strings.TrimSpace(identifier), "@")
+	if identifier == "" {
+		return nil, fmt.Errorf("handle is required")
+	}
+	if password == "" {
+		return nil, fmt.Errorf("app password is required")
+	}
+
+	pds, err := m.resolvePDS(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &appPasswordCredential{
+		Identifier: identifier,
+		Password:   password,
+		PDS:        pds,
+	}
+	if err := m.createSession(ctx, cred); err != nil {
+		return nil, err
+	}
+
+	sessionID := appPasswordSessionPrefix + uuid.New().String()
+	if err := m.save(sessionID, cred); err != nil {
+		return nil, err
+	}
+
+	return &AppPasswordLogin{
+		SessionID: sessionID,
+		DID:       cred.DID,
+		Handle:    cred.Handle,
+	}, nil
+}
+
+// GetBskySession returns the stored tokens as a bskyoauth session
+// The session has no DPoP key, so the archiver uses Bearer authentication
+func (m *AppPasswordManager) GetBskySession(sessionID string) (*bskyoauth.Session, error) {
+	cred, err := m.load(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cred.session(), nil
+}
+
+// RefreshAccessToken obtains a new access token with the refresh token,
+// falling back to a new createSession with the stored app password
+func (m *AppPasswordManager) RefreshAccessToken(ctx context.Context, sessionID string) (*bskyoauth.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cred, err := m.load(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.refreshSession(ctx, cred); err != nil {
+		if err := m.createSession(ctx, cred); err != nil {
+			return nil, fmt.Errorf("failed to refresh app password session: %w", err)
+		}
+	}
+
+	if err := m.save(sessionID, cred); err != nil {
+		return nil, err
+	}
+
+	return cred.session(), nil
+}
+
+// UpdateBskySession stores tokens from an updated session
+func (m *AppPasswordManager) UpdateBskySession(sessionID string, session *bskyoauth.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cred, err := m.load(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if cred.AccessJwt == session.AccessToken && cred.RefreshJwt == session.RefreshToken {
+		return nil
+	}
+
+	cred.AccessJwt = session.AccessToken
+	cred.RefreshJwt = session.RefreshToken
+	return m.save(sessionID, cred)
+}
+
+// Discard removes a stored credential, e.g. when the identity may not sign in
+func (m *AppPasswordManager) Discard(sessionID string) error {
+	return storage.DeleteAppPasswordSession(m.db, sessionID)
+}
+
+// createSession calls com.atproto.server.createSession and updates the credential's tokens
+func (m *AppPasswordManager) createSession(ctx context.Context, cred *appPasswordCredential) error {
+	client := &xrpc.Client{Host: cred.PDS, Client: m.httpClient}
+
+	out, err := atproto.ServerCreateSession(ctx, client, &atproto.ServerCreateSession_Input{
+		Identifier: cred.Identifier,
+		Password:   cred.Password,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	cred.DID = out.Did
+	cred.Handle = out.Handle
+	cred.AccessJwt = out.AccessJwt
+	cred.RefreshJwt = out.RefreshJwt
+	return nil
+}
+
+// refreshSession calls com.atproto.server.refreshSession with the refresh token
+func (m *AppPasswordManager) refreshSession(ctx context.Context, cred *appPasswordCredential) error {
+	client := &xrpc.Client{
+		Host:   cred.PDS,
+		Client: m.httpClient,
+		Auth:   &xrpc.AuthInfo{AccessJwt: cred.RefreshJwt},
+	}
+
+	out, err := atproto.ServerRefreshSession(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	cred.Handle = out.Handle
+	cred.AccessJwt = out.AccessJwt
+	cred.RefreshJwt = out.RefreshJwt
+	return nil
+}
+
+// load reads and decrypts a stored credential
+func (m *AppPasswordManager) load(sessionID string) (*appPasswordCredential, error) {
+	data, err := storage.GetAppPasswordSession(m.db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := m.encryptor.Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt app password session: %w", err)
+	}
+
+	var cred appPasswordCredential
+	if err := json.Unmarshal(plaintext, &cred); err != nil {
+		return nil, fmt.Errorf("failed to decode app password session: %w", err)
+	}
+
+	return &cred, nil
+}
+
+// save encrypts and stores a credential
+func (m *AppPasswordManager) save(sessionID string, cred *appPasswordCredential) error {
+	plaintext, err := json.Marshal(cred)
+	if err != nil {
+		return fmt.Errorf("failed to encode app password session: %w", err)
+	}
+
+	data, err := m.encryptor.Seal(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt app password session: %w", err)
+	}
+
+	return storage.SaveAppPasswordSession(m.db, sessionID, cred.DID, data)
+}
+
+// session converts the credential to a bskyoauth session without a DPoP key
+func (c *appPasswordCredential) session() *bskyoauth.Session {
+	return &bskyoauth.Session{
+		DID:                  c.DID,
+		AccessToken:          c.AccessJwt,
+		RefreshToken:         c.RefreshJwt,
+		PDS:                  c.PDS,
+		AccessTokenExpiresAt: jwtExpiry(c.AccessJwt),
+	}
+}
+
+// resolvePDS finds the PDS for a handle or DID; email addresses use the Bluesky entryway
+func resolvePDS(ctx context.Context, identifier string) (string, error) {
+	if strings.Contains(identifier, "@") {
+		return defaultEntryway, nil
+	}
+
+	atid, err := syntax.ParseAtIdentifier(identifier)
+	if err != nil {
+		return "", fmt.Errorf("invalid handle: %w", err)
+	}
+
+	ident, err := identity.DefaultDirectory().Lookup(ctx, *atid)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve handle: %w", err)
+	}
+
+	pds := ident.PDSEndpoint()
+	if pds == "" {
+		return "", fmt.Errorf("no PDS found for %s", identifier)
+	}
+
+	return pds, nil
+}
+
+// jwtExpiry reads the exp claim from a JWT without verifying it
+// Returns the zero time if the token cannot be parsed
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// fakePDS serves createSession and refreshSession
+type fakePDS struct {
+	mu            sync.Mutex
+	creates       int
+	refreshes     int
+	refreshFails  bool
+	password      string
+	issuedRefresh string
+}
+
+func (p *fakePDS) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			var input struct {
+				Identifier string `json:"identifier"`
+				Password   string `json:"password"`
+			}
+			json.NewDecoder(r.Body).Decode(&input)
+			if input.Password != p.password {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"error":"AuthenticationRequired","message":"Invalid identifier or password"}`)
+				return
+			}
+			p.creates++
+			p.issuedRefresh = fmt.Sprintf("refresh-%d", p.creates)
+			fmt.Fprintf(w, `{"did":"did:plc:alice","handle":"alice.test","accessJwt":%q,"refreshJwt":%q}`,
+				testJWT(time.Now().Add(2*time.Hour)), p.issuedRefresh)
+		case "/xrpc/com.atproto.server.refreshSession":
+			if p.refreshFails || r.Header.Get("Authorization") != "Bearer "+p.issuedRefresh {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"ExpiredToken","message":"Token has expired"}`)
+				return
+			}
+			p.refreshes++
+			p.issuedRefresh = fmt.Sprintf("refreshed-%d", p.refreshes)
+			fmt.Fprintf(w, `{"did":"did:plc:alice","handle":"alice.test","accessJwt":"access-refreshed","refreshJwt":%q}`, p.issuedRefresh)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// testJWT builds an unsigned JWT with an exp claim
+func testJWT(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJub25lIn0." + payload + ".sig"
+}
+
+func setupAppPasswordTest(t *testing.T) (*AppPasswordManager, *fakePDS) {
+	t.Helper()
+
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	pds := &fakePDS{password: "abcd-efgh-ijkl-mnop"}
+	server := httptest.NewServer(pds.handler(t))
+	t.Cleanup(server.Close)
+
+	m, err := NewAppPasswordManager(db, testSecret)
+	if err != nil {
+		t.Fatalf("NewAppPasswordManager() failed: %v", err)
+	}
+	m.resolvePDS = func(ctx context.Context, identifier string) (string, error) {
+		return server.URL, nil
+	}
+
+	return m, pds
+}
+
+// TestAppPasswordLogin verifies login stores an encrypted credential usable as a Bearer session
+func TestAppPasswordLogin(t *testing.T) {
+	m, pds := setupAppPasswordTest(t)
+
+	login, err := m.Login(context.Background(), "@alice.test", pds.password)
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+	if !IsAppPasswordSession(login.SessionID) {
+		t.Errorf("Expected app password session ID, got %q", login.SessionID)
+	}
+	if login.DID != "did:plc:alice" || login.Handle != "alice.test" {
+		t.Errorf("Unexpected login result: %+v", login)
+	}
+
+	data, err := storage.GetAppPasswordSession(m.db, login.SessionID)
+	if err != nil {
+		t.Fatalf("GetAppPasswordSession() failed: %v", err)
+	}
+	if bytes.Contains(data, []byte(pds.password)) {
+		t.Error("App password stored in plaintext")
+	}
+
+	session, err := m.GetBskySession(login.SessionID)
+	if err != nil {
+		t.Fatalf("GetBskySession() failed: %v", err)
+	}
+	if session.DPoPKey != nil {
+		t.Error("Expected app password session without a DPoP key")
+	}
+	if session.RefreshToken != "refresh-1" || session.AccessTokenExpiresAt.IsZero() {
+		t.Errorf("Unexpected session: %+v", session)
+	}
+}
+
+// TestAppPasswordLogin_InvalidPassword verifies a rejected password stores nothing
+func TestAppPasswordLogin_InvalidPassword(t *testing.T) {
+	m, _ := setupAppPasswordTest(t)
+
+	if _, err := m.Login(context.Background(), "alice.test", "wrong"); err == nil {
+		t.Fatal("Expected error for invalid app password")
+	}
+
+	var count int
+	m.db.QueryRow("SELECT COUNT(*) FROM app_password_sessions").Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected no stored sessions, got %d", count)
+	}
+}
+
+// TestAppPasswordRefresh verifies refreshSession is used first, then createSession as a fallback
+func TestAppPasswordRefresh(t *testing.T) {
+	m, pds := setupAppPasswordTest(t)
+
+	login, err := m.Login(context.Background(), "alice.test", pds.password)
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+
+	session, err := m.RefreshAccessToken(context.Background(), login.SessionID)
+	if err != nil {
+		t.Fatalf("RefreshAccessToken() failed: %v", err)
+	}
+	if session.AccessToken != "access-refreshed" || pds.refreshes != 1 {
+		t.Errorf("Expected refreshSession to be used, got token %q (%d refreshes)", session.AccessToken, pds.refreshes)
+	}
+
+	// When the refresh token is rejected, the stored app password creates a new session
+	pds.refreshFails = true
+	session, err = m.RefreshAccessToken(context.Background(), login.SessionID)
+	if err != nil {
+		t.Fatalf("RefreshAccessToken() fallback failed: %v", err)
+	}
+	if pds.creates != 2 || session.RefreshToken != "refresh-2" {
+		t.Errorf("Expected createSession fallback, got %d creates and refresh token %q", pds.creates, session.RefreshToken)
+	}
+
+	stored, err := m.GetBskySession(login.SessionID)
+	if err != nil {
+		t.Fatalf("GetBskySession() failed: %v", err)
+	}
+	if stored.RefreshToken != "refresh-2" {
+		t.Errorf("Expected refreshed tokens to be stored, got %q", stored.RefreshToken)
+	}
+}
+
+// TestSessionSourceRouting verifies app-password session IDs never reach the OAuth manager
+func TestSessionSourceRouting(t *testing.T) {
+	m, pds := setupAppPasswordTest(t)
+
+	login, err := m.Login(context.Background(), "alice.test", pds.password)
+	if err != nil {
+		t.Fatalf("Login() failed: %v", err)
+	}
+
+	source := NewSessionSource(nil, m)
+	if _, err := source.GetBskySession(login.SessionID); err != nil {
+		t.Errorf("Expected app password session to be found, got %v", err)
+	}
+	if _, err := source.GetBskySession("oauth-session-id"); err == nil {
+		t.Error("Expected error for OAuth session without an OAuth manager")
+	}
+}
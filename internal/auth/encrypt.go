@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// Encryptor seals and opens secrets stored at rest using AES-256-GCM
+// The key is derived from the session secret and a purpose label, so values
+// encrypted for one purpose cannot be decrypted as another
+type Encryptor struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptor derives an AES-256 key from secret and purpose
+func NewEncryptor(secret, purpose string) (*Encryptor, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("encryption secret is required")
+	}
+
+	key := sha256.Sum256([]byte("bskyarchive:" + purpose + ":" + secret))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &Encryptor{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, prefixing the random nonce to the ciphertext
+func (e *Encryptor) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open decrypts data produced by Seal
+func (e *Encryptor) Open(data []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	plaintext, err := e.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
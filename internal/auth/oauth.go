@@ -2,9 +2,12 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/bluesky-social/indigo/atproto/identity"
+	"github.com/bluesky-social/indigo/atproto/syntax"
 	"github.com/shindakun/bskyoauth"
 )
 
@@ -15,12 +18,14 @@ type OAuthManager struct {
 }
 
 // InitOAuth creates a new OAuth manager with baseURL and scopes
-func InitOAuth(baseURL string, scopes []string, sessionManager *SessionManager) *OAuthManager {
+// sessionStore persists bskyoauth sessions; nil falls back to bskyoauth's in-memory store
+func InitOAuth(baseURL string, scopes []string, sessionManager *SessionManager, sessionStore bskyoauth.SessionStore) *OAuthManager {
 	opts := bskyoauth.ClientOptions{
 		BaseURL:         baseURL,
 		ClientName:      "Bluesky Personal Archive Tool",
 		ApplicationType: bskyoauth.ApplicationTypeWeb,
 		Scopes:          scopes,
+		SessionStore:    sessionStore,
 	}
 
 	client := bskyoauth.NewClientWithOptions(opts)
@@ -52,15 +57,29 @@ func (om *OAuthManager) HandleOAuthCallback(w http.ResponseWriter, r *http.Reque
 			return
 		}
 
+		// Resolve the handle so the allowlist can match it
+		handle := resolveHandle(r.Context(), bskySession.DID)
+
 		// Save session ID and user info to our database
 		err = om.sessionManager.SaveSession(
 			w, r,
 			bskySession.DID,
-			bskySession.DID, // Use DID as handle for now
+			handle,
 			bskySession.DID, // Use DID as display name for now
 			sessionID,        // Store the bskyoauth session ID
 			"",              // No longer store tokens directly
 		)
+		if errors.Is(err, ErrLoginNotAllowed) {
+			// Don't keep tokens for an identity that may not sign in
+			_ = om.client.DeleteSession(sessionID)
+			http.Redirect(w, r, "/auth/login?error=not_allowed", http.StatusSeeOther)
+			return
+		}
+		if errors.Is(err, ErrAlreadyLinked) {
+			_ = om.client.DeleteSession(sessionID)
+			http.Redirect(w, r, "/accounts?error=already_linked", http.StatusSeeOther)
+			return
+		}
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to save session: %v", err), http.StatusInternalServerError)
 			return
@@ -90,25 +109,32 @@ func (om *OAuthManager) ClientMetadataHandler() http.HandlerFunc {
 	return om.client.ClientMetadataHandler()
 }
 
-// RefreshAccessToken refreshes an expired access token using the refresh token
-func (om *OAuthManager) RefreshAccessToken(did, refreshToken string) (string, string, error) {
-	ctx := context.Background()
-
-	// Create a session object for the refresh call
-	// Note: We only need DID and RefreshToken for the refresh operation
-	session := &bskyoauth.Session{
-		DID:          did,
-		RefreshToken: refreshToken,
+// RefreshAccessToken refreshes an expired access token for a stored bskyoauth session
+// The full session (DPoP key, PDS, nonce) is loaded from the session store so the
+// refresh request can be DPoP-bound, and the refreshed session is written back to the store
+func (om *OAuthManager) RefreshAccessToken(ctx context.Context, sessionID string) (*bskyoauth.Session, error) {
+	session, err := om.client.GetSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session for refresh: %w", err)
 	}
 
 	// Call the bskyoauth RefreshToken method
 	newSession, err := om.client.RefreshToken(ctx, session)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to refresh token: %w", err)
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	// Persist the new tokens so later operations pick them up
+	if err := om.client.UpdateSession(sessionID, newSession); err != nil {
+		return nil, fmt.Errorf("failed to store refreshed session: %w", err)
 	}
 
-	// Return the new tokens
-	return newSession.AccessToken, newSession.RefreshToken, nil
+	return newSession, nil
+}
+
+// UpdateBskySession stores an updated bskyoauth session (e.g., after a DPoP nonce change)
+func (om *OAuthManager) UpdateBskySession(sessionID string, session *bskyoauth.Session) error {
+	return om.client.UpdateSession(sessionID, session)
 }
 
 // GetBskySession retrieves the bskyoauth session by session ID
@@ -124,3 +150,18 @@ func (om *OAuthManager) StartOAuthFlow(ctx context.Context, handle string) (stri
 	}
 	return flowState.AuthURL, nil
 }
+
+// resolveHandle looks up the handle for a DID, falling back to the DID itself
+func resolveHandle(ctx context.Context, did string) string {
+	parsed, err := syntax.ParseDID(did)
+	if err != nil {
+		return did
+	}
+
+	ident, err := identity.DefaultDirectory().LookupDID(ctx, parsed)
+	if err != nil || ident.Handle.IsInvalidHandle() {
+		return did
+	}
+
+	return ident.Handle.String()
+}
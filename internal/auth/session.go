@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -10,18 +11,27 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/sessions"
 	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
 )
 
 const (
 	sessionName      = "bskyarchive-session"
 	sessionKeyUserID = "user_id"
 	sessionKeyDID    = "did"
+
+	// sessionKeyLinkAccount marks a pending link flow: the next login joins this account
+	sessionKeyLinkAccount = "link_account"
 )
 
+// ErrAlreadyLinked is returned when a link flow signs in as an identity that belongs to another account
+// The identity stays with its account; it must be unlinked there first
+var ErrAlreadyLinked = errors.New("this account is already linked to another account")
+
 // SessionManager handles session operations
 type SessionManager struct {
-	store *sessions.CookieStore
-	db    *sql.DB
+	store  *sessions.CookieStore
+	db     *sql.DB
+	policy *AccessPolicy
 }
 
 // InitSessions creates a new session manager with 7-day expiration and HTTP-only cookies
@@ -43,13 +53,91 @@ func InitSessions(secret string, maxAge int, secure bool, sameSite http.SameSite
 	}
 }
 
+// SetAccessPolicy restricts who may sign in and who has the admin role
+func (sm *SessionManager) SetAccessPolicy(policy *AccessPolicy) {
+	sm.policy = policy
+}
+
+// IsAdmin reports whether a DID has the admin role
+func (sm *SessionManager) IsAdmin(did string) bool {
+	return sm != nil && sm.policy.IsAdmin(did)
+}
+
+// CanLogin reports whether the access policy still allows an identity
+// Requests without a cookie session, like feed readers, check their account with it
+func (sm *SessionManager) CanLogin(did, handle string) bool {
+	return sm == nil || sm.policy.CanLogin(did, handle)
+}
+
 // SaveSession stores a new session in the database and cookie
 // accessToken parameter now stores the bskyoauth session ID
 // refreshToken parameter is ignored (kept for compatibility)
+// If the user started a link flow (BeginLink), the identity joins their current account
 func (sm *SessionManager) SaveSession(w http.ResponseWriter, r *http.Request, did, handle, displayName, bskyoauthSessionID, _ string) error {
+	cookieSession, err := sm.store.Get(r, sessionName)
+	if err != nil {
+		return fmt.Errorf("failed to get cookie session: %w", err)
+	}
+
+	// Only link when the pending link belongs to the account that is still signed in
+	accountID := ""
+	if linkAccount, ok := cookieSession.Values[sessionKeyLinkAccount].(string); ok && linkAccount != "" {
+		if current, err := sm.GetSession(r); err == nil && current.AccountID == linkAccount {
+			accountID = linkAccount
+		}
+		delete(cookieSession.Values, sessionKeyLinkAccount)
+	}
+
+	session, err := sm.saveSessionRecord(accountID, did, handle, displayName, bskyoauthSessionID)
+	if err != nil {
+		// Drop the pending link so the next sign-in doesn't retry it
+		_ = cookieSession.Save(r, w)
+		return err
+	}
+
+	// Save to cookie
+	cookieSession.Values[sessionKeyUserID] = session.ID
+	cookieSession.Values[sessionKeyDID] = session.DID
+
+	if err := cookieSession.Save(r, w); err != nil {
+		return fmt.Errorf("failed to save cookie session: %w", err)
+	}
+
+	return nil
+}
+
+// SaveSessionRecord stores a session in the database without touching cookies
+// Used by SaveSession and by headless logins that have no browser
+// An identity that was linked before keeps its account
+func (sm *SessionManager) SaveSessionRecord(did, handle, displayName, bskyoauthSessionID string) (*models.Session, error) {
+	return sm.saveSessionRecord("", did, handle, displayName, bskyoauthSessionID)
+}
+
+// saveSessionRecord upserts a session for an identity in the given account
+// An empty accountID keeps the identity's existing account, or starts a new one
+func (sm *SessionManager) saveSessionRecord(accountID, did, handle, displayName, bskyoauthSessionID string) (*models.Session, error) {
+	if !sm.policy.CanLogin(did, handle) {
+		return nil, fmt.Errorf("%w: %s", ErrLoginNotAllowed, handle)
+	}
+
+	existing, err := storage.GetSessionAccountID(sm.db, did)
+	if err != nil {
+		return nil, err
+	}
+	if accountID == "" {
+		accountID = existing
+	} else if existing != "" && existing != accountID {
+		// Moving the identity would take it, its feeds and saved searches from its owner
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyLinked, handle)
+	}
+	if accountID == "" {
+		accountID = uuid.New().String()
+	}
+
 	// Create session model
 	session := &models.Session{
 		ID:           uuid.New().String(),
+		AccountID:    accountID,
 		DID:          did,
 		Handle:       handle,
 		DisplayName:  displayName,
@@ -61,17 +149,19 @@ func (sm *SessionManager) SaveSession(w http.ResponseWriter, r *http.Request, di
 
 	// Validate session (skip AccessToken check since it's now a session ID)
 	if session.ID == "" {
-		return fmt.Errorf("session id is required")
+		return nil, fmt.Errorf("session id is required")
 	}
 	if session.DID == "" {
-		return fmt.Errorf("did is required")
+		return nil, fmt.Errorf("did is required")
 	}
 
 	// Save to database
 	query := `
-		INSERT INTO sessions (id, did, handle, display_name, access_token, refresh_token, expires_at, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO sessions (id, did, handle, display_name, access_token, refresh_token, expires_at, created_at, account_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(did) DO UPDATE SET
+			id = excluded.id,
+			account_id = excluded.account_id,
 			handle = excluded.handle,
 			display_name = excluded.display_name,
 			access_token = excluded.access_token,
@@ -79,7 +169,7 @@ func (sm *SessionManager) SaveSession(w http.ResponseWriter, r *http.Request, di
 			expires_at = excluded.expires_at
 	`
 
-	_, err := sm.db.Exec(query,
+	_, err = sm.db.Exec(query,
 		session.ID,
 		session.DID,
 		session.Handle,
@@ -88,25 +178,13 @@ func (sm *SessionManager) SaveSession(w http.ResponseWriter, r *http.Request, di
 		session.RefreshToken,
 		session.ExpiresAt,
 		session.CreatedAt,
+		session.AccountID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to save session to database: %w", err)
-	}
-
-	// Save to cookie
-	cookieSession, err := sm.store.Get(r, sessionName)
-	if err != nil {
-		return fmt.Errorf("failed to get cookie session: %w", err)
-	}
-
-	cookieSession.Values[sessionKeyUserID] = session.ID
-	cookieSession.Values[sessionKeyDID] = session.DID
-
-	if err := cookieSession.Save(r, w); err != nil {
-		return fmt.Errorf("failed to save cookie session: %w", err)
+		return nil, fmt.Errorf("failed to save session to database: %w", err)
 	}
 
-	return nil
+	return session, nil
 }
 
 // GetSession retrieves session data from cookie and database
@@ -126,13 +204,14 @@ func (sm *SessionManager) GetSession(r *http.Request) (*models.Session, error) {
 	// Retrieve from database
 	var session models.Session
 	query := `
-		SELECT id, did, handle, display_name, access_token, refresh_token, expires_at, created_at
+		SELECT id, COALESCE(account_id, id), did, handle, display_name, access_token, refresh_token, expires_at, created_at
 		FROM sessions
 		WHERE id = ?
 	`
 
 	err = sm.db.QueryRow(query, userID).Scan(
 		&session.ID,
+		&session.AccountID,
 		&session.DID,
 		&session.Handle,
 		&session.DisplayName,
@@ -150,15 +229,23 @@ func (sm *SessionManager) GetSession(r *http.Request) (*models.Session, error) {
 
 	// Check if session is expired
 	if session.IsExpired() {
-		// Clean up expired session
-		sm.ClearSession(nil, r)
+		// Clean up the expired identity only; other linked identities stay signed in
+		if _, err := sm.db.Exec("DELETE FROM sessions WHERE id = ?", session.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete expired session: %w", err)
+		}
 		return nil, fmt.Errorf("session has expired")
 	}
 
+	// Identities removed from the allowlist are signed out on their next request
+	if !sm.policy.CanLogin(session.DID, session.Handle) {
+		return nil, fmt.Errorf("%w: %s", ErrLoginNotAllowed, session.Handle)
+	}
+
 	return &session, nil
 }
 
 // ClearSession removes session from cookie and database (logout)
+// Every identity linked to the account is signed out
 func (sm *SessionManager) ClearSession(w http.ResponseWriter, r *http.Request) error {
 	// Get session from cookie
 	cookieSession, err := sm.store.Get(r, sessionName)
@@ -170,8 +257,11 @@ func (sm *SessionManager) ClearSession(w http.ResponseWriter, r *http.Request) e
 	// Get user ID from cookie
 	userID, ok := cookieSession.Values[sessionKeyUserID].(string)
 	if ok && userID != "" {
-		// Delete from database
-		query := `DELETE FROM sessions WHERE id = ?`
+		// Delete the account's sessions from the database
+		query := `
+			DELETE FROM sessions
+			WHERE COALESCE(account_id, id) = (SELECT COALESCE(account_id, id) FROM sessions WHERE id = ?)
+		`
 		_, err := sm.db.Exec(query, userID)
 		if err != nil {
 			return fmt.Errorf("failed to delete session from database: %w", err)
@@ -189,6 +279,70 @@ func (sm *SessionManager) ClearSession(w http.ResponseWriter, r *http.Request) e
 	return nil
 }
 
+// BeginLink starts linking another identity to the signed-in account
+// The next successful login in this browser (OAuth callback or app password) joins the account
+func (sm *SessionManager) BeginLink(w http.ResponseWriter, r *http.Request) error {
+	current, err := sm.GetSession(r)
+	if err != nil {
+		return err
+	}
+
+	cookieSession, err := sm.store.Get(r, sessionName)
+	if err != nil {
+		return fmt.Errorf("failed to get cookie session: %w", err)
+	}
+
+	cookieSession.Values[sessionKeyLinkAccount] = current.AccountID
+	if err := cookieSession.Save(r, w); err != nil {
+		return fmt.Errorf("failed to save cookie session: %w", err)
+	}
+
+	return nil
+}
+
+// SwitchAccount makes another identity linked to the signed-in account the active one
+func (sm *SessionManager) SwitchAccount(w http.ResponseWriter, r *http.Request, did string) (*models.Session, error) {
+	current, err := sm.GetSession(r)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := storage.GetAccountSession(sm.db, current.AccountID, did)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsExpired() {
+		return nil, fmt.Errorf("session for %s has expired; sign in again to re-link it", target.Handle)
+	}
+
+	cookieSession, err := sm.store.Get(r, sessionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cookie session: %w", err)
+	}
+
+	cookieSession.Values[sessionKeyUserID] = target.ID
+	cookieSession.Values[sessionKeyDID] = target.DID
+	if err := cookieSession.Save(r, w); err != nil {
+		return nil, fmt.Errorf("failed to save cookie session: %w", err)
+	}
+
+	return target, nil
+}
+
+// UnlinkAccount signs out one identity linked to the signed-in account
+// The active identity cannot be unlinked; switch to another one or log out instead
+func (sm *SessionManager) UnlinkAccount(r *http.Request, did string) error {
+	current, err := sm.GetSession(r)
+	if err != nil {
+		return err
+	}
+	if current.DID == did {
+		return fmt.Errorf("cannot unlink the active account")
+	}
+
+	return storage.DeleteAccountSession(sm.db, current.AccountID, did)
+}
+
 // GetSessionFromContext retrieves session from request context
 func GetSessionFromContext(ctx context.Context) (*models.Session, bool) {
 	session, ok := ctx.Value("session").(*models.Session)
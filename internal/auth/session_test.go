@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// withCookies returns a request carrying the cookies set on a previous response
+func withCookies(rec *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range rec.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+func TestSessionManager_LinkSwitchUnlink(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	sm := InitSessions("test-secret-that-is-at-least-32-chars", 3600, false, http.SameSiteLaxMode, db)
+
+	// Sign in as alice
+	rec := httptest.NewRecorder()
+	if err := sm.SaveSession(rec, httptest.NewRequest(http.MethodGet, "/", nil), "did:plc:alice", "alice.test", "Alice", "oauth-alice", ""); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	alice, err := sm.GetSession(withCookies(rec))
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+
+	// Start a link flow and sign in as bob in the same browser
+	req := withCookies(rec)
+	rec = httptest.NewRecorder()
+	if err := sm.BeginLink(rec, req); err != nil {
+		t.Fatalf("BeginLink failed: %v", err)
+	}
+	req = withCookies(rec)
+	rec = httptest.NewRecorder()
+	if err := sm.SaveSession(rec, req, "did:plc:bob", "bob.test", "Bob", "oauth-bob", ""); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	bob, err := sm.GetSession(withCookies(rec))
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if bob.DID != "did:plc:bob" || bob.AccountID != alice.AccountID {
+		t.Fatalf("Expected bob to be linked to alice's account, got %+v", bob)
+	}
+
+	linked, err := storage.ListAccountSessions(db, alice.AccountID)
+	if err != nil || len(linked) != 2 {
+		t.Fatalf("Expected 2 linked accounts, got %d (err %v)", len(linked), err)
+	}
+
+	// Switch back to alice
+	req = withCookies(rec)
+	rec = httptest.NewRecorder()
+	if _, err := sm.SwitchAccount(rec, req, "did:plc:alice"); err != nil {
+		t.Fatalf("SwitchAccount failed: %v", err)
+	}
+	req = withCookies(rec)
+	current, err := sm.GetSession(req)
+	if err != nil || current.DID != "did:plc:alice" {
+		t.Fatalf("Expected alice to be active, got %+v (err %v)", current, err)
+	}
+
+	// Signing in again as bob without a link flow keeps the account
+	if _, err := sm.SaveSessionRecord("did:plc:bob", "bob.test", "Bob", "oauth-bob-2"); err != nil {
+		t.Fatalf("SaveSessionRecord failed: %v", err)
+	}
+	if accountID, _ := storage.GetSessionAccountID(db, "did:plc:bob"); accountID != alice.AccountID {
+		t.Errorf("Expected bob to stay in alice's account, got %s", accountID)
+	}
+
+	// Identities outside the account cannot be switched to
+	carol, err := sm.SaveSessionRecord("did:plc:carol", "carol.test", "Carol", "oauth-carol")
+	if err != nil {
+		t.Fatalf("SaveSessionRecord failed: %v", err)
+	}
+	if _, err := sm.SwitchAccount(httptest.NewRecorder(), req, "did:plc:carol"); err == nil {
+		t.Error("Expected switching to an unlinked identity to fail")
+	}
+
+	// Linking an identity that belongs to another account is refused; it stays with its owner
+	linkReq := req
+	rec = httptest.NewRecorder()
+	if err := sm.BeginLink(rec, linkReq); err != nil {
+		t.Fatalf("BeginLink failed: %v", err)
+	}
+	linkReq = withCookies(rec)
+	if err := sm.SaveSession(httptest.NewRecorder(), linkReq, "did:plc:carol", "carol.test", "Carol", "oauth-carol-2", ""); !errors.Is(err, ErrAlreadyLinked) {
+		t.Errorf("Expected ErrAlreadyLinked, got %v", err)
+	}
+	if accountID, _ := storage.GetSessionAccountID(db, "did:plc:carol"); accountID != carol.AccountID {
+		t.Errorf("Expected carol to stay in its own account, got %s", accountID)
+	}
+
+	// The active identity cannot be unlinked; others can
+	if err := sm.UnlinkAccount(req, "did:plc:alice"); err == nil {
+		t.Error("Expected unlinking the active identity to fail")
+	}
+	if err := sm.UnlinkAccount(req, "did:plc:bob"); err != nil {
+		t.Fatalf("UnlinkAccount failed: %v", err)
+	}
+	if accountID, _ := storage.GetSessionAccountID(db, "did:plc:bob"); accountID != "" {
+		t.Errorf("Expected bob's session to be removed")
+	}
+}
+
+func TestSessionManager_ClearSessionSignsOutLinkedAccounts(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	sm := InitSessions("test-secret-that-is-at-least-32-chars", 3600, false, http.SameSiteLaxMode, db)
+
+	rec := httptest.NewRecorder()
+	if err := sm.SaveSession(rec, httptest.NewRequest(http.MethodGet, "/", nil), "did:plc:alice", "alice.test", "Alice", "oauth-alice", ""); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	req := withCookies(rec)
+	alice, err := sm.GetSession(req)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if _, err := sm.saveSessionRecord(alice.AccountID, "did:plc:bob", "bob.test", "Bob", "oauth-bob"); err != nil {
+		t.Fatalf("saveSessionRecord failed: %v", err)
+	}
+	if _, err := sm.SaveSessionRecord("did:plc:carol", "carol.test", "Carol", "oauth-carol"); err != nil {
+		t.Fatalf("SaveSessionRecord failed: %v", err)
+	}
+
+	if err := sm.ClearSession(httptest.NewRecorder(), req); err != nil {
+		t.Fatalf("ClearSession failed: %v", err)
+	}
+
+	sessions, err := storage.ListActiveSessions(db)
+	if err != nil {
+		t.Fatalf("ListActiveSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].DID != "did:plc:carol" {
+		t.Errorf("Expected only carol's session to remain, got %+v", sessions)
+	}
+}
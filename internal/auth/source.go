@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shindakun/bskyoauth"
+)
+
+// SessionSource gives the archiver one place to look up sessions, whichever way the user signed in
+// Session IDs from app-password logins are routed to the AppPasswordManager, all others to OAuth
+type SessionSource struct {
+	oauth        *OAuthManager
+	appPasswords *AppPasswordManager
+}
+
+// NewSessionSource creates a session source; either manager may be nil if that login method is unavailable
+func NewSessionSource(oauth *OAuthManager, appPasswords *AppPasswordManager) *SessionSource {
+	return &SessionSource{
+		oauth:        oauth,
+		appPasswords: appPasswords,
+	}
+}
+
+// GetBskySession retrieves the session for a session ID
+func (s *SessionSource) GetBskySession(sessionID string) (*bskyoauth.Session, error) {
+	if IsAppPasswordSession(sessionID) {
+		if s.appPasswords == nil {
+			return nil, fmt.Errorf("app password login is not available")
+		}
+		return s.appPasswords.GetBskySession(sessionID)
+	}
+
+	if s.oauth == nil {
+		return nil, fmt.Errorf("oauth login is not available")
+	}
+	return s.oauth.GetBskySession(sessionID)
+}
+
+// RefreshAccessToken refreshes the access token for a session ID
+func (s *SessionSource) RefreshAccessToken(ctx context.Context, sessionID string) (*bskyoauth.Session, error) {
+	if IsAppPasswordSession(sessionID) {
+		if s.appPasswords == nil {
+			return nil, fmt.Errorf("app password login is not available")
+		}
+		return s.appPasswords.RefreshAccessToken(ctx, sessionID)
+	}
+
+	if s.oauth == nil {
+		return nil, fmt.Errorf("oauth login is not available")
+	}
+	return s.oauth.RefreshAccessToken(ctx, sessionID)
+}
+
+// UpdateBskySession stores an updated session for a session ID
+func (s *SessionSource) UpdateBskySession(sessionID string, session *bskyoauth.Session) error {
+	if IsAppPasswordSession(sessionID) {
+		if s.appPasswords == nil {
+			return fmt.Errorf("app password login is not available")
+		}
+		return s.appPasswords.UpdateBskySession(sessionID, session)
+	}
+
+	if s.oauth == nil {
+		return fmt.Errorf("oauth login is not available")
+	}
+	return s.oauth.UpdateBskySession(sessionID, session)
+}
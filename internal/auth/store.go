@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/storage"
+	"github.com/shindakun/bskyoauth"
+)
+
+// SQLiteSessionStore persists bskyoauth sessions in the oauth_sessions table
+// Session payloads (DPoP key, tokens, nonce) are encrypted before they reach the database,
+// so logins survive restarts and background operations can reuse them
+type SQLiteSessionStore struct {
+	db        *sql.DB
+	encryptor *Encryptor
+}
+
+// storedSession is the serialized form of a bskyoauth.Session
+type storedSession struct {
+	DID                   string    `json:"did"`
+	AccessToken           string    `json:"access_token"`
+	RefreshToken          string    `json:"refresh_token"`
+	DPoPKey               []byte    `json:"dpop_key"` // DER-encoded EC private key
+	PDS                   string    `json:"pds"`
+	DPoPNonce             string    `json:"dpop_nonce"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// NewSQLiteSessionStore creates a session store encrypted with a key derived from secret
+func NewSQLiteSessionStore(db *sql.DB, secret string) (*SQLiteSessionStore, error) {
+	encryptor, err := NewEncryptor(secret, "oauth-session")
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLiteSessionStore{
+		db:        db,
+		encryptor: encryptor,
+	}, nil
+}
+
+// Get retrieves and decrypts a session by ID
+func (s *SQLiteSessionStore) Get(sessionID string) (*bskyoauth.Session, error) {
+	data, err := storage.GetOAuthSession(s.db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.encryptor.Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt oauth session: %w", err)
+	}
+
+	var stored storedSession
+	if err := json.Unmarshal(plaintext, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode oauth session: %w", err)
+	}
+
+	session := &bskyoauth.Session{
+		DID:                   stored.DID,
+		AccessToken:           stored.AccessToken,
+		RefreshToken:          stored.RefreshToken,
+		PDS:                   stored.PDS,
+		DPoPNonce:             stored.DPoPNonce,
+		AccessTokenExpiresAt:  stored.AccessTokenExpiresAt,
+		RefreshTokenExpiresAt: stored.RefreshTokenExpiresAt,
+	}
+
+	if len(stored.DPoPKey) > 0 {
+		key, err := x509.ParseECPrivateKey(stored.DPoPKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DPoP key: %w", err)
+		}
+		session.DPoPKey = key
+	}
+
+	return session, nil
+}
+
+// Set encrypts and stores a session with the given ID
+func (s *SQLiteSessionStore) Set(sessionID string, session *bskyoauth.Session) error {
+	stored := storedSession{
+		DID:                   session.DID,
+		AccessToken:           session.AccessToken,
+		RefreshToken:          session.RefreshToken,
+		PDS:                   session.PDS,
+		DPoPNonce:             session.DPoPNonce,
+		AccessTokenExpiresAt:  session.AccessTokenExpiresAt,
+		RefreshTokenExpiresAt: session.RefreshTokenExpiresAt,
+	}
+
+	if session.DPoPKey != nil {
+		der, err := x509.MarshalECPrivateKey(session.DPoPKey)
+		if err != nil {
+			return fmt.Errorf("failed to encode DPoP key: %w", err)
+		}
+		stored.DPoPKey = der
+	}
+
+	plaintext, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode oauth session: %w", err)
+	}
+
+	data, err := s.encryptor.Seal(plaintext)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt oauth session: %w", err)
+	}
+
+	return storage.SaveOAuthSession(s.db, sessionID, session.DID, data)
+}
+
+// Delete removes a session by ID
+func (s *SQLiteSessionStore) Delete(sessionID string) error {
+	return storage.DeleteOAuthSession(s.db, sessionID)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/storage"
+	"github.com/shindakun/bskyoauth"
+)
+
+const testSecret = "test-secret-that-is-at-least-32-characters-long"
+
+// TestSQLiteSessionStoreRoundTrip verifies sessions survive encryption and storage intact
+func TestSQLiteSessionStoreRoundTrip(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	store, err := NewSQLiteSessionStore(db, testSecret)
+	if err != nil {
+		t.Fatalf("NewSQLiteSessionStore() failed: %v", err)
+	}
+
+	key, err := bskyoauth.GenerateDPoPKey()
+	if err != nil {
+		t.Fatalf("Failed to generate DPoP key: %v", err)
+	}
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	session := &bskyoauth.Session{
+		DID:                  "did:plc:test",
+		AccessToken:          "access-token",
+		RefreshToken:         "refresh-token",
+		DPoPKey:              key,
+		PDS:                  "https://pds.example.com",
+		DPoPNonce:            "nonce",
+		AccessTokenExpiresAt: expires,
+	}
+
+	if err := store.Set("oauth-1", session); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	// Tokens must not be stored in plaintext
+	raw, err := storage.GetOAuthSession(db, "oauth-1")
+	if err != nil {
+		t.Fatalf("GetOAuthSession() failed: %v", err)
+	}
+	if bytes.Contains(raw, []byte("access-token")) || bytes.Contains(raw, []byte("refresh-token")) {
+		t.Error("Stored session contains plaintext tokens")
+	}
+
+	got, err := store.Get("oauth-1")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+
+	if got.DID != session.DID || got.AccessToken != session.AccessToken ||
+		got.RefreshToken != session.RefreshToken || got.PDS != session.PDS ||
+		got.DPoPNonce != session.DPoPNonce {
+		t.Errorf("Round-tripped session mismatch: got %+v", got)
+	}
+	if !got.AccessTokenExpiresAt.Equal(expires) {
+		t.Errorf("Expected expiry %v, got %v", expires, got.AccessTokenExpiresAt)
+	}
+	if got.DPoPKey == nil || !got.DPoPKey.Equal(key) {
+		t.Error("DPoP key was not preserved")
+	}
+
+	if err := store.Delete("oauth-1"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := store.Get("oauth-1"); err == nil {
+		t.Error("Expected error getting deleted session")
+	}
+}
+
+// TestSQLiteSessionStoreWrongSecret verifies sessions cannot be read with a different secret
+func TestSQLiteSessionStoreWrongSecret(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	store, _ := NewSQLiteSessionStore(db, testSecret)
+	if err := store.Set("oauth-1", &bskyoauth.Session{DID: "did:plc:test", AccessToken: "token"}); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+
+	other, _ := NewSQLiteSessionStore(db, "a-different-secret-that-is-also-32-characters")
+	if _, err := other.Get("oauth-1"); err == nil {
+		t.Error("Expected decryption to fail with a different secret")
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 	"time"
@@ -15,6 +16,12 @@ type Config struct {
 	Archive   ArchiveConfig   `yaml:"archive"`
 	OAuth     OAuthConfig     `yaml:"oauth"`
 	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Schedule  ScheduleConfig  `yaml:"schedule"`
+	Watchlist WatchlistConfig `yaml:"watchlist"`
+	Access    AccessConfig    `yaml:"access"`
+	Search    SearchConfig    `yaml:"search"`
+	Alerts    AlertsConfig    `yaml:"alerts"`
+	Export    ExportConfig    `yaml:"export"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -72,6 +79,60 @@ type RateLimitConfig struct {
 	Burst             int           `yaml:"burst"`
 }
 
+// ScheduleConfig contains scheduled sync settings
+// Exactly one of Interval or Cron must be set when Enabled is true
+type ScheduleConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"` // Run every interval (e.g. 6h)
+	Cron     string        `yaml:"cron"`     // Cron-like expression: "minute hour day month weekday"
+	Jitter   time.Duration `yaml:"jitter"`   // Random delay added to each run
+	Accounts []string      `yaml:"accounts"` // DIDs to sync; empty means every signed-in account
+}
+
+// WatchlistConfig contains settings for archiving public accounts without logging in as them
+// Entries are fetched through the unauthenticated public AppView
+type WatchlistConfig struct {
+	AppView  string         `yaml:"appview"`  // Public AppView host (default https://public.api.bsky.app)
+	Interval time.Duration  `yaml:"interval"` // Default schedule for entries without their own
+	Jitter   time.Duration  `yaml:"jitter"`   // Random delay added to each run
+	Accounts []WatchAccount `yaml:"accounts"`
+}
+
+// WatchAccount is a single watchlist entry with an optional schedule of its own
+type WatchAccount struct {
+	Actor    string        `yaml:"actor"`    // Handle or DID
+	Interval time.Duration `yaml:"interval"` // Overrides watchlist.interval
+	Cron     string        `yaml:"cron"`     // Cron-like expression; overrides watchlist.interval
+	Filter   string        `yaml:"filter"`   // Author feed filter (posts_with_replies, posts_no_replies, posts_with_media, posts_and_author_threads)
+}
+
+// WatchFilters lists the author feed filters accepted by the public AppView
+var WatchFilters = []string{"posts_with_replies", "posts_no_replies", "posts_with_media", "posts_and_author_threads"}
+
+// AccessConfig controls who may sign in and who administers the instance
+type AccessConfig struct {
+	Admins    []string `yaml:"admins"`    // DIDs with the admin role (always allowed to sign in)
+	Allowlist []string `yaml:"allowlist"` // DIDs or handles allowed to sign in; empty allows anyone
+}
+
+// SearchConfig contains full-text search settings
+type SearchConfig struct {
+	Tokenizer string `yaml:"tokenizer"` // unicode61 (default), prefix or trigram; changing it rebuilds the index at startup
+}
+
+// SearchTokenizers lists the accepted search.tokenizer values
+var SearchTokenizers = []string{"unicode61", "prefix", "trigram"}
+
+// AlertsConfig controls how new saved search matches are delivered
+type AlertsConfig struct {
+	WebhookURL string `yaml:"webhook_url"` // New matches are POSTed here as JSON after archive runs; empty disables
+}
+
+// ExportConfig contains export settings
+type ExportConfig struct {
+	TemplatesDir string `yaml:"templates_dir"` // Directory of custom export templates (*.tmpl); empty disables them
+}
+
 // Load reads configuration from the specified file path
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -137,9 +198,102 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("rate_limit.requests_per_window must be at least 1")
 	}
 
+	// Schedule validation
+	if c.Schedule.Enabled {
+		if c.Schedule.Interval == 0 && c.Schedule.Cron == "" {
+			return fmt.Errorf("schedule.interval or schedule.cron is required when schedule is enabled")
+		}
+		if c.Schedule.Interval != 0 && c.Schedule.Cron != "" {
+			return fmt.Errorf("schedule.interval and schedule.cron cannot both be set")
+		}
+		if c.Schedule.Interval != 0 && c.Schedule.Interval < time.Minute {
+			return fmt.Errorf("schedule.interval must be at least 1m")
+		}
+		if c.Schedule.Jitter < 0 {
+			return fmt.Errorf("schedule.jitter cannot be negative")
+		}
+	}
+
+	// Access validation
+	for i, admin := range c.Access.Admins {
+		if !strings.HasPrefix(admin, "did:") {
+			return fmt.Errorf("access.admins[%d] must be a DID, got %q", i, admin)
+		}
+	}
+
+	// Search validation
+	if c.Search.Tokenizer != "" && !isSearchTokenizer(c.Search.Tokenizer) {
+		return fmt.Errorf("search.tokenizer must be one of %s", strings.Join(SearchTokenizers, ", "))
+	}
+
+	// Alerts validation
+	if c.Alerts.WebhookURL != "" {
+		u, err := url.Parse(c.Alerts.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("alerts.webhook_url must be an http or https URL")
+		}
+	}
+
+	// Watchlist validation
+	if len(c.Watchlist.Accounts) > 0 {
+		if c.Watchlist.Interval != 0 && c.Watchlist.Interval < time.Minute {
+			return fmt.Errorf("watchlist.interval must be at least 1m")
+		}
+		if c.Watchlist.Jitter < 0 {
+			return fmt.Errorf("watchlist.jitter cannot be negative")
+		}
+
+		seen := make(map[string]bool)
+		for i, account := range c.Watchlist.Accounts {
+			if account.Actor == "" {
+				return fmt.Errorf("watchlist.accounts[%d].actor is required", i)
+			}
+			if strings.HasPrefix(account.Actor, "at://") {
+				return fmt.Errorf("watchlist.accounts[%d].actor must be a handle or DID; custom feeds and lists cannot be watched", i)
+			}
+			if seen[account.Actor] {
+				return fmt.Errorf("watchlist.accounts[%d]: %s is listed more than once", i, account.Actor)
+			}
+			seen[account.Actor] = true
+
+			if account.Interval != 0 && account.Cron != "" {
+				return fmt.Errorf("watchlist.accounts[%d]: interval and cron cannot both be set", i)
+			}
+			if account.Interval != 0 && account.Interval < time.Minute {
+				return fmt.Errorf("watchlist.accounts[%d].interval must be at least 1m", i)
+			}
+			if account.Interval == 0 && account.Cron == "" && c.Watchlist.Interval == 0 {
+				return fmt.Errorf("watchlist.accounts[%d] needs an interval or cron (or set watchlist.interval)", i)
+			}
+			if account.Filter != "" && !isWatchFilter(account.Filter) {
+				return fmt.Errorf("watchlist.accounts[%d].filter must be one of %s", i, strings.Join(WatchFilters, ", "))
+			}
+		}
+	}
+
 	return nil
 }
 
+// isSearchTokenizer reports whether tokenizer is a supported search index tokenizer
+func isSearchTokenizer(tokenizer string) bool {
+	for _, t := range SearchTokenizers {
+		if t == tokenizer {
+			return true
+		}
+	}
+	return false
+}
+
+// isWatchFilter reports whether filter is a supported author feed filter
+func isWatchFilter(filter string) bool {
+	for _, f := range WatchFilters {
+		if f == filter {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAddr returns the full server address (host:port)
 func (c *Config) GetAddr() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
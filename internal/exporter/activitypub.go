@@ -0,0 +1,362 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+const (
+	activityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	activityStreamsPublic  = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+// apCollection is an OrderedCollection; the outbox streams its items separately
+type apCollection struct {
+	Context      string        `json:"@context"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	TotalItems   int           `json:"totalItems"`
+	OrderedItems []interface{} `json:"orderedItems"`
+}
+
+// apImage is an actor's avatar or header
+type apImage struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// apActor is actor.json: the account as an ActivityPub Person
+type apActor struct {
+	Context           []interface{} `json:"@context"`
+	ID                string        `json:"id"`
+	Type              string        `json:"type"`
+	PreferredUsername string        `json:"preferredUsername"`
+	Name              string        `json:"name"`
+	Summary           string        `json:"summary"`
+	URL               string        `json:"url"`
+	Icon              *apImage      `json:"icon,omitempty"`
+	Image             *apImage      `json:"image,omitempty"`
+	Outbox            string        `json:"outbox"`
+	Likes             string        `json:"likes"`
+	Bookmarks         string        `json:"bookmarks"`
+	Followers         string        `json:"followers"`
+}
+
+// apActivity is one Create activity of the outbox
+type apActivity struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Actor     string   `json:"actor"`
+	Published string   `json:"published"`
+	To        []string `json:"to"`
+	CC        []string `json:"cc"`
+	Object    apNote   `json:"object"`
+}
+
+// apNote is an archived post as a Note
+type apNote struct {
+	ID           string            `json:"id"`
+	Type         string            `json:"type"`
+	Summary      *string           `json:"summary"`
+	InReplyTo    *string           `json:"inReplyTo"`
+	Published    string            `json:"published"`
+	URL          string            `json:"url"`
+	AttributedTo string            `json:"attributedTo"`
+	To           []string          `json:"to"`
+	CC           []string          `json:"cc"`
+	Sensitive    bool              `json:"sensitive"`
+	Content      string            `json:"content"`
+	ContentMap   map[string]string `json:"contentMap,omitempty"`
+	Attachment   []apAttachment    `json:"attachment"`
+	Tag          []apTag           `json:"tag"`
+}
+
+// apAttachment is a media file; URL is relative to the export directory like
+// the media_attachments paths of a Mastodon archive
+type apAttachment struct {
+	Type      string `json:"type"`
+	MediaType string `json:"mediaType"`
+	URL       string `json:"url"`
+	Name      string `json:"name,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+}
+
+// apTag is a Hashtag or Mention of a Note
+type apTag struct {
+	Type string `json:"type"`
+	Href string `json:"href"`
+	Name string `json:"name"`
+}
+
+// richTextFacet is a stored facet with the byte range of the text it annotates
+type richTextFacet struct {
+	Index struct {
+		ByteStart int `json:"byteStart"`
+		ByteEnd   int `json:"byteEnd"`
+	} `json:"index"`
+	Features []struct {
+		Type string `json:"$type"`
+		URI  string `json:"uri"`
+		Tag  string `json:"tag"`
+		DID  string `json:"did"`
+	} `json:"features"`
+}
+
+// ExportToActivityPub writes the account as a Mastodon-style archive: actor.json built
+// from the latest profile snapshot, outbox.json with a Create/Note activity per post
+// (newest first) and empty likes.json and bookmarks.json. IDs are bsky.app URLs, so
+// replies within the export point at each other. Attachments are listed when media
+// files are part of the export (includeMedia)
+func ExportToActivityPub(db *sql.DB, did string, dateRange *models.DateRange, exportDir string, batchSize int, includeMedia bool) error {
+	actorID := "https://bsky.app/profile/" + did
+	actor := apActor{
+		Context:           []interface{}{activityStreamsContext},
+		ID:                actorID,
+		Type:              "Person",
+		PreferredUsername: did,
+		URL:               actorID,
+		Outbox:            "outbox.json",
+		Likes:             "likes.json",
+		Bookmarks:         "bookmarks.json",
+		Followers:         actorID + "/followers",
+	}
+	if profile, err := storage.GetLatestProfile(db, did); err == nil {
+		actor.PreferredUsername = profile.Handle
+		actor.Name = profile.DisplayName
+		actor.Summary = paragraphs(html.EscapeString(profile.Description))
+		actor.URL = "https://bsky.app/profile/" + profile.Handle
+		if profile.AvatarURL != "" {
+			actor.Icon = &apImage{Type: "Image", URL: profile.AvatarURL}
+		}
+		if profile.BannerURL != "" {
+			actor.Image = &apImage{Type: "Image", URL: profile.BannerURL}
+		}
+	}
+	if err := writeJSONFile(filepath.Join(exportDir, "actor.json"), actor); err != nil {
+		return err
+	}
+	for _, name := range []string{"likes.json", "bookmarks.json"} {
+		empty := apCollection{Context: activityStreamsContext, ID: name, Type: "OrderedCollection", OrderedItems: []interface{}{}}
+		if err := writeJSONFile(filepath.Join(exportDir, name), empty); err != nil {
+			return err
+		}
+	}
+
+	total, err := storage.CountPostsWithDateRange(db, did, dateRange)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filepath.Join(exportDir, "outbox.json"))
+	if err != nil {
+		return fmt.Errorf("failed to create outbox.json: %w", err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	// The collection is written around the streamed items
+	fmt.Fprintf(w, `{"@context":%q,"id":"outbox.json","type":"OrderedCollection","totalItems":%d,"orderedItems":[`, activityStreamsContext, total)
+	var item bytes.Buffer
+	encoder := json.NewEncoder(&item)
+	encoder.SetEscapeHTML(false)
+
+	offset := 0
+	written := 0
+	for {
+		batch, err := storage.ListPostsWithDateRange(db, did, dateRange, batchSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to fetch batch at offset %d: %w", offset, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var mediaMap map[string][]models.Media
+		if includeMedia {
+			uris := make([]string, len(batch))
+			for i, post := range batch {
+				uris[i] = post.URI
+			}
+			if mediaMap, err = storage.ListMediaForPosts(db, uris); err != nil {
+				return err
+			}
+		}
+
+		for _, post := range batch {
+			item.Reset()
+			if err := encoder.Encode(newActivity(post, mediaMap[post.URI], actorID)); err != nil {
+				return fmt.Errorf("failed to encode activity: %w", err)
+			}
+			if written > 0 {
+				w.WriteString(",")
+			}
+			w.WriteString("\n")
+			w.Write(bytes.TrimRight(item.Bytes(), "\n"))
+			written++
+		}
+
+		offset += len(batch)
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	w.WriteString("\n]}\n")
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write outbox.json: %w", err)
+	}
+	return file.Close()
+}
+
+// newActivity converts a post into a Create activity
+func newActivity(post models.Post, media []models.Media, actorID string) apActivity {
+	published := post.CreatedAt.UTC().Format(time.RFC3339)
+	to := []string{activityStreamsPublic}
+	cc := []string{actorID + "/followers"}
+
+	content, tags := noteContent(post)
+	note := apNote{
+		ID:           post.BlueskyURL(),
+		Type:         "Note",
+		Published:    published,
+		URL:          post.BlueskyURL(),
+		AttributedTo: actorID,
+		To:           to,
+		CC:           cc,
+		Content:      content,
+		Attachment:   []apAttachment{},
+		Tag:          tags,
+	}
+	if len(post.Langs) > 0 {
+		note.ContentMap = map[string]string{post.Langs[0]: content}
+	}
+	if post.ReplyParent != "" {
+		parent := blueskyPostURL(post.ReplyParent)
+		note.InReplyTo = &parent
+	}
+	if labels := post.LabelValues(); len(labels) > 0 {
+		summary := strings.Join(labels, ", ")
+		note.Summary = &summary
+		note.Sensitive = true
+	}
+
+	for _, m := range media {
+		note.Attachment = append(note.Attachment, apAttachment{
+			Type:      "Document",
+			MediaType: m.MimeType,
+			URL:       "/media/" + filepath.Base(m.FilePath),
+			Name:      m.AltText,
+			Width:     m.Width,
+			Height:    m.Height,
+		})
+	}
+
+	return apActivity{
+		ID:        note.ID + "/activity",
+		Type:      "Create",
+		Actor:     actorID,
+		Published: published,
+		To:        to,
+		CC:        cc,
+		Object:    note,
+	}
+}
+
+// noteContent renders the post text as HTML, turning link, mention and hashtag facets
+// into anchors the way Mastodon formats statuses. A link card or quote that is not
+// already in the text is appended as a paragraph
+func noteContent(post models.Post) (string, []apTag) {
+	var facets []richTextFacet
+	if len(post.Facets) > 0 {
+		json.Unmarshal(post.Facets, &facets)
+	}
+	sort.Slice(facets, func(i, j int) bool { return facets[i].Index.ByteStart < facets[j].Index.ByteStart })
+
+	text := post.Text
+	tags := []apTag{}
+	var b strings.Builder
+	pos := 0
+	for _, facet := range facets {
+		start, end := facet.Index.ByteStart, facet.Index.ByteEnd
+		if start < pos || end <= start || end > len(text) || len(facet.Features) == 0 {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:start]))
+		segment := html.EscapeString(text[start:end])
+
+		feature := facet.Features[0]
+		switch feature.Type {
+		case "app.bsky.richtext.facet#link":
+			fmt.Fprintf(&b, `<a href="%s" rel="nofollow noopener noreferrer" target="_blank">%s</a>`, html.EscapeString(feature.URI), segment)
+		case "app.bsky.richtext.facet#tag":
+			href := "https://bsky.app/hashtag/" + feature.Tag
+			fmt.Fprintf(&b, `<a href="%s" class="mention hashtag" rel="tag">%s</a>`, html.EscapeString(href), segment)
+			tags = append(tags, apTag{Type: "Hashtag", Href: href, Name: "#" + feature.Tag})
+		case "app.bsky.richtext.facet#mention":
+			href := "https://bsky.app/profile/" + feature.DID
+			fmt.Fprintf(&b, `<span class="h-card"><a href="%s" class="u-url mention">%s</a></span>`, html.EscapeString(href), segment)
+			tags = append(tags, apTag{Type: "Mention", Href: href, Name: text[start:end]})
+		default:
+			b.WriteString(segment)
+		}
+		pos = end
+	}
+	b.WriteString(html.EscapeString(text[pos:]))
+	content := paragraphs(b.String())
+
+	if link := post.Link(); link != nil && !strings.Contains(text, link.URI) {
+		title := link.Title
+		if title == "" {
+			title = link.URI
+		}
+		content += fmt.Sprintf(`<p><a href="%s" rel="nofollow noopener noreferrer" target="_blank">%s</a></p>`, html.EscapeString(link.URI), html.EscapeString(title))
+	}
+	if quote := post.Quote(); quote != nil {
+		url := html.EscapeString(blueskyPostURL(quote.URI))
+		content += fmt.Sprintf(`<p class="quote-inline">RE: <a href="%s">%s</a></p>`, url, url)
+	}
+
+	return content, tags
+}
+
+// paragraphs wraps escaped text in <p> elements: blank lines separate paragraphs and
+// single line breaks become <br />
+func paragraphs(escaped string) string {
+	if strings.TrimSpace(escaped) == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(escaped, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>" + strings.ReplaceAll(paragraph, "\n", "<br />") + "</p>")
+	}
+	return b.String()
+}
+
+// writeJSONFile writes v as indented JSON
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
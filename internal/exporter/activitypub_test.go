@@ -0,0 +1,126 @@
+package exporter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+func TestExportToActivityPub(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:fedi"
+	if err := storage.SaveProfile(db, &models.Profile{DID: did, Handle: "fedi.test", DisplayName: "Fedi",
+		Description: "Hello <world>", AvatarURL: "https://cdn.example/avatar.jpg", SnapshotAt: time.Now()}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+
+	created := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	text := "Hi @friend.test see example.com #gardening\n\nSecond <b>paragraph</b>"
+	posts := []models.Post{
+		{URI: "at://" + did + "/app.bsky.feed.post/3kfirst", Text: text, CreatedAt: created, Langs: []string{"en"},
+			Facets: json.RawMessage(`[
+				{"index":{"byteStart":32,"byteEnd":42},"features":[{"$type":"app.bsky.richtext.facet#tag","tag":"gardening"}]},
+				{"index":{"byteStart":3,"byteEnd":15},"features":[{"$type":"app.bsky.richtext.facet#mention","did":"did:plc:friend"}]},
+				{"index":{"byteStart":20,"byteEnd":31},"features":[{"$type":"app.bsky.richtext.facet#link","uri":"https://example.com"}]}
+			]`),
+			Labels: json.RawMessage(`[{"val":"graphic-media"}]`), HasMedia: true},
+		{URI: "at://" + did + "/app.bsky.feed.post/3kreply", Text: "A reply", CreatedAt: created.Add(time.Hour),
+			IsReply: true, ReplyParent: "at://" + did + "/app.bsky.feed.post/3kfirst"},
+	}
+	for _, post := range posts {
+		post.CID = "cid"
+		post.DID = did
+		post.IndexedAt = post.CreatedAt
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	if err := storage.SaveMedia(db, &models.Media{Hash: strings.Repeat("e", 64), PostURI: posts[0].URI, MimeType: "image/png",
+		FilePath: "media/ee/plant.png", AltText: "A plant", Width: 800, Height: 600, CreatedAt: created}); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	exportDir := t.TempDir()
+	if err := ExportToActivityPub(db, did, nil, exportDir, 1, true); err != nil {
+		t.Fatalf("ExportToActivityPub failed: %v", err)
+	}
+
+	decode := func(name string, v interface{}) {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(exportDir, name))
+		if err != nil {
+			t.Fatalf("Expected %s: %v", name, err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("%s is not valid JSON: %v", name, err)
+		}
+	}
+
+	var actor apActor
+	decode("actor.json", &actor)
+	if actor.Type != "Person" || actor.PreferredUsername != "fedi.test" || actor.Outbox != "outbox.json" {
+		t.Errorf("Unexpected actor: %+v", actor)
+	}
+	if actor.Summary != "<p>Hello &lt;world&gt;</p>" || actor.Icon == nil || actor.Icon.URL != "https://cdn.example/avatar.jpg" {
+		t.Errorf("Unexpected actor profile fields: %+v", actor)
+	}
+
+	var likes apCollection
+	decode("likes.json", &likes)
+	if likes.Type != "OrderedCollection" || likes.TotalItems != 0 {
+		t.Errorf("Unexpected likes.json: %+v", likes)
+	}
+
+	var outbox struct {
+		Type         string       `json:"type"`
+		TotalItems   int          `json:"totalItems"`
+		OrderedItems []apActivity `json:"orderedItems"`
+	}
+	decode("outbox.json", &outbox)
+	if outbox.Type != "OrderedCollection" || outbox.TotalItems != 2 || len(outbox.OrderedItems) != 2 {
+		t.Fatalf("Unexpected outbox: type %s, %d/%d items", outbox.Type, outbox.TotalItems, len(outbox.OrderedItems))
+	}
+
+	reply, first := outbox.OrderedItems[0], outbox.OrderedItems[1]
+	if reply.Type != "Create" || reply.Object.Type != "Note" || reply.Actor != "https://bsky.app/profile/"+did {
+		t.Errorf("Unexpected activity: %+v", reply)
+	}
+	if reply.Object.InReplyTo == nil || *reply.Object.InReplyTo != first.Object.ID {
+		t.Errorf("Reply should point at the parent note %s, got %v", first.Object.ID, reply.Object.InReplyTo)
+	}
+
+	note := first.Object
+	for _, want := range []string{
+		`<p>Hi <span class="h-card"><a href="https://bsky.app/profile/did:plc:friend" class="u-url mention">@friend.test</a></span>`,
+		`<a href="https://example.com" rel="nofollow noopener noreferrer" target="_blank">example.com</a>`,
+		`<a href="https://bsky.app/hashtag/gardening" class="mention hashtag" rel="tag">#gardening</a></p>`,
+		`<p>Second &lt;b&gt;paragraph&lt;/b&gt;</p>`,
+	} {
+		if !strings.Contains(note.Content, want) {
+			t.Errorf("Content missing %q:\n%s", want, note.Content)
+		}
+	}
+	if note.ContentMap["en"] != note.Content {
+		t.Error("Expected contentMap for the post language")
+	}
+	if !note.Sensitive || note.Summary == nil || *note.Summary != "graphic-media" {
+		t.Errorf("Labels should mark the note sensitive: %v %v", note.Sensitive, note.Summary)
+	}
+	if len(note.Tag) != 2 || note.Tag[0].Type != "Mention" || note.Tag[1].Name != "#gardening" {
+		t.Errorf("Unexpected tags: %+v", note.Tag)
+	}
+	if len(note.Attachment) != 1 || note.Attachment[0].URL != "/media/plant.png" || note.Attachment[0].Name != "A plant" {
+		t.Errorf("Unexpected attachments: %+v", note.Attachment)
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/shindakun/bskyarchive/internal/models"
@@ -18,6 +21,11 @@ import (
 // DEPRECATED: This function loads all posts into memory.
 // For large archives, use ExportToCSVBatched instead.
 func ExportToCSV(posts []models.Post, outputPath string) error {
+	cw, err := newCSVWriter(CSVOptions{})
+	if err != nil {
+		return err
+	}
+
 	// Create the CSV file
 	file, err := os.Create(outputPath)
 	if err != nil {
@@ -25,54 +33,164 @@ func ExportToCSV(posts []models.Post, outputPath string) error {
 	}
 	defer file.Close()
 
+	if err := cw.begin(file); err != nil {
+		return err
+	}
+
+	// Write data rows
+	for _, post := range posts {
+		if err := cw.write(&csvPost{post: post}); err != nil {
+			return err
+		}
+	}
+
+	// Ensure all data is written
+	cw.writer.Flush()
+	if err := cw.writer.Error(); err != nil {
+		return fmt.Errorf("CSV writer error: %w", err)
+	}
+
+	return nil
+}
+
+// CSVOptions shapes a CSV export: which columns in which order, the delimiter and the BOM
+type CSVOptions struct {
+	Columns   []string // Names from models.CSVColumns; empty = models.DefaultCSVColumns()
+	Delimiter rune     // Field separator; 0 = comma
+	NoBOM     bool     // Leave out the UTF-8 BOM
+}
+
+// csvDelimiters maps the delimiter names of export options to field separators
+var csvDelimiters = map[string]rune{
+	"":                           ',',
+	models.CSVDelimiterComma:     ',',
+	models.CSVDelimiterTab:       '\t',
+	models.CSVDelimiterSemicolon: ';',
+}
+
+// csvOptionsFrom reads the CSV options of an export request
+func csvOptionsFrom(opts models.ExportOptions) CSVOptions {
+	return CSVOptions{
+		Columns:   opts.CSVColumns,
+		Delimiter: csvDelimiters[opts.CSVDelimiter],
+		NoBOM:     opts.CSVNoBOM,
+	}
+}
+
+// csvPost is a post with the data its optional columns need
+type csvPost struct {
+	post   models.Post
+	handle string
+	media  []models.Media
+}
+
+// csvColumnValues formats each column of models.CSVColumns for a post
+var csvColumnValues = map[string]func(p *csvPost) string{
+	"URI":         func(p *csvPost) string { return p.post.URI },
+	"CID":         func(p *csvPost) string { return p.post.CID },
+	"DID":         func(p *csvPost) string { return p.post.DID },
+	"Handle":      func(p *csvPost) string { return p.handle },
+	"Text":        func(p *csvPost) string { return p.post.Text },
+	"CreatedAt":   func(p *csvPost) string { return p.post.CreatedAt.Format("2006-01-02T15:04:05Z07:00") },
+	"PostURL":     func(p *csvPost) string { return p.post.BlueskyURL() },
+	"LikeCount":   func(p *csvPost) string { return strconv.Itoa(p.post.LikeCount) },
+	"RepostCount": func(p *csvPost) string { return strconv.Itoa(p.post.RepostCount) },
+	"ReplyCount":  func(p *csvPost) string { return strconv.Itoa(p.post.ReplyCount) },
+	"QuoteCount":  func(p *csvPost) string { return strconv.Itoa(p.post.QuoteCount) },
+	"IsReply":     func(p *csvPost) string { return strconv.FormatBool(p.post.IsReply) },
+	"ReplyParent": func(p *csvPost) string { return p.post.ReplyParent },
+	"HasMedia":    func(p *csvPost) string { return strconv.FormatBool(p.post.HasMedia) },
+	"MediaFiles":  func(p *csvPost) string { return getMediaFilesList(p.post) },
+	"MediaPaths": func(p *csvPost) string {
+		paths := make([]string, len(p.media))
+		for i, m := range p.media {
+			// Where the export copies the file when media is included
+			paths[i] = "media/" + filepath.Base(m.FilePath)
+		}
+		return strings.Join(paths, ";")
+	},
+	"AltTexts": func(p *csvPost) string {
+		var alts []string
+		for _, m := range p.media {
+			if m.AltText != "" {
+				alts = append(alts, m.AltText)
+			}
+		}
+		return strings.Join(alts, ";")
+	},
+	"LinkURLs":  func(p *csvPost) string { return strings.Join(p.post.LinkURLs(), ";") },
+	"Hashtags":  func(p *csvPost) string { return strings.Join(p.post.Tags(), ";") },
+	"EmbedType": func(p *csvPost) string { return p.post.EmbedType },
+	"IndexedAt": func(p *csvPost) string { return p.post.IndexedAt.Format("2006-01-02T15:04:05Z07:00") },
+}
+
+// csvWriter writes posts as CSV rows of the columns chosen in CSVOptions
+type csvWriter struct {
+	opts        CSVOptions
+	columns     []string
+	values      []func(p *csvPost) string
+	row         []string
+	needsMedia  bool // MediaPaths or AltTexts need the posts' media
+	needsHandle bool // Handle needs the archive's profile
+	writer      *csv.Writer
+}
+
+// newCSVWriter resolves the columns of opts; no columns means models.DefaultCSVColumns()
+func newCSVWriter(opts CSVOptions) (*csvWriter, error) {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = models.DefaultCSVColumns()
+	}
+
+	cw := &csvWriter{
+		opts:    opts,
+		columns: columns,
+		values:  make([]func(p *csvPost) string, len(columns)),
+		row:     make([]string, len(columns)),
+	}
+	for i, name := range columns {
+		value, ok := csvColumnValues[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column: %s", name)
+		}
+		cw.values[i] = value
+		cw.needsMedia = cw.needsMedia || name == "MediaPaths" || name == "AltTexts"
+		cw.needsHandle = cw.needsHandle || name == "Handle"
+	}
+
+	return cw, nil
+}
+
+// begin writes the BOM and header row to w
+func (cw *csvWriter) begin(w io.Writer) error {
 	// Write UTF-8 BOM for Excel compatibility
 	// Excel requires BOM to correctly detect UTF-8 encoding
-	if _, err := file.WriteString("\xEF\xBB\xBF"); err != nil {
-		return fmt.Errorf("failed to write BOM: %w", err)
+	if !cw.opts.NoBOM {
+		if _, err := io.WriteString(w, "\xEF\xBB\xBF"); err != nil {
+			return fmt.Errorf("failed to write BOM: %w", err)
+		}
 	}
 
-	// Create CSV writer
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header row with 15 columns
-	header := []string{
-		"URI",
-		"CID",
-		"DID",
-		"Text",
-		"CreatedAt",
-		"LikeCount",
-		"RepostCount",
-		"ReplyCount",
-		"QuoteCount",
-		"IsReply",
-		"ReplyParent",
-		"HasMedia",
-		"MediaFiles",
-		"EmbedType",
-		"IndexedAt",
+	cw.writer = csv.NewWriter(w)
+	if cw.opts.Delimiter != 0 {
+		cw.writer.Comma = cw.opts.Delimiter
 	}
 
-	if err := writer.Write(header); err != nil {
+	if err := cw.writer.Write(cw.columns); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
-	// Write data rows
-	for _, post := range posts {
-		row, err := postToCSVRow(post)
-		if err != nil {
-			return fmt.Errorf("failed to convert post to CSV row: %w", err)
-		}
+	return nil
+}
 
-		if err := writer.Write(row); err != nil {
-			return fmt.Errorf("failed to write CSV row: %w", err)
-		}
+// write writes one post as a row
+func (cw *csvWriter) write(p *csvPost) error {
+	for i, value := range cw.values {
+		cw.row[i] = value(p)
 	}
 
-	// Ensure all data is written
-	if err := writer.Error(); err != nil {
-		return fmt.Errorf("CSV writer error: %w", err)
+	if err := cw.writer.Write(cw.row); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
 	}
 
 	return nil
@@ -81,44 +199,35 @@ func ExportToCSV(posts []models.Post, outputPath string) error {
 // ExportToCSVBatched exports posts to CSV using batched streaming writes
 // This prevents memory exhaustion on large archives by processing posts in batches
 func ExportToCSVBatched(db *sql.DB, did string, dateRange *models.DateRange, outputPath string, batchSize int) error {
-	// Create the CSV file
-	file, err := os.Create(outputPath)
+	return ExportToCSVWithOptions(db, did, dateRange, outputPath, batchSize, CSVOptions{})
+}
+
+// ExportToCSVWithOptions exports posts to CSV in batches with the chosen columns,
+// delimiter and BOM. The zero CSVOptions writes the standard 15 columns
+func ExportToCSVWithOptions(db *sql.DB, did string, dateRange *models.DateRange, outputPath string, batchSize int, opts CSVOptions) error {
+	cw, err := newCSVWriter(opts)
 	if err != nil {
-		return fmt.Errorf("failed to create CSV file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	// Write UTF-8 BOM for Excel compatibility
-	if _, err := file.WriteString("\xEF\xBB\xBF"); err != nil {
-		return fmt.Errorf("failed to write BOM: %w", err)
+	handle := ""
+	if cw.needsHandle {
+		if profile, err := storage.GetLatestProfile(db, did); err == nil {
+			handle = profile.Handle
+		}
 	}
 
-	// Create CSV writer
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header row
-	header := []string{
-		"URI",
-		"CID",
-		"DID",
-		"Text",
-		"CreatedAt",
-		"LikeCount",
-		"RepostCount",
-		"ReplyCount",
-		"QuoteCount",
-		"IsReply",
-		"ReplyParent",
-		"HasMedia",
-		"MediaFiles",
-		"EmbedType",
-		"IndexedAt",
+	// Create the CSV file
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV file: %w", err)
 	}
+	defer file.Close()
 
-	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("failed to write CSV header: %w", err)
+	if err := cw.begin(file); err != nil {
+		return err
 	}
+	defer cw.writer.Flush()
 
 	// Process posts in batches
 	offset := 0
@@ -135,21 +244,27 @@ func ExportToCSVBatched(db *sql.DB, did string, dateRange *models.DateRange, out
 			break
 		}
 
-		// Write each post in the batch
-		for _, post := range batch {
-			row, err := postToCSVRow(post)
-			if err != nil {
-				return fmt.Errorf("failed to convert post to CSV row: %w", err)
+		mediaMap := map[string][]models.Media{}
+		if cw.needsMedia {
+			uris := make([]string, len(batch))
+			for i, post := range batch {
+				uris[i] = post.URI
+			}
+			if mediaMap, err = storage.ListMediaForPosts(db, uris); err != nil {
+				return err
 			}
+		}
 
-			if err := writer.Write(row); err != nil {
-				return fmt.Errorf("failed to write CSV row: %w", err)
+		// Write each post in the batch
+		for _, post := range batch {
+			if err := cw.write(&csvPost{post: post, handle: handle, media: mediaMap[post.URI]}); err != nil {
+				return err
 			}
 		}
 
 		// Flush after each batch to ensure data is written to disk
-		writer.Flush()
-		if err := writer.Error(); err != nil {
+		cw.writer.Flush()
+		if err := cw.writer.Error(); err != nil {
 			return fmt.Errorf("CSV writer error after batch: %w", err)
 		}
 
@@ -164,52 +279,6 @@ func ExportToCSVBatched(db *sql.DB, did string, dateRange *models.DateRange, out
 	return nil
 }
 
-// postToCSVRow converts a Post model to a CSV row
-// Returns a slice of strings representing the row values
-func postToCSVRow(post models.Post) ([]string, error) {
-	// Format timestamps in ISO 8601 format
-	createdAt := post.CreatedAt.Format("2006-01-02T15:04:05Z07:00")
-	indexedAt := post.IndexedAt.Format("2006-01-02T15:04:05Z07:00")
-
-	// Convert boolean to string
-	isReply := "false"
-	if post.IsReply {
-		isReply = "true"
-	}
-
-	hasMedia := "false"
-	if post.HasMedia {
-		hasMedia = "true"
-	}
-
-	// Format reply parent (empty if not a reply)
-	replyParent := post.ReplyParent
-
-	// Get media files as semicolon-separated list
-	mediaFiles := getMediaFilesList(post)
-
-	// Build row with all columns
-	row := []string{
-		post.URI,
-		post.CID,
-		post.DID,
-		post.Text,
-		createdAt,
-		fmt.Sprintf("%d", post.LikeCount),
-		fmt.Sprintf("%d", post.RepostCount),
-		fmt.Sprintf("%d", post.ReplyCount),
-		fmt.Sprintf("%d", post.QuoteCount),
-		isReply,
-		replyParent,
-		hasMedia,
-		mediaFiles,
-		post.EmbedType,
-		indexedAt,
-	}
-
-	return row, nil
-}
-
 // getMediaFilesList extracts media file hashes from post and returns semicolon-separated list
 func getMediaFilesList(post models.Post) string {
 	if !post.HasMedia || len(post.EmbedData) == 0 {
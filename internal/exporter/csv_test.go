@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -42,7 +43,9 @@ func setupCSVTestDB(t *testing.T) *sql.DB {
 			embed_type TEXT,
 			embed_data JSON,
 			labels JSON,
-			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			langs JSON,
+			facets JSON
 		);
 
 		CREATE INDEX idx_posts_did ON posts(did);
@@ -373,6 +376,75 @@ func TestExportToCSVBatched_EmptyResult(t *testing.T) {
 	t.Log("✓ Empty result produces valid CSV with header only")
 }
 
+// TestExportToCSVWithOptions verifies chosen columns, the tab delimiter and no BOM
+func TestExportToCSVWithOptions(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:csvoptions"
+	if err := storage.SaveProfile(db, &models.Profile{DID: did, Handle: "sheets.test", SnapshotAt: time.Now()}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+	post := models.Post{
+		URI:       "at://" + did + "/app.bsky.feed.post/3kcsv",
+		CID:       "cid",
+		DID:       did,
+		Text:      "Read https://example.com #data #bi",
+		CreatedAt: time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC),
+		IndexedAt: time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC),
+		HasMedia:  true,
+		EmbedType: "external",
+		EmbedData: []byte(`{"external":{"uri":"https://blog.example/post","title":"Post"}}`),
+		Facets: []byte(`[{"features":[{"$type":"app.bsky.richtext.facet#link","uri":"https://example.com"}]},
+			{"features":[{"$type":"app.bsky.richtext.facet#tag","tag":"data"}]},
+			{"features":[{"$type":"app.bsky.richtext.facet#tag","tag":"bi"}]}]`),
+	}
+	if err := storage.SavePost(db, &post); err != nil {
+		t.Fatalf("SavePost failed: %v", err)
+	}
+	for _, m := range []models.Media{
+		{Hash: strings.Repeat("a", 64), FilePath: "media/aa/bb/chart.png", AltText: "Bar chart"},
+		{Hash: strings.Repeat("b", 64), FilePath: "media/cc/dd/table.png", AltText: "Table"},
+	} {
+		m.PostURI = post.URI
+		m.MimeType = "image/png"
+		m.CreatedAt = post.CreatedAt
+		if err := storage.SaveMedia(db, &m); err != nil {
+			t.Fatalf("SaveMedia failed: %v", err)
+		}
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "posts.csv")
+	opts := CSVOptions{
+		Columns:   []string{"Handle", "PostURL", "Hashtags", "LinkURLs", "AltTexts", "MediaPaths", "LikeCount"},
+		Delimiter: '\t',
+		NoBOM:     true,
+	}
+	if err := ExportToCSVWithOptions(db, did, nil, outputPath, 100, opts); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	want := "Handle\tPostURL\tHashtags\tLinkURLs\tAltTexts\tMediaPaths\tLikeCount\n" +
+		"sheets.test\thttps://bsky.app/profile/did:plc:csvoptions/post/3kcsv\tdata;bi\thttps://example.com;https://blog.example/post\tBar chart;Table\tmedia/chart.png;media/table.png\t0\n"
+	if string(content) != want {
+		t.Errorf("Unexpected CSV:\n%q\nwant:\n%q", content, want)
+	}
+
+	// Every column offered for export has a value
+	for _, column := range models.CSVColumns {
+		if _, ok := csvColumnValues[column.Name]; !ok {
+			t.Errorf("Column %s has no value", column.Name)
+		}
+	}
+}
+
 // readCSVFile is a helper to read and parse CSV files
 func readCSVFile(t *testing.T, path string) [][]string {
 	t.Helper()
@@ -0,0 +1,354 @@
+package exporter
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"embed"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"html/template"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+	"github.com/shindakun/bskyarchive/internal/version"
+)
+
+// epubFiles holds the templates and static files of the EPUB export
+//
+//go:embed epub
+var epubFiles embed.FS
+
+var epubTemplates = template.Must(template.ParseFS(epubFiles, "epub/*.xhtml", "epub/*.opf"))
+
+// epubPageSize is the number of posts fetched at a time, oldest first
+const epubPageSize = 100
+
+// epubBook describes the whole book for the title page, contents and package document
+type epubBook struct {
+	Identifier  string
+	Title       string
+	Author      string
+	Handle      string
+	Description template.HTML
+	Language    string
+	Period      string
+	Total       int
+	Version     string
+	ExportedAt  time.Time
+	Modified    string
+	Chapters    []epubChapter
+	Images      []epubImage
+}
+
+// epubChapter is the chapter of one month
+type epubChapter struct {
+	ID    string
+	File  string // YYYY-MM.xhtml, relative to OEBPS/
+	Title string
+	Count int
+}
+
+// epubImage is an image copied from the media store into the book
+type epubImage struct {
+	ID        string
+	Src       string // images/{name}, relative to OEBPS/
+	Alt       string
+	MediaType string
+	path      string // File in the media store
+}
+
+// epubPost is a post with its rendered content and the images of the book it shows
+type epubPost struct {
+	models.Post
+	Anchor     string
+	Content    template.HTML
+	ParentLink string // Chapter and anchor of the reply parent when it is in the book
+	Images     []epubImage
+	Omitted    []string // Media that cannot be shown in the book
+}
+
+// epubPage is the data passed to every EPUB template
+type epubPage struct {
+	Book  *epubBook
+	Title string
+	Posts []epubPost
+}
+
+// epubWriter streams the files of an EPUB into a ZIP archive
+type epubWriter struct {
+	zip      *zip.Writer
+	book     *epubBook
+	exported map[string]time.Time // URI -> created_at of every post in the book
+	images   map[string]bool      // Image names already in the book
+}
+
+// ExportToEPUB writes the posts in the date range as an EPUB 3 book: a title page with
+// the latest profile, a table of contents and one chapter per month with posts oldest
+// first. Images are copied from the media store into the book, so it reads offline.
+// Chapters are written as each month completes, so only one month is held in memory
+func ExportToEPUB(db *sql.DB, did string, dateRange *models.DateRange, path string) error {
+	exported, err := storage.ListPostDatesWithDateRange(db, did, dateRange)
+	if err != nil {
+		return err
+	}
+
+	book := &epubBook{
+		Identifier: "at://" + did,
+		Title:      "Posts by " + did,
+		Author:     did,
+		Language:   "en",
+		Total:      len(exported),
+		Version:    version.GetVersion(),
+		ExportedAt: time.Now(),
+		Modified:   time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+	if profile, err := storage.GetLatestProfile(db, did); err == nil {
+		book.Handle = profile.Handle
+		book.Author = "@" + profile.Handle
+		if profile.DisplayName != "" {
+			book.Author = profile.DisplayName
+		}
+		book.Title = "Posts by " + book.Author
+		book.Description = template.HTML(paragraphs(html.EscapeString(profile.Description)))
+	}
+	var first, last time.Time
+	for _, created := range exported {
+		if first.IsZero() || created.Before(first) {
+			first = created
+		}
+		if created.After(last) {
+			last = created
+		}
+	}
+	book.Period = epubPeriod(first.UTC(), last.UTC())
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	w := &epubWriter{zip: zip.NewWriter(file), book: book, exported: exported, images: make(map[string]bool)}
+	if err := w.writeMimetype(); err != nil {
+		return err
+	}
+	container, err := epubFiles.ReadFile("epub/container.xml")
+	if err != nil {
+		return fmt.Errorf("failed to read container.xml: %w", err)
+	}
+	style, err := epubFiles.ReadFile("epub/style.css")
+	if err != nil {
+		return fmt.Errorf("failed to read style.css: %w", err)
+	}
+	if err := w.writeFile("META-INF/container.xml", container); err != nil {
+		return err
+	}
+	if err := w.writeFile("OEBPS/style.css", style); err != nil {
+		return err
+	}
+
+	// Posts arrive oldest first, so each month's posts are consecutive
+	filter := models.PostFilter{}
+	if dateRange != nil {
+		filter.Since = dateRange.StartDate
+		if !dateRange.EndDate.IsZero() {
+			// Date ranges include their end; post filters exclude it
+			filter.Until = dateRange.EndDate.Add(time.Second)
+		}
+	}
+	var month []epubPost
+	after := ""
+	for {
+		page, err := storage.ListPosts(db, did, models.PostListOptions{Filter: filter, Sort: models.PostSortOldest, Limit: epubPageSize, After: after})
+		if err != nil {
+			return err
+		}
+
+		uris := make([]string, len(page.Posts))
+		for i, post := range page.Posts {
+			uris[i] = post.URI
+		}
+		mediaMap, err := storage.ListMediaForPosts(db, uris)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range page.Posts {
+			post.CreatedAt = post.CreatedAt.UTC()
+			if len(month) > 0 && epubChapterFile(month[0].CreatedAt) != epubChapterFile(post.CreatedAt) {
+				if err := w.writeChapter(month); err != nil {
+					return err
+				}
+				month = nil
+			}
+			month = append(month, w.newPost(post, mediaMap[post.URI]))
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		after = page.NextCursor
+	}
+	if len(month) > 0 {
+		if err := w.writeChapter(month); err != nil {
+			return err
+		}
+	}
+
+	// The title page, contents and package document list every chapter, so they come last
+	for _, doc := range []struct {
+		name     string
+		template string
+		title    string
+	}{
+		{"OEBPS/title.xhtml", "title", book.Title},
+		{"OEBPS/nav.xhtml", "nav", "Contents"},
+		{"OEBPS/content.opf", "opf", ""},
+	} {
+		if err := w.writeTemplate(doc.name, doc.template, epubPage{Book: book, Title: doc.title}); err != nil {
+			return err
+		}
+	}
+
+	if err := w.zip.Close(); err != nil {
+		return fmt.Errorf("failed to finish %s: %w", filepath.Base(path), err)
+	}
+	return file.Close()
+}
+
+// newPost prepares a post for its chapter; images missing from the media store and
+// other media are mentioned instead of shown
+func (w *epubWriter) newPost(post models.Post, media []models.Media) epubPost {
+	content, _ := noteContent(post)
+	p := epubPost{Post: post, Anchor: "post-" + blockID(post.RKey()), Content: template.HTML(content)}
+
+	if created, ok := w.exported[post.ReplyParent]; ok {
+		rkey := post.ReplyParent[strings.LastIndex(post.ReplyParent, "/")+1:]
+		p.ParentLink = epubChapterFile(created.UTC()) + "#post-" + blockID(rkey)
+	}
+
+	for _, m := range media {
+		if !isImageFile(m.FilePath) {
+			p.Omitted = append(p.Omitted, fmt.Sprintf("Attachment not included (%s)", m.MimeType))
+			continue
+		}
+		if info, err := os.Stat(m.FilePath); err != nil || info.IsDir() {
+			if m.AltText != "" {
+				p.Omitted = append(p.Omitted, "Image: "+m.AltText)
+			}
+			continue
+		}
+		mediaType := m.MimeType
+		if mediaType == "" {
+			mediaType = mime.TypeByExtension(filepath.Ext(m.FilePath))
+		}
+		p.Images = append(p.Images, epubImage{
+			Src:       "images/" + filepath.Base(m.FilePath),
+			Alt:       m.AltText,
+			MediaType: mediaType,
+			path:      m.FilePath,
+		})
+	}
+	return p
+}
+
+// writeChapter writes the chapter of one month followed by the images it shows
+func (w *epubWriter) writeChapter(posts []epubPost) error {
+	created := posts[0].CreatedAt
+	chapter := epubChapter{
+		ID:    "month-" + created.Format("2006-01"),
+		File:  epubChapterFile(created),
+		Title: created.Format("January 2006"),
+		Count: len(posts),
+	}
+	if err := w.writeTemplate("OEBPS/"+chapter.File, "chapter", epubPage{Book: w.book, Title: chapter.Title, Posts: posts}); err != nil {
+		return err
+	}
+	w.book.Chapters = append(w.book.Chapters, chapter)
+
+	for _, post := range posts {
+		for _, image := range post.Images {
+			if w.images[image.Src] {
+				continue
+			}
+			w.images[image.Src] = true
+			if err := addFileToZIP(w.zip, image.path, "OEBPS/"+image.Src); err != nil {
+				return err
+			}
+			image.ID = fmt.Sprintf("image-%d", len(w.book.Images)+1)
+			w.book.Images = append(w.book.Images, image)
+		}
+	}
+	return nil
+}
+
+// writeMimetype writes the mimetype file, which must come first and be stored
+// uncompressed without a data descriptor so readers can identify the book
+func (w *epubWriter) writeMimetype() error {
+	mimetype := []byte("application/epub+zip")
+	header := &zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	}
+	entry, err := w.zip.CreateRaw(header)
+	if err != nil {
+		return fmt.Errorf("failed to create mimetype: %w", err)
+	}
+	if _, err := entry.Write(mimetype); err != nil {
+		return fmt.Errorf("failed to write mimetype: %w", err)
+	}
+	return nil
+}
+
+// writeFile writes one file of the book
+func (w *epubWriter) writeFile(name string, content []byte) error {
+	entry, err := w.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: w.book.ExportedAt})
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if _, err := entry.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeTemplate renders one EPUB template as an XML document
+func (w *epubWriter) writeTemplate(name, tmpl string, page epubPage) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := epubTemplates.ExecuteTemplate(&buf, tmpl, page); err != nil {
+		return fmt.Errorf("failed to render %s: %w", filepath.Base(name), err)
+	}
+	return w.writeFile(name, buf.Bytes())
+}
+
+// epubChapterFile is the chapter file of the month a post was created in
+func epubChapterFile(created time.Time) string {
+	return created.Format("2006-01") + ".xhtml"
+}
+
+// epubPeriod describes the months a book covers, e.g. "January 2024 – March 2024"
+func epubPeriod(first, last time.Time) string {
+	if first.IsZero() {
+		return "No posts"
+	}
+	from, to := first.Format("January 2006"), last.Format("January 2006")
+	if from == to {
+		return from
+	}
+	if first.Year() == last.Year() && first.Month() == time.January && last.Month() == time.December {
+		return first.Format("2006")
+	}
+	return from + " – " + to
+}
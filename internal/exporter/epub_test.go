@@ -0,0 +1,143 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+func TestExportToEPUB(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:book"
+	if err := storage.SaveProfile(db, &models.Profile{DID: did, Handle: "book.test", DisplayName: "Bookish",
+		Description: "Reader & writer", SnapshotAt: time.Now()}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+
+	uri := func(rkey string) string { return "at://" + did + "/app.bsky.feed.post/" + rkey }
+	jan := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	posts := []models.Post{
+		{URI: uri("3kjan"), Text: "New year <resolutions>", CreatedAt: jan, HasMedia: true},
+		{URI: uri("3kfeb"), Text: "Following up", CreatedAt: jan.AddDate(0, 1, 0), IsReply: true, ReplyParent: uri("3kjan")},
+		{URI: uri("3kdec"), Text: "Year in review", CreatedAt: time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC), HasMedia: true},
+		{URI: uri("3klate"), Text: "Next year", CreatedAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, post := range posts {
+		post.CID = "cid"
+		post.DID = did
+		post.IndexedAt = post.CreatedAt
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+
+	imagePath := filepath.Join(t.TempDir(), "snow.jpg")
+	if err := os.WriteFile(imagePath, []byte("jpeg data"), 0644); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	for _, m := range []models.Media{
+		{Hash: strings.Repeat("a", 64), PostURI: uri("3kjan"), MimeType: "image/jpeg", FilePath: imagePath, AltText: "Snow"},
+		{Hash: strings.Repeat("b", 64), PostURI: uri("3kdec"), MimeType: "video/mp4", FilePath: "media/bb/clip.mp4"},
+	} {
+		m.CreatedAt = jan
+		if err := storage.SaveMedia(db, &m); err != nil {
+			t.Fatalf("SaveMedia failed: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "posts.epub")
+	dateRange := &models.DateRange{StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)}
+	if err := ExportToEPUB(db, did, dateRange, path); err != nil {
+		t.Fatalf("ExportToEPUB failed: %v", err)
+	}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Book is not a ZIP archive: %v", err)
+	}
+	defer reader.Close()
+
+	first := reader.File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store || first.Flags&0x8 != 0 {
+		t.Errorf("mimetype must be the first, stored entry without a data descriptor: %s method %d flags %x", first.Name, first.Method, first.Flags)
+	}
+
+	files := make(map[string]string)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+
+		// Every document must be well-formed XML for e-readers
+		if strings.HasSuffix(f.Name, ".xhtml") || strings.HasSuffix(f.Name, ".opf") || strings.HasSuffix(f.Name, ".xml") {
+			decoder := xml.NewDecoder(strings.NewReader(string(content)))
+			for {
+				if _, err := decoder.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("%s is not well-formed: %v\n%s", f.Name, err, content)
+				}
+			}
+		}
+	}
+	if files["mimetype"] != "application/epub+zip" {
+		t.Errorf("Unexpected mimetype: %q", files["mimetype"])
+	}
+
+	for _, name := range []string{"META-INF/container.xml", "OEBPS/2024-01.xhtml", "OEBPS/2024-02.xhtml", "OEBPS/2024-12.xhtml", "OEBPS/images/snow.jpg"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Book is missing %s", name)
+		}
+	}
+	if _, ok := files["OEBPS/2025-01.xhtml"]; ok {
+		t.Error("Posts after the date range should not be in the book")
+	}
+
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		"<dc:title>Posts by Bookish</dc:title>",
+		`<item id="image-1" href="images/snow.jpg" media-type="image/jpeg"/>`,
+		`<itemref idref="month-2024-01"/>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("content.opf missing %q:\n%s", want, opf)
+		}
+	}
+	if !strings.Contains(files["OEBPS/nav.xhtml"], `<a href="2024-02.xhtml">February 2024</a> <span class="count">(1)</span>`) {
+		t.Errorf("Contents should list each month:\n%s", files["OEBPS/nav.xhtml"])
+	}
+	title := files["OEBPS/title.xhtml"]
+	if !strings.Contains(title, "<p>Reader &amp; writer</p>") || !strings.Contains(title, `<p class="period">2024</p>`) {
+		t.Errorf("Title page should show the profile and period:\n%s", title)
+	}
+
+	jan24 := files["OEBPS/2024-01.xhtml"]
+	for _, want := range []string{"New year &lt;resolutions&gt;", `<img src="images/snow.jpg" alt="Snow" />`, `id="post-3kjan"`} {
+		if !strings.Contains(jan24, want) {
+			t.Errorf("January chapter missing %q:\n%s", want, jan24)
+		}
+	}
+	if !strings.Contains(files["OEBPS/2024-02.xhtml"], `<a href="2024-01.xhtml#post-3kjan">an earlier post</a>`) {
+		t.Errorf("Reply should link to its parent's chapter:\n%s", files["OEBPS/2024-02.xhtml"])
+	}
+	if !strings.Contains(files["OEBPS/2024-12.xhtml"], "Attachment not included (video/mp4)") {
+		t.Errorf("Video should be mentioned:\n%s", files["OEBPS/2024-12.xhtml"])
+	}
+}
@@ -112,24 +112,8 @@ func Run(db *sql.DB, job *models.ExportJob, progressChan chan<- models.ExportPro
 	// Step 2: Count total posts for progress tracking
 	log.Printf("Counting posts for export (DID: %s)", job.Options.DID)
 
-	// Build count query with same filters as export
-	countQuery := "SELECT COUNT(*) FROM posts WHERE did = ?"
-	args := []interface{}{job.Options.DID}
-
-	// Add date range filters if specified
-	if job.Options.DateRange != nil {
-		if !job.Options.DateRange.StartDate.IsZero() {
-			countQuery += " AND created_at >= ?"
-			args = append(args, job.Options.DateRange.StartDate)
-		}
-		if !job.Options.DateRange.EndDate.IsZero() {
-			countQuery += " AND created_at <= ?"
-			args = append(args, job.Options.DateRange.EndDate)
-		}
-	}
-
-	var totalPosts int
-	if err := db.QueryRow(countQuery, args...).Scan(&totalPosts); err != nil {
+	totalPosts, err := storage.CountPostsWithDateRange(db, job.Options.DID, job.Options.DateRange)
+	if err != nil {
 		job.Progress.Status = models.ExportStatusFailed
 		job.Progress.Error = fmt.Sprintf("Failed to count posts: %v", err)
 		progressChan <- job.Progress
@@ -162,33 +146,81 @@ func Run(db *sql.DB, job *models.ExportJob, progressChan chan<- models.ExportPro
 		return nil // Not an error - just empty
 	}
 
-	// Step 3: Export posts to JSON or CSV using batched streaming
+	// Step 3: Export posts in the chosen format using batched streaming
 	var dataFile string
 	const batchSize = 1000 // Process 1000 posts at a time
 
-	if job.Options.Format == models.ExportFormatJSON {
+	// what names the export in the failure message
+	var what string
+	switch job.Options.Format {
+	case models.ExportFormatJSON:
 		dataFile = filepath.Join(exportDir, "posts.json")
+		what = "JSON"
 		log.Printf("Starting batched JSON export (batch size: %d)", batchSize)
-		if err := ExportToJSONBatched(db, job.Options.DID, job.Options.DateRange, dataFile, batchSize); err != nil {
-			job.Progress.Status = models.ExportStatusFailed
-			job.Progress.Error = fmt.Sprintf("Failed to export JSON: %v", err)
-			progressChan <- job.Progress
-			return err
-		}
-	} else if job.Options.Format == models.ExportFormatCSV {
+		err = ExportToJSONBatched(db, job.Options.DID, job.Options.DateRange, dataFile, batchSize)
+	case models.ExportFormatCSV:
 		dataFile = filepath.Join(exportDir, "posts.csv")
+		what = "CSV"
 		log.Printf("Starting batched CSV export (batch size: %d)", batchSize)
-		if err := ExportToCSVBatched(db, job.Options.DID, job.Options.DateRange, dataFile, batchSize); err != nil {
-			job.Progress.Status = models.ExportStatusFailed
-			job.Progress.Error = fmt.Sprintf("Failed to export CSV: %v", err)
-			progressChan <- job.Progress
-			return err
+		err = ExportToCSVWithOptions(db, job.Options.DID, job.Options.DateRange, dataFile, batchSize, csvOptionsFrom(job.Options))
+	case models.ExportFormatHTML:
+		dataFile = filepath.Join(exportDir, "index.html")
+		what = "HTML site"
+		log.Printf("Starting batched HTML site export (batch size: %d)", batchSize)
+		err = ExportToHTMLSite(db, job.Options.DID, job.Options.DateRange, exportDir, batchSize, job.Options.IncludeMedia)
+	case models.ExportFormatJSONL:
+		dataFile = filepath.Join(exportDir, "posts.jsonl")
+		what = "JSON Lines"
+		log.Printf("Starting batched JSON Lines export (batch size: %d)", batchSize)
+		err = ExportToJSONLBatched(db, job.Options.DID, job.Options.DateRange, exportDir, batchSize, job.Options.Companions)
+	case models.ExportFormatSQLite:
+		dataFile = filepath.Join(exportDir, "archive.db")
+		what = "SQLite database"
+		log.Printf("Starting SQLite database export")
+		_, err = storage.ExportDatabase(db, dataFile, job.Options.DID, job.Options.DateRange)
+	case models.ExportFormatMarkdown:
+		dataFile = filepath.Join(exportDir, "posts")
+		if job.Options.MarkdownByDay {
+			dataFile = filepath.Join(exportDir, "days")
 		}
-	} else {
+		what = "Markdown"
+		log.Printf("Starting batched Markdown export (batch size: %d)", batchSize)
+		err = ExportToMarkdown(db, job.Options.DID, job.Options.DateRange, exportDir, batchSize, job.Options.MarkdownByDay, job.Options.IncludeMedia)
+	case models.ExportFormatActivityPub:
+		dataFile = filepath.Join(exportDir, "outbox.json")
+		what = "ActivityPub outbox"
+		log.Printf("Starting batched ActivityPub export (batch size: %d)", batchSize)
+		err = ExportToActivityPub(db, job.Options.DID, job.Options.DateRange, exportDir, batchSize, job.Options.IncludeMedia)
+	case models.ExportFormatFeed:
+		dataFile = filepath.Join(exportDir, "atom.xml")
+		what = "feeds"
+		log.Printf("Starting Atom/RSS feed export")
+		err = ExportToFeed(db, job.Options.DID, job.Options.DateRange, exportDir, job.Options.ExcludeReplies, job.Options.IncludeMedia)
+	case models.ExportFormatEPUB:
+		dataFile = filepath.Join(exportDir, "posts.epub")
+		what = "EPUB"
+		log.Printf("Starting EPUB export")
+		err = ExportToEPUB(db, job.Options.DID, job.Options.DateRange, dataFile)
+	case models.ExportFormatTemplate:
+		dataFile = filepath.Join(exportDir, job.Options.Template)
+		what = "with template " + job.Options.Template
+		templatePath := filepath.Join(job.Options.TemplatesDir, job.Options.Template+exportTemplateExt)
+		log.Printf("Starting batched template export with %s (batch size: %d)", templatePath, batchSize)
+		err = ExportToTemplate(db, job.Options.DID, job.Options.DateRange, templatePath, dataFile, batchSize)
+	case models.ExportFormatWARC:
+		dataFile = filepath.Join(exportDir, warcFilename)
+		what = "WARC"
+		log.Printf("Starting batched WARC export (batch size: %d)", batchSize)
+		err = ExportToWARC(db, job.Options.DID, job.Options.DateRange, exportDir, job.Options.BaseURL, batchSize)
+	default:
+		what = "posts"
+		err = fmt.Errorf("unknown export format: %s", job.Options.Format)
+	}
+	if err != nil {
 		job.Progress.Status = models.ExportStatusFailed
-		job.Progress.Error = fmt.Sprintf("Unknown export format: %s", job.Options.Format)
+		job.Progress.Error = fmt.Sprintf("Failed to export %s: %v", what, err)
 		progressChan <- job.Progress
-		return fmt.Errorf("unknown export format: %s", job.Options.Format)
+		return err
 	}
 
 	// Update progress after export completes
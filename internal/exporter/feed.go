@@ -0,0 +1,337 @@
+package exporter
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// feedPageSize is the number of posts fetched at a time while writing a feed
+const feedPageSize = 100
+
+// feedTitleLength is the longest entry title, in characters, taken from a post's first line
+const feedTitleLength = 80
+
+// FeedOptions selects the posts of a feed and how it links to itself and to media
+type FeedOptions struct {
+	Format   models.FeedFormat
+	DID      string
+	Filter   models.PostFilter         // Replies, Since and Until narrow the feed
+	Limit    int                       // Most recent posts to include; 0 includes every post
+	SelfURL  string                    // Address the feed is served from, if any
+	MediaURL func(models.Media) string // Enclosure address of a media file; nil leaves media out
+}
+
+// atomLink is an Atom link; enclosures carry the media type and size
+type atomLink struct {
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Title  string `xml:"title,attr,omitempty"`
+}
+
+// atomText is a text construct; content is escaped HTML
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+// atomPerson is the author of a feed
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
+}
+
+// atomCategory is a hashtag of an entry
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// atomEntry is one post of an Atom feed
+type atomEntry struct {
+	XMLName    xml.Name       `xml:"entry"`
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomText       `xml:"content"`
+}
+
+// rssAtomLink is the atom:link RSS channels use to point at themselves
+type rssAtomLink struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom link"`
+	Rel     string   `xml:"rel,attr"`
+	Type    string   `xml:"type,attr"`
+	Href    string   `xml:"href,attr"`
+}
+
+// rssGUID identifies an item; AT URIs are not permalinks
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// rssEnclosure is the media file of an item; RSS allows one per item
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// rssItem is one post of an RSS channel
+type rssItem struct {
+	XMLName     xml.Name      `xml:"item"`
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description"`
+	Categories  []string      `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+// WriteFeed writes an account's posts as an Atom 1.0 or RSS 2.0 feed, newest first
+// Posts are fetched a page at a time, so large feeds are streamed
+func WriteFeed(w io.Writer, db *sql.DB, opts FeedOptions) error {
+	remaining := opts.Limit
+	fetch := func(after string) (*models.PagedPostsResponse, error) {
+		limit := feedPageSize
+		if opts.Limit > 0 && remaining < limit {
+			limit = remaining
+		}
+		return storage.ListPosts(db, opts.DID, models.PostListOptions{Filter: opts.Filter, Limit: limit, After: after})
+	}
+
+	// The feed's update time is that of its newest post, so the first page comes first
+	page, err := fetch("")
+	if err != nil {
+		return err
+	}
+	updated := time.Now().UTC()
+	if len(page.Posts) > 0 {
+		updated = page.Posts[0].CreatedAt.UTC()
+	}
+
+	handle, name := opts.DID, ""
+	if profile, err := storage.GetLatestProfile(db, opts.DID); err == nil {
+		handle, name = profile.Handle, profile.DisplayName
+	}
+	if name == "" {
+		name = "@" + handle
+	}
+	profileURL := "https://bsky.app/profile/" + handle
+	title := fmt.Sprintf("Posts by %s", name)
+
+	buffered := bufio.NewWriter(w)
+	buffered.WriteString(xml.Header)
+	encoder := xml.NewEncoder(buffered)
+	encoder.Indent("", "  ")
+	element := func(local string, v interface{}) error {
+		return encoder.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: local}})
+	}
+
+	var closing []xml.EndElement
+	var head []error
+	if opts.Format == models.FeedFormatRSS {
+		rss := xml.StartElement{Name: xml.Name{Local: "rss"}, Attr: []xml.Attr{{Name: xml.Name{Local: "version"}, Value: "2.0"}}}
+		channel := xml.StartElement{Name: xml.Name{Local: "channel"}}
+		head = append(head,
+			encoder.EncodeToken(rss),
+			encoder.EncodeToken(channel),
+			element("title", title),
+			element("link", profileURL),
+			element("description", fmt.Sprintf("Archived Bluesky posts of @%s", handle)),
+			element("lastBuildDate", updated.Format(time.RFC1123Z)),
+		)
+		if opts.SelfURL != "" {
+			head = append(head, encoder.Encode(rssAtomLink{Rel: "self", Type: "application/rss+xml", Href: opts.SelfURL}))
+		}
+		closing = []xml.EndElement{channel.End(), rss.End()}
+	} else {
+		feed := xml.StartElement{Name: xml.Name{Space: "http://www.w3.org/2005/Atom", Local: "feed"}}
+		head = append(head,
+			encoder.EncodeToken(feed),
+			element("id", "at://"+opts.DID),
+			element("title", title),
+			element("updated", updated.Format(time.RFC3339)),
+			element("author", atomPerson{Name: name, URI: profileURL}),
+			element("link", atomLink{Rel: "alternate", Type: "text/html", Href: profileURL}),
+		)
+		if opts.SelfURL != "" {
+			head = append(head, element("link", atomLink{Rel: "self", Type: "application/atom+xml", Href: opts.SelfURL}))
+		}
+		closing = []xml.EndElement{feed.End()}
+	}
+	for _, err := range head {
+		if err != nil {
+			return fmt.Errorf("failed to write feed header: %w", err)
+		}
+	}
+
+	for len(page.Posts) > 0 {
+		var mediaMap map[string][]models.Media
+		if opts.MediaURL != nil {
+			uris := make([]string, len(page.Posts))
+			for i, post := range page.Posts {
+				uris[i] = post.URI
+			}
+			if mediaMap, err = storage.ListMediaForPosts(db, uris); err != nil {
+				return err
+			}
+		}
+
+		for _, post := range page.Posts {
+			var entry interface{}
+			if opts.Format == models.FeedFormatRSS {
+				entry = newRSSItem(post, mediaMap[post.URI], opts.MediaURL)
+			} else {
+				entry = newAtomEntry(post, mediaMap[post.URI], opts.MediaURL)
+			}
+			if err := encoder.Encode(entry); err != nil {
+				return fmt.Errorf("failed to write feed entry: %w", err)
+			}
+		}
+
+		remaining -= len(page.Posts)
+		if page.NextCursor == "" || (opts.Limit > 0 && remaining <= 0) {
+			break
+		}
+		if page, err = fetch(page.NextCursor); err != nil {
+			return err
+		}
+	}
+
+	for _, end := range closing {
+		if err := encoder.EncodeToken(end); err != nil {
+			return fmt.Errorf("failed to write feed: %w", err)
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return fmt.Errorf("failed to write feed: %w", err)
+	}
+	buffered.WriteString("\n")
+	return buffered.Flush()
+}
+
+// ExportToFeed writes atom.xml and rss.xml with every post in the date range
+// Enclosures point at the media/ directory the export copies when includeMedia is set
+func ExportToFeed(db *sql.DB, did string, dateRange *models.DateRange, exportDir string, excludeReplies, includeMedia bool) error {
+	opts := FeedOptions{DID: did}
+	if excludeReplies {
+		opts.Filter.Replies = models.ReplyFilterOriginals
+	}
+	if dateRange != nil {
+		opts.Filter.Since = dateRange.StartDate
+		if !dateRange.EndDate.IsZero() {
+			// Date ranges include their end; feed filters exclude it
+			opts.Filter.Until = dateRange.EndDate.Add(time.Second)
+		}
+	}
+	if includeMedia {
+		opts.MediaURL = func(m models.Media) string { return "media/" + filepath.Base(m.FilePath) }
+	}
+
+	for _, feed := range []struct {
+		format models.FeedFormat
+		name   string
+	}{
+		{models.FeedFormatAtom, "atom.xml"},
+		{models.FeedFormatRSS, "rss.xml"},
+	} {
+		file, err := os.Create(filepath.Join(exportDir, feed.name))
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", feed.name, err)
+		}
+		opts.Format = feed.format
+		if err := WriteFeed(file, db, opts); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", feed.name, err)
+		}
+	}
+	return nil
+}
+
+// newAtomEntry converts a post into an Atom entry with an enclosure link per media file
+func newAtomEntry(post models.Post, media []models.Media, mediaURL func(models.Media) string) atomEntry {
+	created := post.CreatedAt.UTC().Format(time.RFC3339)
+	entry := atomEntry{
+		ID:        post.URI,
+		Title:     atomText{Type: "text", Body: feedEntryTitle(post)},
+		Published: created,
+		Updated:   created,
+		Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: post.BlueskyURL()}},
+		Content:   atomText{Type: "html", Body: feedContent(post, media, mediaURL)},
+	}
+	for _, tag := range post.Tags() {
+		entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+	}
+	if mediaURL != nil {
+		for _, m := range media {
+			entry.Links = append(entry.Links, atomLink{Rel: "enclosure", Type: m.MimeType, Length: m.SizeBytes, Href: mediaURL(m), Title: m.AltText})
+		}
+	}
+	return entry
+}
+
+// newRSSItem converts a post into an RSS item; its first media file is the enclosure
+func newRSSItem(post models.Post, media []models.Media, mediaURL func(models.Media) string) rssItem {
+	item := rssItem{
+		Title:       feedEntryTitle(post),
+		Link:        post.BlueskyURL(),
+		GUID:        rssGUID{Value: post.URI},
+		PubDate:     post.CreatedAt.UTC().Format(time.RFC1123Z),
+		Description: feedContent(post, media, mediaURL),
+		Categories:  post.Tags(),
+	}
+	if mediaURL != nil && len(media) > 0 {
+		item.Enclosure = &rssEnclosure{URL: mediaURL(media[0]), Length: media[0].SizeBytes, Type: media[0].MimeType}
+	}
+	return item
+}
+
+// feedContent renders a post as HTML for feed readers, with its images inline
+func feedContent(post models.Post, media []models.Media, mediaURL func(models.Media) string) string {
+	content, _ := noteContent(post)
+	if mediaURL == nil {
+		return content
+	}
+	for _, m := range media {
+		if isImageFile(m.FilePath) {
+			content += fmt.Sprintf(`<p><img src="%s" alt="%s" /></p>`, html.EscapeString(mediaURL(m)), html.EscapeString(m.AltText))
+		}
+	}
+	return content
+}
+
+// feedEntryTitle is the first line of a post, shortened to feedTitleLength characters
+func feedEntryTitle(post models.Post) string {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(post.Text), "\n", 2)[0])
+	if title == "" {
+		if post.IsReply {
+			return "Reply"
+		}
+		return "Post"
+	}
+	if utf8.RuneCountInString(title) > feedTitleLength {
+		runes := []rune(title)
+		title = strings.TrimSpace(string(runes[:feedTitleLength-1])) + "…"
+	}
+	return title
+}
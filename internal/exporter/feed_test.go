@@ -0,0 +1,184 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+func TestWriteFeed(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:feed"
+	if err := storage.SaveProfile(db, &models.Profile{DID: did, Handle: "feed.test", DisplayName: "Feed & Co", SnapshotAt: time.Now()}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+
+	uri := func(i int) string { return fmt.Sprintf("at://%s/app.bsky.feed.post/3k%03d", did, i) }
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 150; i++ {
+		post := models.Post{URI: uri(i), CID: "cid", DID: did, Text: fmt.Sprintf("Post <number> %d", i), CreatedAt: start.Add(time.Duration(i) * time.Hour)}
+		post.IsReply = i%2 == 1
+		post.IndexedAt = post.CreatedAt
+		if i == 149 {
+			post.Text = strings.Repeat("long ", 30) + "\nsecond line"
+			post.HasMedia = true
+		}
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	newest := uri(149)
+	for _, m := range []models.Media{
+		{Hash: strings.Repeat("a", 64), MimeType: "image/jpeg", FilePath: "media/aa/one.jpg", SizeBytes: 1234, AltText: "First \"image\""},
+		{Hash: strings.Repeat("b", 64), MimeType: "video/mp4", FilePath: "media/bb/two.mp4", SizeBytes: 5678},
+	} {
+		m.PostURI = newest
+		m.CreatedAt = start
+		if err := storage.SaveMedia(db, &m); err != nil {
+			t.Fatalf("SaveMedia failed: %v", err)
+		}
+	}
+	mediaURL := func(m models.Media) string { return "https://archive.example/feeds/t/media/" + m.Hash }
+
+	t.Run("atom", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteFeed(&buf, db, FeedOptions{Format: models.FeedFormatAtom, DID: did, SelfURL: "https://archive.example/feeds/t/atom", MediaURL: mediaURL})
+		if err != nil {
+			t.Fatalf("WriteFeed failed: %v", err)
+		}
+
+		var feed struct {
+			XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+			Title   string      `xml:"title"`
+			Updated string      `xml:"updated"`
+			Links   []atomLink  `xml:"link"`
+			Entries []atomEntry `xml:"entry"`
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+			t.Fatalf("Feed is not valid XML: %v\n%s", err, buf.String())
+		}
+		if feed.Title != "Posts by Feed & Co" || feed.Updated != "2025-03-07T17:00:00Z" {
+			t.Errorf("Unexpected feed header: %q %q", feed.Title, feed.Updated)
+		}
+		if len(feed.Links) != 2 || feed.Links[1].Rel != "self" {
+			t.Errorf("Expected alternate and self links, got %+v", feed.Links)
+		}
+		// Pages are followed past the first 100 posts
+		if len(feed.Entries) != 150 {
+			t.Fatalf("Expected 150 entries, got %d", len(feed.Entries))
+		}
+
+		entry := feed.Entries[0]
+		if entry.ID != newest || !strings.HasSuffix(entry.Title.Body, "…") || len([]rune(entry.Title.Body)) != feedTitleLength {
+			t.Errorf("Unexpected entry: %q %q", entry.ID, entry.Title.Body)
+		}
+		if len(entry.Links) != 3 || entry.Links[1].Rel != "enclosure" || entry.Links[1].Length != 1234 || entry.Links[2].Type != "video/mp4" {
+			t.Errorf("Expected an enclosure per media file, got %+v", entry.Links)
+		}
+		if !strings.Contains(entry.Content.Body, `<img src="https://archive.example/feeds/t/media/`+strings.Repeat("a", 64)+`" alt="First &#34;image&#34;" />`) {
+			t.Errorf("Content should show the image inline:\n%s", entry.Content.Body)
+		}
+		if !strings.Contains(feed.Entries[149].Content.Body, "Post &lt;number&gt;") {
+			t.Errorf("Post text should be escaped HTML: %s", feed.Entries[149].Content.Body)
+		}
+	})
+
+	t.Run("rss with filters", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := WriteFeed(&buf, db, FeedOptions{Format: models.FeedFormatRSS, DID: did, Limit: 10, MediaURL: mediaURL,
+			Filter: models.PostFilter{Replies: models.ReplyFilterOriginals, Until: start.Add(100 * time.Hour)}})
+		if err != nil {
+			t.Fatalf("WriteFeed failed: %v", err)
+		}
+
+		var rss struct {
+			Version string `xml:"version,attr"`
+			Channel struct {
+				Title string    `xml:"title"`
+				Items []rssItem `xml:"item"`
+			} `xml:"channel"`
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &rss); err != nil {
+			t.Fatalf("Feed is not valid XML: %v\n%s", err, buf.String())
+		}
+		if rss.Version != "2.0" || len(rss.Channel.Items) != 10 {
+			t.Fatalf("Expected 10 items in an RSS 2.0 channel, got %q with %d", rss.Version, len(rss.Channel.Items))
+		}
+		first := rss.Channel.Items[0]
+		if first.PubDate != "Wed, 05 Mar 2025 14:00:00 +0000" || first.GUID.IsPermaLink || !strings.HasPrefix(first.Link, "https://bsky.app/profile/") {
+			t.Errorf("Unexpected item: %+v", first)
+		}
+		for _, item := range rss.Channel.Items {
+			if item.Enclosure != nil {
+				t.Errorf("Only the newest post has media: %+v", item.Enclosure)
+			}
+		}
+	})
+}
+
+func TestExportToFeed(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:feedexport"
+	day := time.Date(2025, 5, 10, 8, 0, 0, 0, time.UTC)
+	posts := []models.Post{
+		{URI: "at://" + did + "/app.bsky.feed.post/1", Text: "In range", CreatedAt: day, HasMedia: true},
+		{URI: "at://" + did + "/app.bsky.feed.post/2", Text: "A reply", CreatedAt: day.Add(time.Hour), IsReply: true},
+		{URI: "at://" + did + "/app.bsky.feed.post/3", Text: "Too late", CreatedAt: day.Add(48 * time.Hour)},
+	}
+	for _, post := range posts {
+		post.CID = "cid"
+		post.DID = did
+		post.IndexedAt = post.CreatedAt
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	if err := storage.SaveMedia(db, &models.Media{Hash: strings.Repeat("c", 64), PostURI: posts[0].URI, MimeType: "image/png",
+		FilePath: "media/cc/photo.png", SizeBytes: 42, CreatedAt: day}); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	exportDir := t.TempDir()
+	dateRange := &models.DateRange{EndDate: time.Date(2025, 5, 10, 23, 59, 59, 0, time.UTC)}
+	if err := ExportToFeed(db, did, dateRange, exportDir, true, true); err != nil {
+		t.Fatalf("ExportToFeed failed: %v", err)
+	}
+
+	atom, err := os.ReadFile(filepath.Join(exportDir, "atom.xml"))
+	if err != nil {
+		t.Fatalf("Expected atom.xml: %v", err)
+	}
+	rss, err := os.ReadFile(filepath.Join(exportDir, "rss.xml"))
+	if err != nil {
+		t.Fatalf("Expected rss.xml: %v", err)
+	}
+	for name, feed := range map[string]string{"atom.xml": string(atom), "rss.xml": string(rss)} {
+		if !strings.Contains(feed, "In range") || strings.Contains(feed, "A reply") || strings.Contains(feed, "Too late") {
+			t.Errorf("%s should only hold the original post in range:\n%s", name, feed)
+		}
+	}
+	if !strings.Contains(string(atom), `<link rel="enclosure" type="image/png" length="42" href="media/photo.png"></link>`) {
+		t.Errorf("atom.xml should link the copied media:\n%s", atom)
+	}
+	if !strings.Contains(string(rss), `<enclosure url="media/photo.png" length="42" type="image/png"></enclosure>`) {
+		t.Errorf("rss.xml should link the copied media:\n%s", rss)
+	}
+}
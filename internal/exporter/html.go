@@ -0,0 +1,404 @@
+package exporter
+
+import (
+	"bufio"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+	"github.com/shindakun/bskyarchive/internal/version"
+)
+
+// siteFiles holds the templates and assets of the static site export
+//
+//go:embed site
+var siteFiles embed.FS
+
+// sitePageSize is the number of posts on each timeline page
+const sitePageSize = 50
+
+var siteTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"card": func(root string, post *sitePost) siteCard {
+		return siteCard{Root: root, Post: post}
+	},
+	"uriDID": func(uri string) string {
+		parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+		return parts[0]
+	},
+	"uriRKey": func(uri string) string {
+		return uri[strings.LastIndex(uri, "/")+1:]
+	},
+}).ParseFS(siteFiles, "site/*.html"))
+
+// siteInfo describes the whole export for page headers and footers
+type siteInfo struct {
+	Name       string
+	DID        string
+	Total      int
+	Version    string
+	ExportedAt time.Time
+}
+
+// siteMedia is a media file linked from a post page; Src is relative to the site root
+// and empty when media files are not part of the export
+type siteMedia struct {
+	Src     string
+	Alt     string
+	IsImage bool
+}
+
+// sitePost is a post with the site paths of its page, its media and related posts
+type sitePost struct {
+	models.Post
+	Media      []siteMedia
+	Page       string // posts/{rkey}.html
+	ParentPage string // Page of the reply parent when it is part of the export
+	QuotePage  string // Page of the quoted post when it is part of the export
+}
+
+// siteCard renders a post in a list; Root prefixes links from pages in subdirectories
+type siteCard struct {
+	Root string
+	Post *sitePost
+}
+
+// siteYear and siteMonth make up the month index
+type siteYear struct {
+	Year   int
+	Months []siteMonth
+}
+
+type siteMonth struct {
+	Year  int
+	Label string
+	Page  string
+	Count int
+}
+
+// sitePage is the data passed to every site template
+type sitePage struct {
+	Site      *siteInfo
+	Title     string
+	Root      string // "" for pages at the site root, "../" for pages in subdirectories
+	Posts     []*sitePost
+	Post      *sitePost
+	PageNum   int
+	PageCount int
+	Prev      string
+	Next      string
+	Years     []siteYear
+}
+
+// searchEntry is one post in the client-side search index
+type searchEntry struct {
+	Page string `json:"p"`
+	Date string `json:"d"`
+	Text string `json:"t"`
+}
+
+// ExportToHTMLSite writes a static site that opens from disk with no server:
+// paginated timeline pages, a page per post, a page per month and a client-side search
+// index (search-index.json, also loaded as search-index.js since browsers block
+// reading JSON files from disk). Media links point at the media/ directory the export
+// copies when includeMedia is set. Posts are processed in batches, newest first
+func ExportToHTMLSite(db *sql.DB, did string, dateRange *models.DateRange, exportDir string, batchSize int, includeMedia bool) error {
+	total, err := storage.CountPostsWithDateRange(db, did, dateRange)
+	if err != nil {
+		return err
+	}
+	exported, err := storage.ListPostDatesWithDateRange(db, did, dateRange)
+	if err != nil {
+		return err
+	}
+
+	site := &siteInfo{
+		Name:       did,
+		DID:        did,
+		Total:      total,
+		Version:    version.GetVersion(),
+		ExportedAt: time.Now(),
+	}
+	if profile, err := storage.GetLatestProfile(db, did); err == nil {
+		site.Name = "@" + profile.Handle
+	}
+
+	for _, dir := range []string{"posts", "months"} {
+		if err := os.MkdirAll(filepath.Join(exportDir, dir), 0755); err != nil {
+			return fmt.Errorf("failed to create site directory: %w", err)
+		}
+	}
+	for _, asset := range []string{"style.css", "search.js"} {
+		content, err := siteFiles.ReadFile("site/" + asset)
+		if err != nil {
+			return fmt.Errorf("failed to read site asset %s: %w", asset, err)
+		}
+		if err := os.WriteFile(filepath.Join(exportDir, asset), content, 0644); err != nil {
+			return fmt.Errorf("failed to write site asset %s: %w", asset, err)
+		}
+	}
+
+	index, err := newSearchIndexWriter(exportDir)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	pageCount := (total + sitePageSize - 1) / sitePageSize
+	if pageCount == 0 {
+		pageCount = 1
+	}
+
+	var (
+		timeline  []*sitePost
+		pageNum   = 1
+		month     []*sitePost
+		monthKey  string
+		months    []siteMonth
+		monthTime time.Time
+	)
+
+	writeTimeline := func() error {
+		page := sitePage{Site: site, Title: "Timeline", Posts: timeline, PageNum: pageNum, PageCount: pageCount}
+		if pageNum > 1 {
+			page.Prev = sitePageFile(pageNum - 1)
+		}
+		if pageNum < pageCount {
+			page.Next = sitePageFile(pageNum + 1)
+		}
+		if err := writeSitePage(filepath.Join(exportDir, sitePageFile(pageNum)), "timeline", page); err != nil {
+			return err
+		}
+		timeline = nil
+		pageNum++
+		return nil
+	}
+
+	writeMonth := func() error {
+		if len(month) == 0 {
+			return nil
+		}
+		label := monthTime.Format("January 2006")
+		page := sitePage{Site: site, Title: label, Root: "../", Posts: month}
+		if err := writeSitePage(filepath.Join(exportDir, "months", monthKey+".html"), "timeline", page); err != nil {
+			return err
+		}
+		months = append(months, siteMonth{Year: monthTime.Year(), Label: monthTime.Format("January"), Page: "months/" + monthKey + ".html", Count: len(month)})
+		month = nil
+		return nil
+	}
+
+	offset := 0
+	for {
+		batch, err := storage.ListPostsWithDateRange(db, did, dateRange, batchSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to fetch batch at offset %d: %w", offset, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		uris := make([]string, len(batch))
+		for i, post := range batch {
+			uris[i] = post.URI
+		}
+		mediaMap, err := storage.ListMediaForPosts(db, uris)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range batch {
+			sp := newSitePost(post, mediaMap[post.URI], exported, includeMedia)
+
+			page := sitePage{Site: site, Title: post.CreatedAt.Format("Jan 2, 2006 15:04"), Root: "../", Post: sp}
+			if err := writeSitePage(filepath.Join(exportDir, sp.Page), "post", page); err != nil {
+				return err
+			}
+
+			timeline = append(timeline, sp)
+			if len(timeline) == sitePageSize {
+				if err := writeTimeline(); err != nil {
+					return err
+				}
+			}
+
+			// Posts arrive newest first, so each month's posts are consecutive
+			created := post.CreatedAt.UTC()
+			if key := created.Format("2006-01"); key != monthKey {
+				if err := writeMonth(); err != nil {
+					return err
+				}
+				monthKey, monthTime = key, created
+			}
+			month = append(month, sp)
+
+			if err := index.Add(searchEntry{Page: sp.Page, Date: created.Format("2006-01-02"), Text: post.Text}); err != nil {
+				return err
+			}
+		}
+
+		offset += len(batch)
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	if len(timeline) > 0 || pageNum == 1 {
+		if err := writeTimeline(); err != nil {
+			return err
+		}
+	}
+	if err := writeMonth(); err != nil {
+		return err
+	}
+
+	// Month index, newest year first
+	var years []siteYear
+	for _, m := range months {
+		if len(years) == 0 || years[len(years)-1].Year != m.Year {
+			years = append(years, siteYear{Year: m.Year})
+		}
+		years[len(years)-1].Months = append(years[len(years)-1].Months, m)
+	}
+	if err := writeSitePage(filepath.Join(exportDir, "months.html"), "months", sitePage{Site: site, Title: "Months", Years: years}); err != nil {
+		return err
+	}
+	if err := writeSitePage(filepath.Join(exportDir, "search.html"), "search", sitePage{Site: site, Title: "Search"}); err != nil {
+		return err
+	}
+
+	return index.Close()
+}
+
+// newSitePost prepares a post for the site; exported holds the URIs in the export so
+// replies and quotes can link to local pages
+func newSitePost(post models.Post, media []models.Media, exported map[string]time.Time, includeMedia bool) *sitePost {
+	sp := &sitePost{Post: post, Page: sitePostFile(post.URI)}
+	if _, ok := exported[post.ReplyParent]; ok {
+		sp.ParentPage = sitePostFile(post.ReplyParent)
+	}
+	if quote := post.Quote(); quote != nil {
+		if _, ok := exported[quote.URI]; ok {
+			sp.QuotePage = sitePostFile(quote.URI)
+		}
+	}
+
+	for _, m := range media {
+		item := siteMedia{Alt: m.AltText}
+		if includeMedia {
+			item.Src = "media/" + filepath.Base(m.FilePath)
+			item.IsImage = isImageFile(m.FilePath)
+		}
+		sp.Media = append(sp.Media, item)
+	}
+	return sp
+}
+
+// sitePostFile returns the page of a post relative to the site root
+func sitePostFile(uri string) string {
+	return "posts/" + uri[strings.LastIndex(uri, "/")+1:] + ".html"
+}
+
+// sitePageFile returns the file of a timeline page; the first page is index.html
+func sitePageFile(page int) string {
+	if page == 1 {
+		return "index.html"
+	}
+	return fmt.Sprintf("page-%d.html", page)
+}
+
+// writeSitePage renders one site template to a file
+func writeSitePage(path, name string, page sitePage) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if err := siteTemplates.ExecuteTemplate(w, name, page); err != nil {
+		return fmt.Errorf("failed to render %s: %w", filepath.Base(path), err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// searchIndexWriter streams the search index to search-index.json and search-index.js
+type searchIndexWriter struct {
+	files   []*os.File
+	writers []*bufio.Writer
+	count   int
+	closed  bool
+}
+
+func newSearchIndexWriter(exportDir string) (*searchIndexWriter, error) {
+	w := &searchIndexWriter{}
+	for name, prefix := range map[string]string{
+		"search-index.json": "[",
+		"search-index.js":   "window.searchIndex = [",
+	} {
+		file, err := os.Create(filepath.Join(exportDir, name))
+		if err != nil {
+			w.Close()
+			return nil, fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		buf := bufio.NewWriter(file)
+		buf.WriteString(prefix)
+		w.files = append(w.files, file)
+		w.writers = append(w.writers, buf)
+	}
+	return w, nil
+}
+
+// Add appends one entry to both index files
+func (w *searchIndexWriter) Add(entry searchEntry) error {
+	// json.Marshal escapes <, > and &, so entries are safe inside a script
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode search entry: %w", err)
+	}
+	for _, buf := range w.writers {
+		if w.count > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+		if _, err := buf.Write(encoded); err != nil {
+			return fmt.Errorf("failed to write search index: %w", err)
+		}
+	}
+	w.count++
+	return nil
+}
+
+// Close finishes both files; it is safe to call more than once
+func (w *searchIndexWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var firstErr error
+	for i, file := range w.files {
+		suffix := "\n]\n"
+		if strings.HasSuffix(file.Name(), ".js") {
+			suffix = "\n];\n"
+		}
+		w.writers[i].WriteString(suffix)
+		if err := w.writers[i].Flush(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to write search index: %w", err)
+		}
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close search index: %w", err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,127 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// TestExportToHTMLSite checks the pages, relative links and search index of the static site
+func TestExportToHTMLSite(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := storage.InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:site"
+	if err := storage.SaveProfile(db, &models.Profile{DID: did, Handle: "site.test", SnapshotAt: time.Now()}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+
+	// 60 posts across January and February, newest last
+	baseTime := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	uri := func(i int) string { return fmt.Sprintf("at://%s/app.bsky.feed.post/%05d", did, i) }
+	for i := 0; i < 60; i++ {
+		post := models.Post{
+			URI:       uri(i),
+			CID:       "cid",
+			DID:       did,
+			Text:      fmt.Sprintf("Post number %d <script>", i),
+			CreatedAt: baseTime.Add(time.Duration(i) * time.Hour),
+		}
+		post.IndexedAt = post.CreatedAt
+		switch i {
+		case 59:
+			post.IsReply = true
+			post.ReplyParent = uri(58)
+			post.HasMedia = true
+		case 58:
+			post.EmbedType = "record"
+			post.EmbedData = json.RawMessage(`{"record":{"uri":"` + uri(0) + `","value":{"text":"Post number 0"}}}`)
+		}
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	media := &models.Media{
+		Hash:      strings.Repeat("a", 64),
+		PostURI:   uri(59),
+		MimeType:  "image/jpeg",
+		FilePath:  "media/aa/photo.jpg",
+		AltText:   "A photo",
+		CreatedAt: time.Now(),
+	}
+	if err := storage.SaveMedia(db, media); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	exportDir := filepath.Join(tmpDir, "site")
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		t.Fatalf("Failed to create export dir: %v", err)
+	}
+	if err := ExportToHTMLSite(db, did, nil, exportDir, 25, true); err != nil {
+		t.Fatalf("ExportToHTMLSite failed: %v", err)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(exportDir, name))
+		if err != nil {
+			t.Fatalf("Expected %s: %v", name, err)
+		}
+		return string(content)
+	}
+
+	index := read("index.html")
+	for _, want := range []string{"@site.test", `href="page-2.html"`, "Page 1 of 2", `href="posts/00059.html"`, "&lt;script&gt;"} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html missing %q", want)
+		}
+	}
+	if page2 := read("page-2.html"); !strings.Contains(page2, `href="index.html">← Newer`) {
+		t.Error("page-2.html should link back to index.html")
+	}
+
+	post := read("posts/00059.html")
+	for _, want := range []string{`src="../media/photo.jpg"`, `href="../posts/00058.html"`, "A photo", `href="../style.css"`} {
+		if !strings.Contains(post, want) {
+			t.Errorf("posts/00059.html missing %q", want)
+		}
+	}
+	if quote := read("posts/00058.html"); !strings.Contains(quote, `href="../posts/00000.html"`) {
+		t.Error("Quote of an exported post should link to its page")
+	}
+
+	months := read("months.html")
+	for _, want := range []string{`href="months/2025-02.html"`, `href="months/2025-01.html"`, "(36)", "(24)"} {
+		if !strings.Contains(months, want) {
+			t.Errorf("months.html missing %q", want)
+		}
+	}
+	if january := read("months/2025-01.html"); !strings.Contains(january, `href="../posts/00000.html"`) {
+		t.Error("Month page should link to its posts")
+	}
+
+	var entries []searchEntry
+	if err := json.Unmarshal([]byte(read("search-index.json")), &entries); err != nil {
+		t.Fatalf("search-index.json is not valid JSON: %v", err)
+	}
+	if len(entries) != 60 || entries[0].Page != "posts/00059.html" {
+		t.Errorf("Unexpected search index: %d entries", len(entries))
+	}
+	script := read("search-index.js")
+	if !strings.HasPrefix(script, "window.searchIndex = [") || strings.Contains(script, "<script>") {
+		t.Error("search-index.js should assign the index with markup escaped")
+	}
+	read("search.html")
+	read("search.js")
+}
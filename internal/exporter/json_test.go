@@ -41,7 +41,9 @@ func setupJSONTestDB(t *testing.T) *sql.DB {
 			embed_type TEXT,
 			embed_data JSON,
 			labels JSON,
-			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			langs JSON,
+			facets JSON
 		);
 
 		CREATE INDEX idx_posts_did ON posts(did);
@@ -0,0 +1,151 @@
+package exporter
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// jsonlWriter writes one compact JSON value per line
+type jsonlWriter struct {
+	file    *os.File
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newJSONLWriter(path string) (*jsonlWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+	}
+	buf := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	return &jsonlWriter{file: file, buf: buf, encoder: encoder}, nil
+}
+
+// Write encodes v followed by a newline
+func (w *jsonlWriter) Write(v interface{}) error {
+	if err := w.encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(w.file.Name()), err)
+	}
+	return nil
+}
+
+// Close flushes and closes the file
+func (w *jsonlWriter) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(w.file.Name()), err)
+	}
+	return w.file.Close()
+}
+
+// ExportToJSONLBatched writes posts.jsonl with one post per line, newest first.
+// With companions it also writes media.jsonl (media metadata of the exported posts),
+// profiles.jsonl (every profile snapshot) and operations.jsonl (archive run history)
+func ExportToJSONLBatched(db *sql.DB, did string, dateRange *models.DateRange, exportDir string, batchSize int, companions bool) error {
+	posts, err := newJSONLWriter(filepath.Join(exportDir, "posts.jsonl"))
+	if err != nil {
+		return err
+	}
+	defer posts.file.Close()
+
+	var media *jsonlWriter
+	if companions {
+		if media, err = newJSONLWriter(filepath.Join(exportDir, "media.jsonl")); err != nil {
+			return err
+		}
+		defer media.file.Close()
+	}
+
+	offset := 0
+	for {
+		batch, err := storage.ListPostsWithDateRange(db, did, dateRange, batchSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to fetch batch at offset %d: %w", offset, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, post := range batch {
+			if err := posts.Write(post); err != nil {
+				return err
+			}
+		}
+
+		if media != nil {
+			uris := make([]string, len(batch))
+			for i, post := range batch {
+				uris[i] = post.URI
+			}
+			mediaMap, err := storage.ListMediaForPosts(db, uris)
+			if err != nil {
+				return err
+			}
+			for _, uri := range uris {
+				for _, m := range mediaMap[uri] {
+					if err := media.Write(m); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		offset += len(batch)
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	if err := posts.Close(); err != nil {
+		return err
+	}
+	if media == nil {
+		return nil
+	}
+	if err := media.Close(); err != nil {
+		return err
+	}
+
+	profiles, err := storage.ListProfileSnapshots(db, did)
+	if err != nil {
+		return err
+	}
+	profileFile, err := newJSONLWriter(filepath.Join(exportDir, "profiles.jsonl"))
+	if err != nil {
+		return err
+	}
+	defer profileFile.file.Close()
+	for _, profile := range profiles {
+		if err := profileFile.Write(profile); err != nil {
+			return err
+		}
+	}
+	if err := profileFile.Close(); err != nil {
+		return err
+	}
+
+	operations, err := storage.ListOperationHistory(db, did)
+	if err != nil {
+		return err
+	}
+	operationFile, err := newJSONLWriter(filepath.Join(exportDir, "operations.jsonl"))
+	if err != nil {
+		return err
+	}
+	defer operationFile.file.Close()
+	for _, op := range operations {
+		if err := operationFile.Write(op); err != nil {
+			return err
+		}
+	}
+	return operationFile.Close()
+}
@@ -0,0 +1,149 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// readJSONLines decodes every line of a JSON Lines file into maps
+func readJSONLines(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("%s line %d is not a JSON object: %v", filepath.Base(path), len(lines)+1, err)
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Failed to read %s: %v", filepath.Base(path), err)
+	}
+	return lines
+}
+
+func TestExportToJSONLBatched(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := storage.InitDB(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:lines"
+	baseTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		post := models.Post{
+			URI:       fmt.Sprintf("at://%s/app.bsky.feed.post/%d", did, i),
+			CID:       "cid",
+			DID:       did,
+			Text:      fmt.Sprintf("Line %d\nwith a newline & <markup>", i),
+			CreatedAt: baseTime.Add(time.Duration(i) * time.Hour),
+			IndexedAt: baseTime,
+			HasMedia:  i == 2,
+		}
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	media := &models.Media{
+		Hash:      strings.Repeat("b", 64),
+		PostURI:   fmt.Sprintf("at://%s/app.bsky.feed.post/2", did),
+		MimeType:  "image/png",
+		FilePath:  "media/bb/image.png",
+		CreatedAt: baseTime,
+	}
+	if err := storage.SaveMedia(db, media); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		profile := &models.Profile{DID: did, Handle: "lines.test", FollowersCount: i, SnapshotAt: baseTime.Add(time.Duration(i) * time.Hour)}
+		if err := storage.SaveProfile(db, profile); err != nil {
+			t.Fatalf("SaveProfile failed: %v", err)
+		}
+	}
+	// Operations reference the account's session
+	if _, err := db.Exec(`INSERT INTO sessions (id, did, handle, access_token, refresh_token, expires_at)
+		VALUES ('s1', ?, 'lines.test', 'a', 'r', ?)`, did, baseTime); err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+	op := &models.ArchiveOperation{ID: "op-1", DID: did, Type: models.OperationTypeInitial, Status: models.OperationStatusCompleted, StartedAt: baseTime}
+	if err := storage.CreateOperation(db, op); err != nil {
+		t.Fatalf("CreateOperation failed: %v", err)
+	}
+
+	t.Run("posts only", func(t *testing.T) {
+		exportDir := t.TempDir()
+		if err := ExportToJSONLBatched(db, did, nil, exportDir, 3, false); err != nil {
+			t.Fatalf("ExportToJSONLBatched failed: %v", err)
+		}
+
+		posts := readJSONLines(t, filepath.Join(exportDir, "posts.jsonl"))
+		if len(posts) != 7 {
+			t.Fatalf("Expected 7 lines, got %d", len(posts))
+		}
+		if posts[0]["uri"] != fmt.Sprintf("at://%s/app.bsky.feed.post/6", did) {
+			t.Errorf("Expected newest post first, got %v", posts[0]["uri"])
+		}
+		if text := posts[0]["text"]; text != "Line 6\nwith a newline & <markup>" {
+			t.Errorf("Text not preserved: %q", text)
+		}
+
+		for _, name := range []string{"media.jsonl", "profiles.jsonl", "operations.jsonl"} {
+			if _, err := os.Stat(filepath.Join(exportDir, name)); !os.IsNotExist(err) {
+				t.Errorf("%s should only be written with companions", name)
+			}
+		}
+	})
+
+	t.Run("with companions", func(t *testing.T) {
+		exportDir := t.TempDir()
+		dateRange := &models.DateRange{StartDate: baseTime.Add(90 * time.Minute)}
+		if err := ExportToJSONLBatched(db, did, dateRange, exportDir, 3, true); err != nil {
+			t.Fatalf("ExportToJSONLBatched failed: %v", err)
+		}
+
+		if posts := readJSONLines(t, filepath.Join(exportDir, "posts.jsonl")); len(posts) != 5 {
+			t.Errorf("Expected 5 posts in range, got %d", len(posts))
+		}
+		mediaLines := readJSONLines(t, filepath.Join(exportDir, "media.jsonl"))
+		if len(mediaLines) != 1 || mediaLines[0]["post_uri"] != media.PostURI {
+			t.Errorf("Unexpected media lines: %v", mediaLines)
+		}
+		profiles := readJSONLines(t, filepath.Join(exportDir, "profiles.jsonl"))
+		if len(profiles) != 2 || profiles[0]["followers_count"] != float64(0) {
+			t.Errorf("Expected both profile snapshots oldest first, got %v", profiles)
+		}
+		if ops := readJSONLines(t, filepath.Join(exportDir, "operations.jsonl")); len(ops) != 1 || ops[0]["id"] != "op-1" {
+			t.Errorf("Unexpected operation lines: %v", ops)
+		}
+	})
+}
+
+func TestExportOptions_CompanionsRequireJSONL(t *testing.T) {
+	opts := models.ExportOptions{Format: models.ExportFormatJSON, DID: "did:plc:x", Companions: true}
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected companions with json format to be rejected")
+	}
+	opts.Format = models.ExportFormatJSONL
+	if err := opts.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
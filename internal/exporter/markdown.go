@@ -0,0 +1,299 @@
+package exporter
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// blockIDUnsafe matches characters Obsidian does not accept in block IDs
+var blockIDUnsafe = regexp.MustCompile(`[^A-Za-z0-9-]`)
+
+// markdownVault writes the notes of a Markdown export
+type markdownVault struct {
+	exportDir    string
+	byDay        bool
+	includeMedia bool
+	exported     map[string]time.Time // URI -> created_at of every post in the export
+}
+
+// ExportToMarkdown writes a Markdown vault for Obsidian and similar tools: one note per
+// post (posts/YYYY/MM/YYYY-MM-DD-rkey.md) or, with byDay, one note per day
+// (days/YYYY/YYYY-MM-DD.md) with a section per post. Notes start with YAML front matter.
+// Replies wiki-link to their parent when it is part of the export, and media links point
+// at the media/ directory the export copies when includeMedia is set
+func ExportToMarkdown(db *sql.DB, did string, dateRange *models.DateRange, exportDir string, batchSize int, byDay, includeMedia bool) error {
+	exported, err := storage.ListPostDatesWithDateRange(db, did, dateRange)
+	if err != nil {
+		return err
+	}
+	vault := &markdownVault{exportDir: exportDir, byDay: byDay, includeMedia: includeMedia, exported: exported}
+
+	// Posts arrive newest first, so each day's posts are consecutive
+	var day []models.Post
+	dayMedia := make(map[string][]models.Media)
+	flushDay := func() error {
+		if len(day) == 0 {
+			return nil
+		}
+		if err := vault.writeDay(day, dayMedia); err != nil {
+			return err
+		}
+		day = nil
+		dayMedia = make(map[string][]models.Media)
+		return nil
+	}
+
+	offset := 0
+	for {
+		batch, err := storage.ListPostsWithDateRange(db, did, dateRange, batchSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to fetch batch at offset %d: %w", offset, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		uris := make([]string, len(batch))
+		for i, post := range batch {
+			uris[i] = post.URI
+		}
+		mediaMap, err := storage.ListMediaForPosts(db, uris)
+		if err != nil {
+			return err
+		}
+
+		for _, post := range batch {
+			if !byDay {
+				if err := vault.writePost(post, mediaMap[post.URI]); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if len(day) > 0 && noteDay(day[0].CreatedAt) != noteDay(post.CreatedAt) {
+				if err := flushDay(); err != nil {
+					return err
+				}
+			}
+			day = append(day, post)
+			dayMedia[post.URI] = mediaMap[post.URI]
+		}
+
+		offset += len(batch)
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	return flushDay()
+}
+
+// writePost writes the note of one post
+func (v *markdownVault) writePost(post models.Post, media []models.Media) error {
+	created := post.CreatedAt.UTC()
+	var b strings.Builder
+	b.WriteString("---\n")
+	writePostFrontMatter(&b, post, "")
+	b.WriteString("---\n\n")
+	v.writeBody(&b, post, media, "../../../")
+	fmt.Fprintf(&b, "[View on Bluesky](%s)\n", post.BlueskyURL())
+
+	path := filepath.Join(v.exportDir, "posts", created.Format("2006"), created.Format("01"), postNoteName(created, post.RKey())+".md")
+	return writeNote(path, b.String())
+}
+
+// writeDay writes the note of one day; posts are newest first and listed oldest first
+func (v *markdownVault) writeDay(posts []models.Post, media map[string][]models.Media) error {
+	date := noteDay(posts[0].CreatedAt)
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, post := range posts {
+		for _, tag := range post.Tags() {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "date: %s\n", date)
+	fmt.Fprintf(&b, "tags: %s\n", yamlValue(tags))
+	b.WriteString("posts:\n")
+	for i := len(posts) - 1; i >= 0; i-- {
+		writePostFrontMatter(&b, posts[i], "  ")
+	}
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "# %s\n\n", date)
+
+	for i := len(posts) - 1; i >= 0; i-- {
+		post := posts[i]
+		fmt.Fprintf(&b, "## %s\n\n", post.CreatedAt.UTC().Format("15:04"))
+		v.writeBody(&b, post, media[post.URI], "../../")
+		fmt.Fprintf(&b, "[View on Bluesky](%s) ^%s\n\n", post.BlueskyURL(), blockID(post.RKey()))
+	}
+
+	created := posts[0].CreatedAt.UTC()
+	path := filepath.Join(v.exportDir, "days", created.Format("2006"), date+".md")
+	return writeNote(path, strings.TrimRight(b.String(), "\n")+"\n")
+}
+
+// writeBody writes the text, media, link card, quote and reply context of a post
+// root is the relative path from the note to the export directory
+func (v *markdownVault) writeBody(b *strings.Builder, post models.Post, media []models.Media, root string) {
+	if post.Text != "" {
+		b.WriteString(post.Text + "\n\n")
+	}
+
+	for _, m := range media {
+		alt := strings.NewReplacer("\n", " ", "[", "(", "]", ")").Replace(m.AltText)
+		switch {
+		case !v.includeMedia:
+			if alt != "" {
+				fmt.Fprintf(b, "*Image: %s*\n\n", alt)
+			}
+		case isImageFile(m.FilePath):
+			fmt.Fprintf(b, "![%s](%smedia/%s)\n\n", alt, root, filepath.Base(m.FilePath))
+		default:
+			fmt.Fprintf(b, "[📎 %s](%smedia/%s)\n\n", m.MimeType, root, filepath.Base(m.FilePath))
+		}
+	}
+
+	if link := post.Link(); link != nil {
+		title := link.Title
+		if title == "" {
+			title = link.URI
+		}
+		fmt.Fprintf(b, "[%s](%s)\n\n", title, link.URI)
+	}
+
+	if quote := post.Quote(); quote != nil {
+		quoted := quote.DID
+		if quote.Handle != "" {
+			quoted = "@" + quote.Handle
+		}
+		if quote.Text != "" {
+			fmt.Fprintf(b, "> %s:\n> %s\n>\n", quoted, strings.ReplaceAll(quote.Text, "\n", "\n> "))
+		}
+		fmt.Fprintf(b, "> Quoted post: %s\n\n", v.postLink(quote.URI, "quoted post"))
+	}
+
+	if post.ReplyParent != "" {
+		fmt.Fprintf(b, "Reply to %s\n\n", v.postLink(post.ReplyParent, "a post"))
+	}
+}
+
+// postLink links a post: a wiki-link when it is part of the export, otherwise its
+// address on bsky.app with label as the text
+func (v *markdownVault) postLink(uri, label string) string {
+	created, ok := v.exported[uri]
+	if !ok {
+		return fmt.Sprintf("[%s](%s)", label, blueskyPostURL(uri))
+	}
+
+	rkey := uri[strings.LastIndex(uri, "/")+1:]
+	if v.byDay {
+		return fmt.Sprintf("[[%s#^%s]]", noteDay(created), blockID(rkey))
+	}
+	return fmt.Sprintf("[[%s]]", postNoteName(created.UTC(), rkey))
+}
+
+// blueskyPostURL returns the bsky.app address of a post URI
+func blueskyPostURL(uri string) string {
+	did := strings.SplitN(strings.TrimPrefix(uri, "at://"), "/", 2)[0]
+	post := models.Post{URI: uri, DID: did}
+	return post.BlueskyURL()
+}
+
+// frontMatterField is one key of a note's YAML front matter with its encoded value
+type frontMatterField struct {
+	key   string
+	value string
+}
+
+// writePostFrontMatter writes a post's front matter fields; with an indent they form
+// one item of a YAML list
+func writePostFrontMatter(b *strings.Builder, post models.Post, indent string) {
+	fields := []frontMatterField{
+		{"uri", yamlValue(post.URI)},
+		{"cid", yamlValue(post.CID)},
+		{"created_at", post.CreatedAt.UTC().Format(time.RFC3339)},
+		{"likes", fmt.Sprint(post.LikeCount)},
+		{"reposts", fmt.Sprint(post.RepostCount)},
+		{"replies", fmt.Sprint(post.ReplyCount)},
+		{"quotes", fmt.Sprint(post.QuoteCount)},
+		{"tags", yamlValue(post.Tags())},
+	}
+	if post.ReplyParent != "" {
+		fields = append(fields, frontMatterField{"reply_parent", yamlValue(post.ReplyParent)})
+	}
+
+	for i, field := range fields {
+		prefix := indent
+		if indent != "" {
+			prefix = indent + "  "
+			if i == 0 {
+				prefix = indent + "- "
+			}
+		}
+		fmt.Fprintf(b, "%s%s: %s\n", prefix, field.key, field.value)
+	}
+}
+
+// yamlValue encodes a string or list as a YAML flow value; JSON is valid YAML
+func yamlValue(v interface{}) string {
+	if tags, ok := v.([]string); ok && tags == nil {
+		return "[]"
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return `""`
+	}
+	return string(encoded)
+}
+
+// postNoteName is the note name of a post, which wiki-links refer to
+func postNoteName(created time.Time, rkey string) string {
+	return created.Format("2006-01-02") + "-" + rkey
+}
+
+// noteDay is the name of a day's note; days follow UTC like the rest of the export
+func noteDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// blockID turns a record key into an Obsidian block ID
+func blockID(rkey string) string {
+	return blockIDUnsafe.ReplaceAllString(rkey, "-")
+}
+
+// isImageFile reports whether a media file is shown inline
+func isImageFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		return true
+	}
+	return false
+}
+
+// writeNote writes a note, creating its directory
+func writeNote(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create note directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
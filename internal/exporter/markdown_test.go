@@ -0,0 +1,114 @@
+package exporter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+func TestExportToMarkdown(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:notes"
+	uri := func(rkey string) string { return "at://" + did + "/app.bsky.feed.post/" + rkey }
+	day := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	posts := []models.Post{
+		{URI: uri("3kparent"), Text: "Planting #tomatoes today", CreatedAt: day,
+			Facets: json.RawMessage(`[{"features":[{"$type":"app.bsky.richtext.facet#tag","tag":"tomatoes"}]}]`), LikeCount: 4, HasMedia: true},
+		{URI: uri("3kreply"), Text: "They sprouted", CreatedAt: day.Add(3 * time.Hour), IsReply: true, ReplyParent: uri("3kparent")},
+		{URI: uri("3kother"), Text: "Next day, replying elsewhere", CreatedAt: day.Add(26 * time.Hour), IsReply: true,
+			ReplyParent: "at://did:plc:friend/app.bsky.feed.post/3kfriend"},
+	}
+	for _, post := range posts {
+		post.CID = "cid-" + post.RKey()
+		post.DID = did
+		post.IndexedAt = post.CreatedAt
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	media := &models.Media{Hash: strings.Repeat("d", 64), PostURI: uri("3kparent"), MimeType: "image/jpeg",
+		FilePath: "media/dd/seedlings.jpg", AltText: "Seedlings [in pots]", CreatedAt: day}
+	if err := storage.SaveMedia(db, media); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	read := func(t *testing.T, path string) string {
+		t.Helper()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected note %s: %v", path, err)
+		}
+		return string(content)
+	}
+
+	t.Run("per post", func(t *testing.T) {
+		exportDir := t.TempDir()
+		if err := ExportToMarkdown(db, did, nil, exportDir, 2, false, true); err != nil {
+			t.Fatalf("ExportToMarkdown failed: %v", err)
+		}
+
+		parent := read(t, filepath.Join(exportDir, "posts", "2025", "04", "2025-04-02-3kparent.md"))
+		for _, want := range []string{
+			"---\nuri: \"" + uri("3kparent") + "\"\ncid: \"cid-3kparent\"\ncreated_at: 2025-04-02T09:00:00Z\nlikes: 4\n",
+			"tags: [\"tomatoes\"]\n---\n",
+			"Planting #tomatoes today",
+			"![Seedlings (in pots)](../../../media/seedlings.jpg)",
+		} {
+			if !strings.Contains(parent, want) {
+				t.Errorf("Parent note missing %q:\n%s", want, parent)
+			}
+		}
+
+		reply := read(t, filepath.Join(exportDir, "posts", "2025", "04", "2025-04-02-3kreply.md"))
+		for _, want := range []string{"reply_parent: \"" + uri("3kparent") + "\"", "tags: []", "Reply to [[2025-04-02-3kparent]]"} {
+			if !strings.Contains(reply, want) {
+				t.Errorf("Reply note missing %q:\n%s", want, reply)
+			}
+		}
+
+		other := read(t, filepath.Join(exportDir, "posts", "2025", "04", "2025-04-03-3kother.md"))
+		if !strings.Contains(other, "Reply to [a post](https://bsky.app/profile/did:plc:friend/post/3kfriend)") {
+			t.Errorf("Parent outside the export should link to Bluesky:\n%s", other)
+		}
+	})
+
+	t.Run("per day", func(t *testing.T) {
+		exportDir := t.TempDir()
+		if err := ExportToMarkdown(db, did, nil, exportDir, 2, true, false); err != nil {
+			t.Fatalf("ExportToMarkdown failed: %v", err)
+		}
+
+		note := read(t, filepath.Join(exportDir, "days", "2025", "2025-04-02.md"))
+		for _, want := range []string{
+			"---\ndate: 2025-04-02\ntags: [\"tomatoes\"]\nposts:\n  - uri: \"" + uri("3kparent") + "\"\n    cid: \"cid-3kparent\"\n",
+			"^3kparent",
+			"Reply to [[2025-04-02#^3kparent]]",
+			"*Image: Seedlings (in pots)*",
+		} {
+			if !strings.Contains(note, want) {
+				t.Errorf("Day note missing %q:\n%s", want, note)
+			}
+		}
+		if strings.Index(note, "## 09:00") > strings.Index(note, "## 12:00") {
+			t.Error("Posts in a day note should be oldest first")
+		}
+
+		if _, err := os.Stat(filepath.Join(exportDir, "days", "2025", "2025-04-03.md")); err != nil {
+			t.Errorf("Expected a note for the second day: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(exportDir, "posts")); !os.IsNotExist(err) {
+			t.Error("Per-day export should not write post notes")
+		}
+	})
+}
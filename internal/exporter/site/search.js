@@ -0,0 +1,45 @@
+// Client-side search over search-index.js (window.searchIndex)
+// Each entry is {p: post page, d: date, t: text}; every word must appear in the text
+(function() {
+    var input = document.getElementById('search');
+    var status = document.getElementById('search-status');
+    var results = document.getElementById('search-results');
+    var index = window.searchIndex || [];
+    var limit = 100;
+
+    function render(query) {
+        results.textContent = '';
+        var words = query.toLowerCase().split(/\s+/).filter(Boolean);
+        if (words.length === 0) {
+            status.textContent = 'Type to search ' + index.length + ' posts. All words must match.';
+            return;
+        }
+
+        var matches = index.filter(function(entry) {
+            var text = entry.t.toLowerCase();
+            return words.every(function(word) { return text.indexOf(word) !== -1; });
+        });
+        status.textContent = matches.length + ' matching posts' + (matches.length > limit ? ' (showing ' + limit + ')' : '');
+
+        matches.slice(0, limit).forEach(function(entry) {
+            var article = document.createElement('article');
+            var link = document.createElement('a');
+            link.href = entry.p;
+            link.textContent = entry.d;
+            var date = document.createElement('small');
+            date.appendChild(link);
+            var text = document.createElement('p');
+            text.className = 'text';
+            text.textContent = entry.t;
+            article.appendChild(date);
+            article.appendChild(text);
+            results.appendChild(article);
+        });
+    }
+
+    input.addEventListener('input', function() { render(input.value); });
+    if (location.hash.length > 1) {
+        input.value = decodeURIComponent(location.hash.slice(1));
+        render(input.value);
+    }
+})();
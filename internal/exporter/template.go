@@ -0,0 +1,223 @@
+package exporter
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+	"github.com/shindakun/bskyarchive/internal/version"
+)
+
+// exportTemplateExt is the file extension of custom export templates
+const exportTemplateExt = ".tmpl"
+
+// ExportTemplate is a custom export format found in the templates directory
+// A template posts.xml.tmpl is named "posts.xml" and writes that file
+type ExportTemplate struct {
+	Name string
+	Path string
+}
+
+// TemplateExport is the data of a template's header and footer sections
+type TemplateExport struct {
+	DID        string
+	Profile    *models.Profile // Latest profile snapshot; nil if none was archived
+	DateRange  *models.DateRange
+	Total      int // Posts in the export
+	ExportedAt time.Time
+	Version    string
+}
+
+// TemplatePost is the data a template's body is rendered with, once per post
+type TemplatePost struct {
+	Post    models.Post
+	Media   []models.Media
+	Profile *models.Profile
+	Export  *TemplateExport
+	Index   int  // Position in the export, starting at 0 (newest first)
+	First   bool // Index is 0, e.g. to separate items with commas
+}
+
+// exportTemplateFuncs are the functions available to custom templates
+var exportTemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// xml escapes text for XML content and attributes
+	"xml": func(s string) (string, error) {
+		var b strings.Builder
+		err := xml.EscapeText(&b, []byte(s))
+		return b.String(), err
+	},
+	// csv quotes a value as one CSV field
+	"csv": func(s string) (string, error) {
+		var b strings.Builder
+		w := csv.NewWriter(&b)
+		if err := w.Write([]string{s}); err != nil {
+			return "", err
+		}
+		w.Flush()
+		return strings.TrimSuffix(b.String(), "\n"), w.Error()
+	},
+	// date formats a time in UTC with a Go layout, e.g. {{date "2006-01-02" .Post.CreatedAt}}
+	"date": func(layout string, t time.Time) string {
+		return t.UTC().Format(layout)
+	},
+	// mediaPath is where a media file is in the export when media files are included
+	"mediaPath": func(m models.Media) string {
+		return "media/" + filepath.Base(m.FilePath)
+	},
+	"join": strings.Join,
+}
+
+// ListExportTemplates lists the custom templates in dir by name
+// An empty dir means custom templates are not configured
+func ListExportTemplates(dir string) ([]ExportTemplate, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+
+	var templates []ExportTemplate
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, exportTemplateExt) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		templateName := strings.TrimSuffix(name, exportTemplateExt)
+		if !models.ValidTemplateName(templateName) {
+			log.Printf("Warning: skipping export template %s: %s is reserved for the export itself", name, templateName)
+			continue
+		}
+		templates = append(templates, ExportTemplate{
+			Name: templateName,
+			Path: filepath.Join(dir, name),
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	return templates, nil
+}
+
+// ParseExportTemplate parses a custom template; its body is rendered for each post and
+// optional "header" and "footer" sections before and after the posts
+func ParseExportTemplate(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(exportTemplateFuncs).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", filepath.Base(path), err)
+	}
+	return tmpl, nil
+}
+
+// ExportToTemplate renders a custom template to outputPath: the header section, the
+// body once per post (newest first, in batches) and the footer section
+func ExportToTemplate(db *sql.DB, did string, dateRange *models.DateRange, templatePath, outputPath string, batchSize int) error {
+	tmpl, err := ParseExportTemplate(templatePath)
+	if err != nil {
+		return err
+	}
+
+	total, err := storage.CountPostsWithDateRange(db, did, dateRange)
+	if err != nil {
+		return err
+	}
+	export := &TemplateExport{
+		DID:        did,
+		DateRange:  dateRange,
+		Total:      total,
+		ExportedAt: time.Now(),
+		Version:    version.GetVersion(),
+	}
+	if profile, err := storage.GetLatestProfile(db, did); err == nil {
+		export.Profile = profile
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(outputPath), err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	section := func(name string) error {
+		if tmpl.Lookup(name) == nil {
+			return nil
+		}
+		if err := tmpl.ExecuteTemplate(w, name, export); err != nil {
+			return fmt.Errorf("failed to render %s section: %w", name, err)
+		}
+		return nil
+	}
+
+	if err := section("header"); err != nil {
+		return err
+	}
+
+	offset := 0
+	for {
+		batch, err := storage.ListPostsWithDateRange(db, did, dateRange, batchSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to fetch batch at offset %d: %w", offset, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		uris := make([]string, len(batch))
+		for i, post := range batch {
+			uris[i] = post.URI
+		}
+		mediaMap, err := storage.ListMediaForPosts(db, uris)
+		if err != nil {
+			return err
+		}
+
+		for i, post := range batch {
+			data := TemplatePost{
+				Post:    post,
+				Media:   mediaMap[post.URI],
+				Profile: export.Profile,
+				Export:  export,
+				Index:   offset + i,
+				First:   offset+i == 0,
+			}
+			if err := tmpl.Execute(w, data); err != nil {
+				return fmt.Errorf("failed to render post %s: %w", post.URI, err)
+			}
+		}
+
+		offset += len(batch)
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	if err := section("footer"); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(outputPath), err)
+	}
+	return file.Close()
+}
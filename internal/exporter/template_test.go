@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+func TestListExportTemplates(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"posts.xml.tmpl", "digest.txt.tmpl", ".hidden.tmpl", "notes.txt", "manifest.json.tmpl", "Media.tmpl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{{.Post.Text}}"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	templates, err := ListExportTemplates(dir)
+	if err != nil {
+		t.Fatalf("ListExportTemplates failed: %v", err)
+	}
+	if len(templates) != 2 || templates[0].Name != "digest.txt" || templates[1].Name != "posts.xml" {
+		t.Errorf("Expected digest.txt and posts.xml, got %+v", templates)
+	}
+
+	if templates, err := ListExportTemplates(""); err != nil || templates != nil {
+		t.Errorf("No directory should list no templates: %v %v", templates, err)
+	}
+}
+
+func TestExportOptions_TemplateNames(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"posts.xml":     true,
+		"digest.txt":    true,
+		"manifest.json": false, // Overwritten by the export's manifest
+		"MEDIA":         false, // Collides with the media directory
+		"media":         false,
+		"../posts.xml":  false,
+		".hidden":       false,
+	} {
+		opts := models.ExportOptions{Format: models.ExportFormatTemplate, DID: "did:plc:x", Template: name}
+		if err := opts.Validate(); (err == nil) != valid {
+			t.Errorf("Template %q: expected valid=%v, got %v", name, valid, err)
+		}
+	}
+}
+
+func TestExportToTemplate(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:templated"
+	if err := storage.SaveProfile(db, &models.Profile{DID: did, Handle: "templated.test", DisplayName: "Templated", SnapshotAt: time.Now()}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+	created := time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC)
+	for i, text := range []string{"First & oldest", "Second", "Third <newest>"} {
+		post := models.Post{
+			URI:       "at://" + did + "/app.bsky.feed.post/" + string(rune('a'+i)),
+			CID:       "cid",
+			DID:       did,
+			Text:      text,
+			CreatedAt: created.AddDate(0, 0, i),
+			IndexedAt: created,
+			HasMedia:  i == 0,
+		}
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	if err := storage.SaveMedia(db, &models.Media{Hash: strings.Repeat("c", 64), PostURI: "at://" + did + "/app.bsky.feed.post/a",
+		MimeType: "image/jpeg", FilePath: "media/cc/photo.jpg", AltText: "A photo", CreatedAt: created}); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	templatePath := filepath.Join(t.TempDir(), "posts.xml.tmpl")
+	content := `{{define "header"}}<posts author="{{xml .Profile.Handle}}" total="{{.Total}}">
+{{end}}{{define "footer"}}</posts>
+{{end}}  <post n="{{.Index}}" date="{{date "2006-01-02" .Post.CreatedAt}}">{{xml .Post.Text}}{{range .Media}}<img src="{{mediaPath .}}" alt="{{xml .AltText}}"/>{{end}}</post>
+`
+	if err := os.WriteFile(templatePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	// A batch size of 2 makes the posts arrive over two batches
+	outputPath := filepath.Join(t.TempDir(), "posts.xml")
+	if err := ExportToTemplate(db, did, nil, templatePath, outputPath, 2); err != nil {
+		t.Fatalf("ExportToTemplate failed: %v", err)
+	}
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	want := `<posts author="templated.test" total="3">
+  <post n="0" date="2025-03-07">Third &lt;newest&gt;</post>
+  <post n="1" date="2025-03-06">Second</post>
+  <post n="2" date="2025-03-05">First &amp; oldest<img src="media/photo.jpg" alt="A photo"/></post>
+</posts>
+`
+	if string(output) != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", output, want)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "broken.tmpl")
+	if err := os.WriteFile(invalid, []byte("{{.Post.Text"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if err := ExportToTemplate(db, did, nil, invalid, filepath.Join(t.TempDir(), "broken"), 2); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
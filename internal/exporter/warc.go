@@ -0,0 +1,350 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+	"github.com/shindakun/bskyarchive/internal/version"
+)
+
+const (
+	warcFilename   = "archive.warc.gz"
+	cdxjFilename   = "index.cdxj"
+	warcDateLayout = "2006-01-02T15:04:05Z"
+)
+
+// defaultWARCBaseURL is the archive address used in WARC exports when none is configured
+const defaultWARCBaseURL = "http://localhost:8080"
+
+// warcField is one named header of a WARC record, kept in order
+type warcField struct {
+	name  string
+	value string
+}
+
+// cdxjEntry is the JSON block of a CDXJ line, in the fields pywb reads
+type cdxjEntry struct {
+	URL      string `json:"url"`
+	Mime     string `json:"mime"`
+	Status   string `json:"status"`
+	Digest   string `json:"digest"`
+	Length   string `json:"length"`
+	Offset   string `json:"offset"`
+	Filename string `json:"filename"`
+}
+
+// warcWriter writes gzip-compressed WARC records, one gzip member per record, and
+// collects a CDXJ line for every response so replay tools can seek to it
+type warcWriter struct {
+	out    io.Writer
+	offset int64
+	index  []string
+}
+
+// ExportToWARC writes the posts in the date range as a WARC 1.1 file (archive.warc.gz)
+// with a CDXJ index (index.cdxj) for pywb and other web archive tools. Each post is
+// captured as a request and response for its JSON view on the archive at baseURL, and
+// each media file as a request and response for the URL it was downloaded from; media
+// archived before source URLs were recorded is captured at the archive's /media/ address
+func ExportToWARC(db *sql.DB, did string, dateRange *models.DateRange, exportDir, baseURL string, batchSize int) error {
+	if baseURL == "" {
+		baseURL = defaultWARCBaseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	file, err := os.Create(filepath.Join(exportDir, warcFilename))
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", warcFilename, err)
+	}
+	defer file.Close()
+
+	w := &warcWriter{out: file}
+	if err := w.writeInfo(); err != nil {
+		return err
+	}
+
+	offset := 0
+	for {
+		batch, err := storage.ListPostsWithDateRange(db, did, dateRange, batchSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to fetch batch at offset %d: %w", offset, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		uris := make([]string, len(batch))
+		for i, post := range batch {
+			uris[i] = post.URI
+		}
+		mediaMap, err := storage.ListMediaForPosts(db, uris)
+		if err != nil {
+			return err
+		}
+		engagement, err := storage.ListEngagementForPosts(db, uris)
+		if err != nil {
+			return err
+		}
+
+		for i := range batch {
+			post := &batch[i]
+			download := models.PostDownload{Post: post, Media: mediaMap[post.URI], Engagement: engagement[post.URI]}
+			if err := w.writePost(&download, baseURL); err != nil {
+				return err
+			}
+			for _, m := range mediaMap[post.URI] {
+				if err := w.writeMedia(m, baseURL); err != nil {
+					return err
+				}
+			}
+		}
+
+		offset += len(batch)
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", warcFilename, err)
+	}
+
+	// CDXJ indexes are sorted so lookups can binary search by URL key and time
+	sort.Strings(w.index)
+	index := strings.Join(w.index, "\n")
+	if index != "" {
+		index += "\n"
+	}
+	if err := os.WriteFile(filepath.Join(exportDir, cdxjFilename), []byte(index), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", cdxjFilename, err)
+	}
+
+	return nil
+}
+
+// writeInfo writes the warcinfo record that opens the file
+func (w *warcWriter) writeInfo() error {
+	info := "software: bskyarchive/" + version.GetVersion() + "\r\n" +
+		"format: WARC File Format 1.1\r\n" +
+		"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"
+	_, _, err := w.writeRecord([]warcField{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", warcRecordID()},
+		{"WARC-Date", time.Now().UTC().Format(warcDateLayout)},
+		{"WARC-Filename", warcFilename},
+		{"Content-Type", "application/warc-fields"},
+		{"WARC-Block-Digest", warcDigest(sha1.Sum([]byte(info)))},
+	}, strings.NewReader(info), int64(len(info)))
+	return err
+}
+
+// writePost captures the post's JSON view, the same document /post/{did}/{rkey}/json serves
+func (w *warcWriter) writePost(download *models.PostDownload, baseURL string) error {
+	post := download.Post
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(download); err != nil {
+		return fmt.Errorf("failed to encode post %s: %w", post.URI, err)
+	}
+
+	capturedAt := post.IndexedAt
+	if capturedAt.IsZero() {
+		capturedAt = post.CreatedAt
+	}
+	target := baseURL + "/post/" + post.DID + "/" + post.RKey() + "/json"
+	payload := bytes.NewReader(body.Bytes())
+	return w.writeCapture(target, "application/json", capturedAt, payload, int64(body.Len()))
+}
+
+// writeMedia captures a media file from the media store; missing files are skipped
+func (w *warcWriter) writeMedia(m models.Media, baseURL string) error {
+	target := m.SourceURL
+	if target == "" {
+		target = baseURL + "/media/" + m.Hash
+	}
+
+	file, err := os.Open(m.FilePath)
+	if err != nil {
+		log.Printf("Warning: media file for %s is missing, leaving it out of the WARC: %v", target, err)
+		return nil
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		log.Printf("Warning: media file for %s is unreadable, leaving it out of the WARC", target)
+		return nil
+	}
+
+	return w.writeCapture(target, m.MimeType, m.CreatedAt, file, info.Size())
+}
+
+// writeCapture writes a GET request record and the 200 response record it received,
+// then indexes the response. The payload is read twice: once for digests, once to write
+func (w *warcWriter) writeCapture(target, mimeType string, capturedAt time.Time, payload io.ReadSeeker, size int64) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid capture URL %s: %w", target, err)
+	}
+	date := capturedAt.UTC().Format(warcDateLayout)
+	responseID := warcRecordID()
+
+	request := "GET " + u.RequestURI() + " HTTP/1.1\r\nHost: " + u.Host + "\r\n\r\n"
+	if _, _, err := w.writeRecord([]warcField{
+		{"WARC-Type", "request"},
+		{"WARC-Record-ID", warcRecordID()},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", target},
+		{"WARC-Concurrent-To", responseID},
+		{"Content-Type", "application/http; msgtype=request"},
+		{"WARC-Block-Digest", warcDigest(sha1.Sum([]byte(request)))},
+	}, strings.NewReader(request), int64(len(request))); err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n", mimeType, size)
+	payloadHash, blockHash := sha1.New(), sha1.New()
+	blockHash.Write([]byte(header))
+	if _, err := io.Copy(io.MultiWriter(payloadHash, blockHash), payload); err != nil {
+		return fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	if _, err := payload.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read %s: %w", target, err)
+	}
+	payloadDigest := warcDigest(sumOf(payloadHash))
+
+	offset, length, err := w.writeRecord([]warcField{
+		{"WARC-Type", "response"},
+		{"WARC-Record-ID", responseID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", target},
+		{"Content-Type", "application/http; msgtype=response"},
+		{"WARC-Payload-Digest", payloadDigest},
+		{"WARC-Block-Digest", warcDigest(sumOf(blockHash))},
+	}, io.MultiReader(strings.NewReader(header), payload), int64(len(header))+size)
+	if err != nil {
+		return err
+	}
+
+	entry, err := json.Marshal(cdxjEntry{
+		URL:      target,
+		Mime:     mimeType,
+		Status:   "200",
+		Digest:   payloadDigest,
+		Length:   fmt.Sprint(length),
+		Offset:   fmt.Sprint(offset),
+		Filename: warcFilename,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", target, err)
+	}
+	w.index = append(w.index, surtKey(target)+" "+capturedAt.UTC().Format("20060102150405")+" "+string(entry))
+	return nil
+}
+
+// writeRecord writes one record as its own gzip member and returns where the member
+// starts in the file and its compressed length
+func (w *warcWriter) writeRecord(fields []warcField, block io.Reader, length int64) (int64, int64, error) {
+	var head strings.Builder
+	head.WriteString("WARC/1.1\r\n")
+	for _, f := range fields {
+		head.WriteString(f.name + ": " + f.value + "\r\n")
+	}
+	fmt.Fprintf(&head, "Content-Length: %d\r\n\r\n", length)
+
+	start := w.offset
+	counter := &countingWriter{w: w.out}
+	gz := gzip.NewWriter(counter)
+	if _, err := io.WriteString(gz, head.String()); err != nil {
+		return 0, 0, fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	if _, err := io.Copy(gz, block); err != nil {
+		return 0, 0, fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	if _, err := io.WriteString(gz, "\r\n\r\n"); err != nil {
+		return 0, 0, fmt.Errorf("failed to write WARC record: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return 0, 0, fmt.Errorf("failed to write WARC record: %w", err)
+	}
+
+	w.offset += counter.n
+	return start, counter.n, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// warcRecordID is a new record ID in the urn:uuid form WARC uses
+func warcRecordID() string {
+	return "<urn:uuid:" + uuid.NewString() + ">"
+}
+
+// warcDigest formats a SHA-1 sum as WARC digests are written: sha1:BASE32
+func warcDigest(sum [sha1.Size]byte) string {
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// sumOf returns the SHA-1 sum a hash has accumulated
+func sumOf(h hash.Hash) [sha1.Size]byte {
+	var sum [sha1.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// surtKey is the Sort-friendly URI Reordering Transform of a URL used as the CDXJ key,
+// e.g. https://cdn.bsky.app/img/a.jpg becomes app,bsky,cdn)/img/a.jpg
+func surtKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return strings.ToLower(rawURL)
+	}
+
+	labels := strings.Split(strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	key := strings.Join(labels, ",")
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		key += ":" + port
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key += ")" + strings.ToLower(path)
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		sort.Strings(params)
+		key += "?" + strings.ToLower(strings.Join(params, "&"))
+	}
+	return key
+}
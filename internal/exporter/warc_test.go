@@ -0,0 +1,143 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+func TestExportToWARC(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:warc"
+	created := time.Date(2025, 4, 2, 8, 30, 0, 0, time.UTC)
+	for _, post := range []models.Post{
+		{URI: "at://" + did + "/app.bsky.feed.post/3kone", Text: "Captured post", CreatedAt: created, HasMedia: true},
+		{URI: "at://" + did + "/app.bsky.feed.post/3ktwo", Text: "Another post", CreatedAt: created.Add(time.Hour)},
+	} {
+		post.CID = "cid"
+		post.DID = did
+		post.IndexedAt = created.AddDate(0, 0, 1)
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+
+	mediaDir := t.TempDir()
+	photo := filepath.Join(mediaDir, "photo.jpg")
+	if err := os.WriteFile(photo, []byte("jpeg bytes"), 0644); err != nil {
+		t.Fatalf("Failed to write media: %v", err)
+	}
+	legacy := filepath.Join(mediaDir, "legacy.png")
+	if err := os.WriteFile(legacy, []byte("png bytes"), 0644); err != nil {
+		t.Fatalf("Failed to write media: %v", err)
+	}
+	for _, m := range []models.Media{
+		{Hash: strings.Repeat("a", 64), MimeType: "image/jpeg", FilePath: photo, SourceURL: "https://cdn.bsky.app/img/feed_fullsize/plain/" + did + "/bafkreiphoto@jpeg"},
+		{Hash: strings.Repeat("b", 64), MimeType: "image/png", FilePath: legacy},
+		{Hash: strings.Repeat("c", 64), MimeType: "image/gif", FilePath: filepath.Join(mediaDir, "missing.gif")},
+	} {
+		m.PostURI = "at://" + did + "/app.bsky.feed.post/3kone"
+		m.CreatedAt = created.AddDate(0, 0, 2)
+		if err := storage.SaveMedia(db, &m); err != nil {
+			t.Fatalf("SaveMedia failed: %v", err)
+		}
+	}
+
+	exportDir := t.TempDir()
+	if err := ExportToWARC(db, did, nil, exportDir, "https://archive.example/", 1); err != nil {
+		t.Fatalf("ExportToWARC failed: %v", err)
+	}
+
+	warc, err := os.ReadFile(filepath.Join(exportDir, "archive.warc.gz"))
+	if err != nil {
+		t.Fatalf("Failed to read WARC: %v", err)
+	}
+	all, err := gzip.NewReader(bytes.NewReader(warc))
+	if err != nil {
+		t.Fatalf("WARC is not gzip: %v", err)
+	}
+	content, err := io.ReadAll(all)
+	if err != nil {
+		t.Fatalf("Failed to decompress WARC: %v", err)
+	}
+	if !bytes.HasPrefix(content, []byte("WARC/1.1\r\nWARC-Type: warcinfo\r\n")) {
+		t.Errorf("WARC should open with a warcinfo record:\n%.200s", content)
+	}
+	if got := strings.Count(string(content), "WARC-Type: request\r\n"); got != 4 {
+		t.Errorf("Expected a request for 2 posts and 2 media files, got %d", got)
+	}
+
+	index, err := os.ReadFile(filepath.Join(exportDir, "index.cdxj"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(index), "\n"), "\n")
+	wantKeys := []string{
+		"app,bsky,cdn)/img/feed_fullsize/plain/did:plc:warc/bafkreiphoto@jpeg 20250404083000",
+		"example,archive)/media/" + strings.Repeat("b", 64) + " 20250404083000",
+		"example,archive)/post/did:plc:warc/3kone/json 20250403083000",
+		"example,archive)/post/did:plc:warc/3ktwo/json 20250403083000",
+	}
+	if len(lines) != len(wantKeys) {
+		t.Fatalf("Expected %d index lines, got:\n%s", len(wantKeys), index)
+	}
+
+	for i, line := range lines {
+		parts := strings.SplitN(line, " ", 3)
+		if key := parts[0] + " " + parts[1]; key != wantKeys[i] {
+			t.Errorf("Line %d: expected key %q, got %q", i, wantKeys[i], key)
+		}
+		var entry struct {
+			URL    string `json:"url"`
+			Mime   string `json:"mime"`
+			Status string `json:"status"`
+			Digest string `json:"digest"`
+			Length int64  `json:"length,string"`
+			Offset int64  `json:"offset,string"`
+		}
+		if err := json.Unmarshal([]byte(parts[2]), &entry); err != nil {
+			t.Fatalf("Line %d has invalid JSON: %v", i, err)
+		}
+
+		// Each indexed record is a gzip member that decompresses on its own
+		member, err := gzip.NewReader(bytes.NewReader(warc[entry.Offset : entry.Offset+entry.Length]))
+		if err != nil {
+			t.Fatalf("%s: offset does not start a gzip member: %v", entry.URL, err)
+		}
+		member.Multistream(false)
+		record, err := io.ReadAll(member)
+		if err != nil {
+			t.Fatalf("%s: failed to read record: %v", entry.URL, err)
+		}
+		for _, want := range []string{"WARC-Type: response\r\n", "WARC-Target-URI: " + entry.URL + "\r\n",
+			"WARC-Payload-Digest: " + entry.Digest + "\r\n", "Content-Type: " + entry.Mime + "\r\n"} {
+			if !strings.Contains(string(record), want) {
+				t.Errorf("%s: record missing %q:\n%s", entry.URL, want, record)
+			}
+		}
+		if !strings.HasSuffix(string(record), "\r\n\r\n") {
+			t.Errorf("%s: record should end with two CRLFs", entry.URL)
+		}
+	}
+
+	if !strings.Contains(string(content), "jpeg bytes") || strings.Contains(string(content), "WARC-Target-URI: https://archive.example/media/"+strings.Repeat("c", 64)) {
+		t.Error("WARC should hold the media files that exist")
+	}
+	if !strings.Contains(string(content), `"text": "Captured post"`) {
+		t.Error("WARC should hold the post JSON view")
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// InstanceStats summarizes the whole instance for the admin page
+type InstanceStats struct {
+	Accounts         int64 `json:"accounts"`          // Local accounts (linked identities count once)
+	Identities       int64 `json:"identities"`        // Signed-in Bluesky identities
+	Posts            int64 `json:"posts"`             // Posts from every archive, including watched accounts
+	Media            int64 `json:"media"`             // Media files
+	MediaBytes       int64 `json:"media_bytes"`       // Total size of media files
+	Exports          int64 `json:"exports"`           // Export records
+	WatchedAccounts  int64 `json:"watched_accounts"`  // Accounts on the watchlist
+	ActiveOperations int64 `json:"active_operations"` // Pending or running archive operations
+}
+
+// MediaSizeMB returns the media size in megabytes
+func (s *InstanceStats) MediaSizeMB() float64 {
+	return float64(s.MediaBytes) / (1024 * 1024)
+}
+
+// UserSummary describes one signed-in identity for the admin page
+type UserSummary struct {
+	Session
+	TotalPosts    int64      `json:"total_posts"`
+	LastArchiveAt *time.Time `json:"last_archive_at,omitempty"`
+}
@@ -10,10 +10,102 @@ import (
 type ExportFormat string
 
 const (
-	ExportFormatJSON ExportFormat = "json"
-	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatJSON        ExportFormat = "json"
+	ExportFormatCSV         ExportFormat = "csv"
+	ExportFormatHTML        ExportFormat = "html"        // Static site that opens without a server
+	ExportFormatJSONL       ExportFormat = "jsonl"       // One JSON object per line
+	ExportFormatSQLite      ExportFormat = "sqlite"      // Standalone database for SQL tools
+	ExportFormatMarkdown    ExportFormat = "markdown"    // Notes for Obsidian and other Markdown tools
+	ExportFormatActivityPub ExportFormat = "activitypub" // Mastodon-style outbox.json and actor.json
+	ExportFormatFeed        ExportFormat = "feed"        // Atom and RSS feeds (atom.xml, rss.xml)
+	ExportFormatEPUB        ExportFormat = "epub"        // E-book with a chapter per month
+	ExportFormatTemplate    ExportFormat = "template"    // Custom text/template from export.templates_dir
+	ExportFormatWARC        ExportFormat = "warc"        // WARC file with a CDXJ index for web archive tools
 )
 
+// ExportFormats lists the accepted export formats
+var ExportFormats = []ExportFormat{ExportFormatJSON, ExportFormatCSV, ExportFormatHTML, ExportFormatJSONL, ExportFormatSQLite, ExportFormatMarkdown, ExportFormatActivityPub, ExportFormatFeed, ExportFormatEPUB, ExportFormatTemplate, ExportFormatWARC}
+
+// Valid reports whether the format is one of ExportFormats
+func (f ExportFormat) Valid() bool {
+	for _, format := range ExportFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// formatList returns the accepted formats for error messages, e.g. "json, csv, html"
+func formatList() string {
+	names := make([]string, len(ExportFormats))
+	for i, format := range ExportFormats {
+		names[i] = string(format)
+	}
+	return strings.Join(names, ", ")
+}
+
+// CSVColumn is a column the CSV export can write
+type CSVColumn struct {
+	Name        string
+	Description string
+	Default     bool // Part of the standard 15-column layout
+}
+
+// CSVColumns lists the CSV export's columns in their standard order
+// Columns with several values (media, alt texts, links, hashtags) join them with semicolons
+var CSVColumns = []CSVColumn{
+	{"URI", "AT URI of the post", true},
+	{"CID", "Content ID of the post record", true},
+	{"DID", "Author's DID", true},
+	{"Handle", "Author's handle from the latest profile snapshot", false},
+	{"Text", "Post text", true},
+	{"CreatedAt", "When the post was created", true},
+	{"PostURL", "Link to the post on bsky.app", false},
+	{"LikeCount", "Likes", true},
+	{"RepostCount", "Reposts", true},
+	{"ReplyCount", "Replies", true},
+	{"QuoteCount", "Quotes", true},
+	{"IsReply", "Whether the post is a reply", true},
+	{"ReplyParent", "AT URI of the post replied to", true},
+	{"HasMedia", "Whether the post has media", true},
+	{"MediaFiles", "Media blob hashes", true},
+	{"MediaPaths", "Media file paths relative to the export (media/...)", false},
+	{"AltTexts", "Image alt texts", false},
+	{"LinkURLs", "Links in the text and the link card", false},
+	{"Hashtags", "Hashtags without the #", false},
+	{"EmbedType", "Kind of embed (images, external, record, record_with_media)", true},
+	{"IndexedAt", "When the post was archived", true},
+}
+
+// DefaultCSVColumns returns the names of the standard 15 columns
+func DefaultCSVColumns() []string {
+	var names []string
+	for _, column := range CSVColumns {
+		if column.Default {
+			names = append(names, column.Name)
+		}
+	}
+	return names
+}
+
+// CSV field delimiters
+const (
+	CSVDelimiterComma     = "comma"
+	CSVDelimiterTab       = "tab"
+	CSVDelimiterSemicolon = "semicolon"
+)
+
+// validCSVColumn reports whether name is one of CSVColumns
+func validCSVColumn(name string) bool {
+	for _, column := range CSVColumns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // DateRange represents an optional time range filter for exports
 type DateRange struct {
 	StartDate time.Time `json:"start_date"`
@@ -25,7 +117,8 @@ func (dr *DateRange) Validate() error {
 	if dr == nil {
 		return nil
 	}
-	if dr.EndDate.Before(dr.StartDate) {
+	// Open-ended ranges (only a start or only an end date) are allowed
+	if !dr.StartDate.IsZero() && !dr.EndDate.IsZero() && dr.EndDate.Before(dr.StartDate) {
 		return fmt.Errorf("end date must be after start date")
 	}
 	return nil
@@ -33,7 +126,7 @@ func (dr *DateRange) Validate() error {
 
 // ExportOptions defines user-configurable options for export operations
 type ExportOptions struct {
-	// Format specifies output format: "json" or "csv"
+	// Format specifies the output format (one of ExportFormats)
 	Format ExportFormat `json:"format"`
 
 	// OutputDir is the base directory for exports (default: "./exports")
@@ -42,6 +135,33 @@ type ExportOptions struct {
 	// IncludeMedia determines if media files should be copied
 	IncludeMedia bool `json:"include_media"`
 
+	// Companions adds media.jsonl, profiles.jsonl and operations.jsonl (jsonl format only)
+	Companions bool `json:"companions,omitempty"`
+
+	// MarkdownByDay writes one note per day instead of one per post (markdown format only)
+	MarkdownByDay bool `json:"markdown_by_day,omitempty"`
+
+	// ExcludeReplies leaves replies out of the feeds (feed format only)
+	ExcludeReplies bool `json:"exclude_replies,omitempty"`
+
+	// Template names the custom template to render (template format only)
+	Template string `json:"template,omitempty"`
+
+	// CSVColumns picks and orders the CSV columns (csv format only; empty = DefaultCSVColumns)
+	CSVColumns []string `json:"csv_columns,omitempty"`
+
+	// CSVDelimiter separates CSV fields: comma (default), tab or semicolon (csv format only)
+	CSVDelimiter string `json:"csv_delimiter,omitempty"`
+
+	// CSVNoBOM leaves out the UTF-8 byte order mark Excel needs to detect the encoding (csv format only)
+	CSVNoBOM bool `json:"csv_no_bom,omitempty"`
+
+	// TemplatesDir is the directory holding custom templates (export.templates_dir)
+	TemplatesDir string `json:"-"`
+
+	// BaseURL is the archive's address, used for the post URLs in WARC exports
+	BaseURL string `json:"-"`
+
 	// DateRange filters posts by creation date (nil = all posts)
 	DateRange *DateRange `json:"date_range,omitempty"`
 
@@ -49,10 +169,61 @@ type ExportOptions struct {
 	DID string `json:"did"`
 }
 
+// reservedTemplateNames are written next to a template's output by every export
+var reservedTemplateNames = []string{"manifest.json", "media"}
+
+// ValidTemplateName reports whether a custom template may use name as its output file:
+// a plain file name that doesn't collide with the manifest or the media directory
+func ValidTemplateName(name string) bool {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return false
+	}
+	for _, reserved := range reservedTemplateNames {
+		// Case-insensitive file systems would collide on any casing
+		if strings.EqualFold(name, reserved) {
+			return false
+		}
+	}
+	return true
+}
+
 // Validate checks if export options are valid
 func (opts *ExportOptions) Validate() error {
-	if opts.Format != ExportFormatJSON && opts.Format != ExportFormatCSV {
-		return fmt.Errorf("format must be 'json' or 'csv'")
+	if !opts.Format.Valid() {
+		return fmt.Errorf("format must be one of: %s", formatList())
+	}
+	if opts.Companions && opts.Format != ExportFormatJSONL {
+		return fmt.Errorf("companion files require the %s format", ExportFormatJSONL)
+	}
+	if opts.MarkdownByDay && opts.Format != ExportFormatMarkdown {
+		return fmt.Errorf("notes per day require the %s format", ExportFormatMarkdown)
+	}
+	if opts.ExcludeReplies && opts.Format != ExportFormatFeed {
+		return fmt.Errorf("excluding replies requires the %s format", ExportFormatFeed)
+	}
+	if (opts.Template != "") != (opts.Format == ExportFormatTemplate) {
+		return fmt.Errorf("the %s format requires a template name, and a template name requires the %s format", ExportFormatTemplate, ExportFormatTemplate)
+	}
+	if opts.Template != "" && !ValidTemplateName(opts.Template) {
+		return fmt.Errorf("invalid template name: %s", opts.Template)
+	}
+	if (len(opts.CSVColumns) > 0 || opts.CSVDelimiter != "" || opts.CSVNoBOM) && opts.Format != ExportFormatCSV {
+		return fmt.Errorf("column, delimiter and BOM options require the %s format", ExportFormatCSV)
+	}
+	seen := make(map[string]bool)
+	for _, name := range opts.CSVColumns {
+		if !validCSVColumn(name) {
+			return fmt.Errorf("unknown CSV column: %s", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("CSV column %s is listed twice", name)
+		}
+		seen[name] = true
+	}
+	switch opts.CSVDelimiter {
+	case "", CSVDelimiterComma, CSVDelimiterTab, CSVDelimiterSemicolon:
+	default:
+		return fmt.Errorf("CSV delimiter must be one of: %s, %s, %s", CSVDelimiterComma, CSVDelimiterTab, CSVDelimiterSemicolon)
 	}
 	if opts.DID == "" {
 		return fmt.Errorf("DID is required")
@@ -116,7 +287,7 @@ type ExportJob struct {
 
 // ExportManifest describes the contents of an export
 type ExportManifest struct {
-	// ExportFormat is "json" or "csv"
+	// ExportFormat is one of ExportFormats
 	ExportFormat string `json:"export_format"`
 
 	// ExportTimestamp is when export was created
@@ -143,7 +314,7 @@ type ExportManifest struct {
 type ExportRecord struct {
 	ID             string     `json:"id"`                           // Format: {did}/{timestamp}
 	DID            string     `json:"did"`                          // Owner's DID for security
-	Format         string     `json:"format"`                       // "json" or "csv"
+	Format         string     `json:"format"`                       // One of ExportFormats
 	CreatedAt      time.Time  `json:"created_at"`                   // When export was created
 	DirectoryPath  string     `json:"directory_path"`               // Full filesystem path
 	PostCount      int        `json:"post_count"`                   // Number of posts
@@ -162,8 +333,8 @@ func (e *ExportRecord) Validate() error {
 	if e.DID == "" {
 		return fmt.Errorf("DID is required")
 	}
-	if e.Format != "json" && e.Format != "csv" {
-		return fmt.Errorf("format must be 'json' or 'csv'")
+	if !ExportFormat(e.Format).Valid() {
+		return fmt.Errorf("format must be one of: %s", formatList())
 	}
 	if e.PostCount < 0 {
 		return fmt.Errorf("post count must be >= 0")
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// FeedFormat is the syndication format of a feed
+type FeedFormat string
+
+const (
+	FeedFormatAtom FeedFormat = "atom" // Atom 1.0
+	FeedFormatRSS  FeedFormat = "rss"  // RSS 2.0
+)
+
+// FeedToken is a secret that lets feed readers fetch one archive's feed without logging in
+// Anyone with the token can read the feed and its media, so it is shown only to its owner
+type FeedToken struct {
+	Token          string     `json:"-" db:"token"`
+	AccountID      string     `json:"-" db:"account_id"` // Local account that created the token
+	DID            string     `json:"did" db:"did"`      // Archive the feed serves
+	Name           string     `json:"name,omitempty" db:"name"`
+	IncludeReplies bool       `json:"include_replies" db:"include_replies"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
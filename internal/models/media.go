@@ -7,15 +7,16 @@ import (
 
 // Media represents media (images, videos) embedded in posts, with local storage information
 type Media struct {
-	Hash      string    `json:"hash" db:"hash"`             // SHA-256 hash (content-addressable)
-	PostURI   string    `json:"post_uri" db:"post_uri"`     // FK to posts table
-	MimeType  string    `json:"mime_type" db:"mime_type"`   // e.g., "image/jpeg"
-	FilePath  string    `json:"file_path" db:"file_path"`   // Local file path
-	SizeBytes int64     `json:"size_bytes" db:"size_bytes"` // File size in bytes
-	Width     int       `json:"width" db:"width"`           // Image/video width
-	Height    int       `json:"height" db:"height"`         // Image/video height
-	AltText   string    `json:"alt_text" db:"alt_text"`     // Accessibility alt text
-	CreatedAt time.Time `json:"created_at" db:"created_at"` // When archived
+	Hash      string    `json:"hash" db:"hash"`                       // SHA-256 hash (content-addressable)
+	PostURI   string    `json:"post_uri" db:"post_uri"`               // FK to posts table
+	MimeType  string    `json:"mime_type" db:"mime_type"`             // e.g., "image/jpeg"
+	FilePath  string    `json:"file_path" db:"file_path"`             // Local file path
+	SizeBytes int64     `json:"size_bytes" db:"size_bytes"`           // File size in bytes
+	Width     int       `json:"width" db:"width"`                     // Image/video width
+	Height    int       `json:"height" db:"height"`                   // Image/video height
+	AltText   string    `json:"alt_text" db:"alt_text"`               // Accessibility alt text
+	SourceURL string    `json:"source_url,omitempty" db:"source_url"` // URL the file was downloaded from
+	CreatedAt time.Time `json:"created_at" db:"created_at"`           // When archived
 }
 
 // Validate checks if the media fields are valid
@@ -26,6 +26,8 @@ type Post struct {
 	EmbedData   json.RawMessage `json:"embed_data,omitempty" db:"embed_data"`
 	Labels      json.RawMessage `json:"labels,omitempty" db:"labels"`
 	ArchivedAt  time.Time       `json:"archived_at" db:"archived_at"`
+	Langs       []string        `json:"langs,omitempty" db:"langs"`   // Languages declared by the author (e.g. "en", "pt-BR")
+	Facets      json.RawMessage `json:"facets,omitempty" db:"facets"` // Rich text facets (links, mentions, hashtags)
 }
 
 // Validate checks if the post fields are valid
@@ -75,19 +77,80 @@ func (p *Post) Validate() error {
 	return nil
 }
 
-// PagedPostsResponse represents a paginated list of posts
+// PagedPostsResponse represents a page of posts with keyset cursors
+// NextCursor and PrevCursor are post URIs for PostListOptions.After and Before;
+// they are empty when there are no more posts in that direction
 type PagedPostsResponse struct {
 	Posts      []Post `json:"posts"`
-	Total      int    `json:"total"`
-	Page       int    `json:"page"`
+	Total      int    `json:"total"` // Posts matching the filter across all pages
 	PageSize   int    `json:"page_size"`
-	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
+// PostSort orders browsed posts
+type PostSort string
+
+const (
+	PostSortNewest  PostSort = "newest"  // Newest posts first (default)
+	PostSortOldest  PostSort = "oldest"  // Oldest posts first
+	PostSortLikes   PostSort = "likes"   // Most liked first
+	PostSortReposts PostSort = "reposts" // Most reposted first
+)
+
+// ReplyFilter selects replies or original posts
+type ReplyFilter string
+
+const (
+	ReplyFilterAll       ReplyFilter = ""          // Replies and original posts
+	ReplyFilterOnly      ReplyFilter = "replies"   // Only replies
+	ReplyFilterOriginals ReplyFilter = "originals" // Only posts that are not replies
+)
+
+// PostFilter narrows browsed posts; the zero value matches every post
+type PostFilter struct {
+	MediaOnly  bool
+	Replies    ReplyFilter
+	QuotesOnly bool
+	Since      time.Time // Inclusive; zero means no lower bound
+	Until      time.Time // Exclusive; zero means no upper bound
+}
+
+// IsZero reports whether the filter matches every post
+func (f PostFilter) IsZero() bool {
+	return f == PostFilter{}
+}
+
+// PostListOptions selects, orders and pages posts for ListPosts
+// Set After to the previous page's NextCursor, or Before to its PrevCursor
+type PostListOptions struct {
+	Filter PostFilter
+	Sort   PostSort
+	Limit  int
+	After  string // Cursor: list posts after this one
+	Before string // Cursor: list posts before this one (paging back)
+}
+
+// SearchSort orders search results
+type SearchSort string
+
+const (
+	SearchSortNewest    SearchSort = "newest"    // Newest posts first (default)
+	SearchSortOldest    SearchSort = "oldest"    // Oldest posts first
+	SearchSortLikes     SearchSort = "likes"     // Most liked first
+	SearchSortReposts   SearchSort = "reposts"   // Most reposted first
+	SearchSortRelevance SearchSort = "relevance" // Best matches first (FTS5 bm25)
+)
+
 // SearchPostsResponse represents search results with highlighting
+// Highlights and Snippets are HTML-escaped with matches wrapped in <mark>; they are
+// only set for posts matched by search words (not for filter-only queries)
 type SearchPostsResponse struct {
-	Posts   []Post `json:"posts"`
-	Total   int    `json:"total"`
-	Query   string `json:"query"`
-	Elapsed string `json:"elapsed"` // Search duration
+	Posts      []Post            `json:"posts"`
+	Total      int               `json:"total"`
+	Query      string            `json:"query"`
+	Sort       SearchSort        `json:"sort"`
+	Elapsed    string            `json:"elapsed"`              // Search duration
+	Highlights map[string]string `json:"highlights,omitempty"` // Post URI to full text with matches marked
+	Snippets   map[string]string `json:"snippets,omitempty"`   // Post URI to an excerpt from the best matching field
 }
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QuotedPost is the post embedded in a quote post, as captured at archive time
+type QuotedPost struct {
+	URI       string
+	DID       string
+	Handle    string
+	Text      string
+	CreatedAt time.Time
+}
+
+// LinkCard is the external link embedded in a post
+type LinkCard struct {
+	URI         string
+	Title       string
+	Description string
+}
+
+// EngagementSnapshot records a post's counts at one point in time
+type EngagementSnapshot struct {
+	LikeCount   int       `json:"like_count"`
+	RepostCount int       `json:"repost_count"`
+	ReplyCount  int       `json:"reply_count"`
+	QuoteCount  int       `json:"quote_count"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// PostDownload is the JSON view of one post with its media and engagement history
+type PostDownload struct {
+	Post       *Post                `json:"post"`
+	Media      []Media              `json:"media,omitempty"`
+	Engagement []EngagementSnapshot `json:"engagement,omitempty"`
+}
+
+// embedView is the subset of the stored embed view read for quotes and link cards
+type embedView struct {
+	External *struct {
+		URI         string `json:"uri"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"external"`
+	Record *embedRecord `json:"record"`
+	Media  *embedView   `json:"media"`
+}
+
+// embedRecord is a quoted record; with media the record view is nested one level deeper
+type embedRecord struct {
+	URI    string `json:"uri"`
+	Author *struct {
+		DID    string `json:"did"`
+		Handle string `json:"handle"`
+	} `json:"author"`
+	Value *struct {
+		Text      string    `json:"text"`
+		CreatedAt time.Time `json:"createdAt"`
+	} `json:"value"`
+	Record *embedRecord `json:"record"`
+}
+
+// embed decodes the stored embed view, or returns nil if there is none
+func (p *Post) embed() *embedView {
+	if len(p.EmbedData) == 0 {
+		return nil
+	}
+	var view embedView
+	if err := json.Unmarshal(p.EmbedData, &view); err != nil {
+		return nil
+	}
+	return &view
+}
+
+// RKey returns the record key, the last segment of the post URI
+func (p *Post) RKey() string {
+	return p.URI[strings.LastIndex(p.URI, "/")+1:]
+}
+
+// PermalinkPath returns the path of the post's page in the archive
+func (p *Post) PermalinkPath() string {
+	return "/post/" + p.DID + "/" + p.RKey()
+}
+
+// BlueskyURL returns the post's address on bsky.app
+func (p *Post) BlueskyURL() string {
+	return "https://bsky.app/profile/" + p.DID + "/post/" + p.RKey()
+}
+
+// Quote returns the quoted post, or nil if the post quotes nothing
+// Blocked, deleted or detached quotes only carry a URI
+func (p *Post) Quote() *QuotedPost {
+	if p.EmbedType != "record" && p.EmbedType != "record_with_media" {
+		return nil
+	}
+	view := p.embed()
+	if view == nil || view.Record == nil {
+		return nil
+	}
+
+	record := view.Record
+	if record.Record != nil {
+		record = record.Record
+	}
+	if record.URI == "" {
+		return nil
+	}
+
+	quote := &QuotedPost{URI: record.URI}
+	if record.Author != nil {
+		quote.DID = record.Author.DID
+		quote.Handle = record.Author.Handle
+	}
+	if record.Value != nil {
+		quote.Text = record.Value.Text
+		quote.CreatedAt = record.Value.CreatedAt
+	}
+	return quote
+}
+
+// Link returns the external link card, or nil if the post has none
+func (p *Post) Link() *LinkCard {
+	view := p.embed()
+	if view == nil {
+		return nil
+	}
+	if view.External == nil && view.Media != nil {
+		view = view.Media
+	}
+	if view.External == nil || view.External.URI == "" {
+		return nil
+	}
+	return &LinkCard{URI: view.External.URI, Title: view.External.Title, Description: view.External.Description}
+}
+
+// LabelValues returns the moderation labels applied to the post (e.g. "nudity")
+func (p *Post) LabelValues() []string {
+	if len(p.Labels) == 0 {
+		return nil
+	}
+	var labels []struct {
+		Val string `json:"val"`
+		Neg bool   `json:"neg"`
+	}
+	if err := json.Unmarshal(p.Labels, &labels); err != nil {
+		return nil
+	}
+
+	var values []string
+	for _, label := range labels {
+		if label.Val != "" && !label.Neg {
+			values = append(values, label.Val)
+		}
+	}
+	return values
+}
+
+// facetFeature is one rich text feature (link, mention or hashtag) of the stored facets
+type facetFeature struct {
+	Type string `json:"$type"`
+	URI  string `json:"uri"`
+	Tag  string `json:"tag"`
+}
+
+// features decodes the features of every facet, in text order
+func (p *Post) features() []facetFeature {
+	if len(p.Facets) == 0 {
+		return nil
+	}
+	var facets []struct {
+		Features []facetFeature `json:"features"`
+	}
+	if err := json.Unmarshal(p.Facets, &facets); err != nil {
+		return nil
+	}
+
+	var features []facetFeature
+	for _, facet := range facets {
+		features = append(features, facet.Features...)
+	}
+	return features
+}
+
+// Tags returns the post's hashtags without the leading #, once each
+func (p *Post) Tags() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, feature := range p.features() {
+		if feature.Type == "app.bsky.richtext.facet#tag" && feature.Tag != "" && !seen[feature.Tag] {
+			seen[feature.Tag] = true
+			tags = append(tags, feature.Tag)
+		}
+	}
+	return tags
+}
+
+// LinkURLs returns the links in the post's text and its link card, once each
+func (p *Post) LinkURLs() []string {
+	var links []string
+	seen := make(map[string]bool)
+	for _, feature := range p.features() {
+		if feature.Type == "app.bsky.richtext.facet#link" && feature.URI != "" && !seen[feature.URI] {
+			seen[feature.URI] = true
+			links = append(links, feature.URI)
+		}
+	}
+	if card := p.Link(); card != nil && !seen[card.URI] {
+		links = append(links, card.URI)
+	}
+	return links
+}
+
+// Markdown formats the post as Markdown: author line, text, alt texts, link, quote and a link back to Bluesky
+// handle may be empty, in which case the DID is shown
+func (p *Post) Markdown(handle string, media []Media) string {
+	author := p.DID
+	if handle != "" {
+		author = "@" + handle
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** · %s\n\n", author, p.CreatedAt.UTC().Format("Jan 2, 2006 15:04 UTC"))
+	if p.Text != "" {
+		b.WriteString(p.Text + "\n\n")
+	}
+	for _, m := range media {
+		if m.AltText != "" {
+			fmt.Fprintf(&b, "*Image: %s*\n\n", m.AltText)
+		}
+	}
+	if link := p.Link(); link != nil {
+		title := link.Title
+		if title == "" {
+			title = link.URI
+		}
+		fmt.Fprintf(&b, "[%s](%s)\n\n", title, link.URI)
+	}
+	if quote := p.Quote(); quote != nil && quote.Text != "" {
+		quoted := quote.DID
+		if quote.Handle != "" {
+			quoted = "@" + quote.Handle
+		}
+		fmt.Fprintf(&b, "> %s:\n> %s\n\n", quoted, strings.ReplaceAll(quote.Text, "\n", "\n> "))
+	}
+	fmt.Fprintf(&b, "[View on Bluesky](%s)\n", p.BlueskyURL())
+	return b.String()
+}
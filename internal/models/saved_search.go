@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// SavedSearch is a search query a user keeps to be alerted about new matches
+// Searches run across every account the user can browse: linked identities and the watchlist
+type SavedSearch struct {
+	ID            int64      `json:"id" db:"id"`
+	AccountID     string     `json:"-" db:"account_id"` // Local account that owns the search
+	Name          string     `json:"name,omitempty" db:"name"`
+	Query         string     `json:"query" db:"query"`
+	CheckedRowID  int64      `json:"-" db:"checked_rowid"` // Last post rowid checked for new matches
+	SeenRowID     int64      `json:"-" db:"seen_rowid"`    // Last post rowid when the user viewed the matches
+	NewMatches    int        `json:"new_matches" db:"new_matches"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty" db:"last_checked_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// DisplayName returns the search name, or its query if it has none
+func (s *SavedSearch) DisplayName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Query
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// ScheduleRunStatus represents the outcome of a scheduled sync attempt
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunStatusStarted ScheduleRunStatus = "started" // Archive operation was started
+	ScheduleRunStatusSkipped ScheduleRunStatus = "skipped" // Another operation was already active
+	ScheduleRunStatusFailed  ScheduleRunStatus = "failed"  // Operation could not be started
+)
+
+// ScheduleRun records a single scheduled sync attempt for an account
+type ScheduleRun struct {
+	ID           string            `json:"id" db:"id"`
+	DID          string            `json:"did" db:"did"`
+	ScheduledFor time.Time         `json:"scheduled_for" db:"scheduled_for"` // When the run was due
+	StartedAt    time.Time         `json:"started_at" db:"started_at"`       // When the scheduler acted on it
+	Status       ScheduleRunStatus `json:"status" db:"status"`
+	OperationID  string            `json:"operation_id,omitempty" db:"operation_id"`
+	Message      string            `json:"message,omitempty" db:"message"`
+}
+
+// ScheduleStatus summarizes scheduled sync state for an account (shown on the dashboard)
+type ScheduleStatus struct {
+	DID        string        `json:"did"`
+	NextRunAt  *time.Time    `json:"next_run_at,omitempty"`
+	LastRun    *ScheduleRun  `json:"last_run,omitempty"`
+	RecentRuns []ScheduleRun `json:"recent_runs,omitempty"`
+}
+
+// IsScheduled checks if a future run is planned
+func (s *ScheduleStatus) IsScheduled() bool {
+	return s != nil && s.NextRunAt != nil
+}
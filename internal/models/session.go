@@ -9,6 +9,7 @@ import (
 // Session represents an authenticated user's session with OAuth tokens and identity information
 type Session struct {
 	ID           string    `json:"id"`
+	AccountID    string    `json:"account_id"`    // Local account that groups linked identities
 	DID          string    `json:"did"`           // Decentralized Identifier
 	Handle       string    `json:"handle"`        // Bluesky handle (e.g., "user.bsky.social")
 	DisplayName  string    `json:"display_name"`  // Optional display name
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// WatchRunStatus represents the outcome of the last watchlist run for an account
+type WatchRunStatus string
+
+const (
+	WatchRunStatusCompleted WatchRunStatus = "completed"
+	WatchRunStatusFailed    WatchRunStatus = "failed"
+)
+
+// WatchedAccount is a public account archived through the AppView without logging in as it
+type WatchedAccount struct {
+	Actor         string         `json:"actor" db:"actor"`   // Handle or DID as written in the config
+	DID           string         `json:"did" db:"did"`       // Resolved after the first run
+	Handle        string         `json:"handle" db:"handle"` // Latest known handle
+	NextRunAt     *time.Time     `json:"next_run_at,omitempty" db:"next_run_at"`
+	LastRunAt     *time.Time     `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastStatus    WatchRunStatus `json:"last_status,omitempty" db:"last_status"`
+	LastError     string         `json:"last_error,omitempty" db:"last_error"`
+	LastPostCount int            `json:"last_post_count" db:"last_post_count"` // Posts saved by the last run
+	PostCount     int            `json:"post_count"`                           // Posts archived for this DID
+}
+
+// DisplayName returns the best available label for the account
+func (a *WatchedAccount) DisplayName() string {
+	if a.Handle != "" {
+		return a.Handle
+	}
+	return a.Actor
+}
+
+// HasRun checks if the account has been archived at least once
+func (a *WatchedAccount) HasRun() bool {
+	return a.LastRunAt != nil
+}
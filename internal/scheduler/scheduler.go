@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shindakun/bskyarchive/internal/config"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// Archiver starts archive operations
+// Implemented by archiver.Worker
+type Archiver interface {
+	StartArchive(ctx context.Context, did, bskyoauthSessionID string, operationType models.OperationType) (string, error)
+}
+
+// Scheduler runs incremental archive operations for signed-in accounts on an
+// interval or cron-like schedule, recording each attempt in schedule_runs
+type Scheduler struct {
+	db       *sql.DB
+	archiver Archiver
+	cfg      config.ScheduleConfig
+	timing   *timing
+	logger   *log.Logger
+	now      func() time.Time
+	loop     loop
+}
+
+// New creates a scheduler from the schedule configuration
+func New(db *sql.DB, archiver Archiver, cfg config.ScheduleConfig, logger *log.Logger) (*Scheduler, error) {
+	s := &Scheduler{
+		db:       db,
+		archiver: archiver,
+		cfg:      cfg,
+		logger:   logger,
+		now:      time.Now,
+	}
+
+	timing, err := newTiming(cfg.Interval, cfg.Cron, cfg.Jitter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+	s.timing = timing
+
+	return s, nil
+}
+
+// Start launches the scheduler loop in the background
+func (s *Scheduler) Start() {
+	s.loop.start(s.tick, "scheduled sync", s.logger)
+}
+
+// Stop halts the scheduler loop and waits for it to exit
+// Archive operations that were already started keep running
+func (s *Scheduler) Stop() {
+	s.loop.stop()
+}
+
+// tick starts a run for every account whose next run is due
+// Accounts seen for the first time are given a next run without running immediately
+func (s *Scheduler) tick(ctx context.Context) error {
+	sessions, err := storage.ListActiveSessions(s.db)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	for _, session := range sessions {
+		if !s.includes(session.DID) {
+			continue
+		}
+
+		next, err := storage.GetNextScheduledRun(s.db, session.DID)
+		if err != nil {
+			s.logger.Printf("Warning: %v", err)
+			continue
+		}
+
+		if next != nil && now.Before(*next) {
+			continue
+		}
+
+		if next != nil {
+			s.run(ctx, session, *next, now)
+		}
+
+		nextRun := s.nextRun(now)
+		if nextRun.IsZero() {
+			s.logger.Printf("Warning: schedule.cron %q never matches; scheduled sync disabled for %s", s.cfg.Cron, session.DID)
+			continue
+		}
+		if err := storage.SetNextScheduledRun(s.db, session.DID, nextRun); err != nil {
+			s.logger.Printf("Warning: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// run starts an incremental archive for an account and records the outcome
+func (s *Scheduler) run(ctx context.Context, session models.Session, scheduledFor, now time.Time) {
+	run := &models.ScheduleRun{
+		ID:           uuid.New().String(),
+		DID:          session.DID,
+		ScheduledFor: scheduledFor,
+		StartedAt:    now,
+	}
+
+	activeOp, err := storage.GetActiveOperation(s.db, session.DID)
+	switch {
+	case err != nil:
+		run.Status = models.ScheduleRunStatusFailed
+		run.Message = err.Error()
+	case activeOp != nil:
+		run.Status = models.ScheduleRunStatusSkipped
+		run.Message = fmt.Sprintf("operation %s already in progress", activeOp.ID)
+	default:
+		operationID, err := s.archiver.StartArchive(ctx, session.DID, session.AccessToken, models.OperationTypeIncremental)
+		if err != nil {
+			run.Status = models.ScheduleRunStatusFailed
+			run.Message = err.Error()
+		} else {
+			run.Status = models.ScheduleRunStatusStarted
+			run.OperationID = operationID
+		}
+	}
+
+	s.logger.Printf("Scheduled sync for %s: %s %s", session.DID, run.Status, run.Message)
+
+	if err := storage.CreateScheduleRun(s.db, run); err != nil {
+		s.logger.Printf("Warning: %v", err)
+	}
+}
+
+// nextRun computes the next run after t, including jitter
+func (s *Scheduler) nextRun(t time.Time) time.Time {
+	return s.timing.next(t)
+}
+
+// includes reports whether an account is covered by the schedule
+func (s *Scheduler) includes(did string) bool {
+	if len(s.cfg.Accounts) == 0 {
+		return true
+	}
+	for _, account := range s.cfg.Accounts {
+		if account == did {
+			return true
+		}
+	}
+	return false
+}
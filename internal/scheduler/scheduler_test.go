@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/config"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// fakeArchiver records StartArchive calls
+type fakeArchiver struct {
+	calls []string
+}
+
+func (f *fakeArchiver) StartArchive(ctx context.Context, did, bskyoauthSessionID string, operationType models.OperationType) (string, error) {
+	f.calls = append(f.calls, did+"|"+bskyoauthSessionID+"|"+string(operationType))
+	return "op-" + did, nil
+}
+
+func setupSchedulerTest(t *testing.T, cfg config.ScheduleConfig) (*sql.DB, *fakeArchiver, *Scheduler) {
+	t.Helper()
+
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`INSERT INTO sessions (id, did, handle, access_token, refresh_token, expires_at)
+		VALUES ('s1', 'did:plc:alice', 'alice', 'oauth-alice', '', ?), ('s2', 'did:plc:bob', 'bob', 'oauth-bob', '', ?)`,
+		time.Now().Add(time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to insert sessions: %v", err)
+	}
+
+	archiver := &fakeArchiver{}
+	s, err := New(db, archiver, cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+
+	return db, archiver, s
+}
+
+func TestScheduler_RunsWhenDue(t *testing.T) {
+	db, archiver, s := setupSchedulerTest(t, config.ScheduleConfig{
+		Enabled:  true,
+		Interval: time.Hour,
+		Accounts: []string{"did:plc:alice"},
+	})
+
+	now := time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	// First tick only plans the next run
+	if err := s.tick(context.Background()); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+	if len(archiver.calls) != 0 {
+		t.Fatalf("Expected no runs on first tick, got %v", archiver.calls)
+	}
+
+	next, err := storage.GetNextScheduledRun(db, "did:plc:alice")
+	if err != nil || next == nil || !next.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Expected next run at %v, got %v (err %v)", now.Add(time.Hour), next, err)
+	}
+	if next, _ := storage.GetNextScheduledRun(db, "did:plc:bob"); next != nil {
+		t.Errorf("Expected bob to be excluded from the schedule, got next run %v", next)
+	}
+
+	// Once due, an incremental archive is started with the stored bskyoauth session ID
+	now = now.Add(time.Hour)
+	if err := s.tick(context.Background()); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+	if len(archiver.calls) != 1 || archiver.calls[0] != "did:plc:alice|oauth-alice|incremental" {
+		t.Fatalf("Unexpected archive calls: %v", archiver.calls)
+	}
+
+	status, err := storage.GetScheduleStatus(db, "did:plc:alice")
+	if err != nil {
+		t.Fatalf("GetScheduleStatus failed: %v", err)
+	}
+	if status.LastRun == nil || status.LastRun.Status != models.ScheduleRunStatusStarted || status.LastRun.OperationID != "op-did:plc:alice" {
+		t.Errorf("Unexpected last run: %+v", status.LastRun)
+	}
+	if status.NextRunAt == nil || !status.NextRunAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected next run at %v, got %v", now.Add(time.Hour), status.NextRunAt)
+	}
+}
+
+func TestScheduler_SkipsWhenOperationActive(t *testing.T) {
+	db, archiver, s := setupSchedulerTest(t, config.ScheduleConfig{
+		Enabled:  true,
+		Interval: time.Hour,
+		Accounts: []string{"did:plc:alice"},
+	})
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	if err := storage.SetNextScheduledRun(db, "did:plc:alice", now.Add(-time.Minute)); err != nil {
+		t.Fatalf("SetNextScheduledRun failed: %v", err)
+	}
+	op := &models.ArchiveOperation{
+		ID:        "op-running",
+		DID:       "did:plc:alice",
+		Type:      models.OperationTypeInitial,
+		Status:    models.OperationStatusRunning,
+		StartedAt: now,
+	}
+	if err := storage.CreateOperation(db, op); err != nil {
+		t.Fatalf("CreateOperation failed: %v", err)
+	}
+
+	if err := s.tick(context.Background()); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+	if len(archiver.calls) != 0 {
+		t.Errorf("Expected run to be skipped, got %v", archiver.calls)
+	}
+
+	runs, err := storage.ListScheduleRuns(db, "did:plc:alice", 10)
+	if err != nil {
+		t.Fatalf("ListScheduleRuns failed: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != models.ScheduleRunStatusSkipped {
+		t.Errorf("Expected one skipped run, got %+v", runs)
+	}
+}
+
+func TestScheduler_NextRunJitter(t *testing.T) {
+	_, _, s := setupSchedulerTest(t, config.ScheduleConfig{
+		Enabled:  true,
+		Interval: time.Hour,
+		Jitter:   10 * time.Minute,
+	})
+
+	base := time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		next := s.nextRun(base)
+		if next.Before(base.Add(time.Hour)) || !next.Before(base.Add(time.Hour+10*time.Minute)) {
+			t.Fatalf("nextRun %v outside jitter window", next)
+		}
+	}
+}
+
+func TestNew_InvalidCron(t *testing.T) {
+	_, err := New(nil, &fakeArchiver{}, config.ScheduleConfig{Enabled: true, Cron: "bad"}, log.New(io.Discard, "", 0))
+	if err == nil {
+		t.Error("Expected error for invalid cron expression")
+	}
+}
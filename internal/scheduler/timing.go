@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// timing computes run times from an interval or a cron expression, plus optional jitter
+type timing struct {
+	interval time.Duration
+	cron     *CronSchedule
+	jitter   time.Duration
+}
+
+// newTiming builds a timing; cronExpr takes precedence over interval when both are set
+func newTiming(interval time.Duration, cronExpr string, jitter time.Duration) (*timing, error) {
+	t := &timing{interval: interval, jitter: jitter}
+
+	if cronExpr != "" {
+		cron, err := ParseCron(cronExpr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron: %w", err)
+		}
+		t.cron = cron
+	} else if interval <= 0 {
+		return nil, fmt.Errorf("an interval or cron expression is required")
+	}
+
+	return t, nil
+}
+
+// next returns the next run after t, including jitter
+// Returns the zero time if the cron expression never matches
+func (t *timing) next(after time.Time) time.Time {
+	var next time.Time
+	if t.cron != nil {
+		next = t.cron.Next(after)
+		if next.IsZero() {
+			return next
+		}
+	} else {
+		next = after.Add(t.interval)
+	}
+
+	if t.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(t.jitter))))
+	}
+
+	return next
+}
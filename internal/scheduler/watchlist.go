@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/archiver"
+	"github.com/shindakun/bskyarchive/internal/config"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// PublicArchiver archives public accounts without a login
+// Implemented by archiver.Worker
+type PublicArchiver interface {
+	ArchivePublicAccount(ctx context.Context, client *archiver.ATProtoClient, actor, filter string, incremental bool) (*archiver.PublicArchiveResult, error)
+}
+
+// watchEntry is a watchlist account with its resolved schedule
+type watchEntry struct {
+	account config.WatchAccount
+	timing  *timing
+}
+
+// Watchlist archives the configured public accounts, each on its own schedule
+// Run state lives in the watched_accounts table so the dashboard and Browse can show it
+type Watchlist struct {
+	db       *sql.DB
+	archiver PublicArchiver
+	client   *archiver.ATProtoClient
+	entries  []watchEntry
+	logger   *log.Logger
+	now      func() time.Time
+	loop     loop
+}
+
+// NewWatchlist creates a watchlist runner from the watchlist configuration
+// Entries without their own interval or cron use watchlist.interval
+func NewWatchlist(db *sql.DB, publicArchiver PublicArchiver, cfg config.WatchlistConfig, logger *log.Logger) (*Watchlist, error) {
+	w := &Watchlist{
+		db:       db,
+		archiver: publicArchiver,
+		client:   archiver.NewPublicClient(cfg.AppView),
+		logger:   logger,
+		now:      time.Now,
+	}
+
+	for _, account := range cfg.Accounts {
+		interval := account.Interval
+		if interval == 0 && account.Cron == "" {
+			interval = cfg.Interval
+		}
+
+		timing, err := newTiming(interval, account.Cron, cfg.Jitter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watchlist entry %s: %w", account.Actor, err)
+		}
+
+		w.entries = append(w.entries, watchEntry{account: account, timing: timing})
+	}
+
+	return w, nil
+}
+
+// Start launches the watchlist loop in the background
+func (w *Watchlist) Start() {
+	w.loop.start(w.tick, "watchlist", w.logger)
+}
+
+// Stop halts the watchlist loop, cancelling any run in progress
+func (w *Watchlist) Stop() {
+	w.loop.stop()
+}
+
+// tick archives every entry whose next run is due
+// Entries that have never been archived run immediately
+func (w *Watchlist) tick(ctx context.Context) error {
+	for _, entry := range w.entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		account, err := storage.GetWatchedAccount(w.db, entry.account.Actor)
+		if err != nil {
+			w.logger.Printf("Warning: %v", err)
+			continue
+		}
+
+		if account.NextRunAt != nil && w.now().Before(*account.NextRunAt) {
+			continue
+		}
+
+		if account.NextRunAt != nil || !account.HasRun() {
+			w.run(ctx, entry, account)
+		}
+
+		next := entry.timing.next(w.now())
+		if next.IsZero() {
+			w.logger.Printf("Warning: cron %q never matches; watchlist disabled for %s", entry.account.Cron, entry.account.Actor)
+			continue
+		}
+		if err := storage.SetWatchedAccountNextRun(w.db, entry.account.Actor, next); err != nil {
+			w.logger.Printf("Warning: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// run archives one watched account and records the outcome
+// Runs are incremental once a full run has completed
+func (w *Watchlist) run(ctx context.Context, entry watchEntry, account *models.WatchedAccount) {
+	incremental := account.LastStatus == models.WatchRunStatusCompleted
+
+	result, err := w.archiver.ArchivePublicAccount(ctx, w.client, entry.account.Actor, entry.account.Filter, incremental)
+
+	now := w.now()
+	account.LastRunAt = &now
+	account.LastError = ""
+	account.LastPostCount = 0
+	if result != nil {
+		account.DID = result.DID
+		account.Handle = result.Handle
+		account.LastPostCount = result.NewPosts
+	}
+
+	if err != nil {
+		account.LastStatus = models.WatchRunStatusFailed
+		account.LastError = err.Error()
+		w.logger.Printf("Watchlist run for %s failed: %v", entry.account.Actor, err)
+	} else {
+		account.LastStatus = models.WatchRunStatusCompleted
+		w.logger.Printf("Watchlist run for %s completed: %d new posts", entry.account.Actor, account.LastPostCount)
+	}
+
+	if err := storage.RecordWatchedAccountRun(w.db, account); err != nil {
+		w.logger.Printf("Warning: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/archiver"
+	"github.com/shindakun/bskyarchive/internal/config"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// fakePublicArchiver records ArchivePublicAccount calls
+type fakePublicArchiver struct {
+	calls []string
+	err   error
+}
+
+func (f *fakePublicArchiver) ArchivePublicAccount(ctx context.Context, client *archiver.ATProtoClient, actor, filter string, incremental bool) (*archiver.PublicArchiveResult, error) {
+	f.calls = append(f.calls, fmt.Sprintf("%s|%s|%t", actor, filter, incremental))
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &archiver.PublicArchiveResult{DID: "did:plc:" + actor, Handle: actor + ".test", NewPosts: 2}, nil
+}
+
+func TestWatchlist_PerEntrySchedule(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	if err := storage.SyncWatchedAccounts(db, []string{"hourly", "daily"}); err != nil {
+		t.Fatalf("SyncWatchedAccounts failed: %v", err)
+	}
+
+	fake := &fakePublicArchiver{}
+	w, err := NewWatchlist(db, fake, config.WatchlistConfig{
+		Interval: 24 * time.Hour,
+		Accounts: []config.WatchAccount{
+			{Actor: "hourly", Interval: time.Hour, Filter: "posts_no_replies"},
+			{Actor: "daily"},
+		},
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewWatchlist failed: %v", err)
+	}
+
+	now := time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+
+	// Entries that were never archived run immediately with a full fetch
+	if err := w.tick(context.Background()); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+	if len(fake.calls) != 2 || fake.calls[0] != "hourly|posts_no_replies|false" || fake.calls[1] != "daily||false" {
+		t.Fatalf("Unexpected calls: %v", fake.calls)
+	}
+
+	account, err := storage.GetWatchedAccount(db, "hourly")
+	if err != nil {
+		t.Fatalf("GetWatchedAccount failed: %v", err)
+	}
+	if account.DID != "did:plc:hourly" || account.LastStatus != models.WatchRunStatusCompleted || account.LastPostCount != 2 {
+		t.Errorf("Unexpected account state: %+v", account)
+	}
+	if account.NextRunAt == nil || !account.NextRunAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected next run at %v, got %v", now.Add(time.Hour), account.NextRunAt)
+	}
+
+	// An hour later only the hourly entry is due, and it runs incrementally
+	fake.calls = nil
+	now = now.Add(time.Hour)
+	if err := w.tick(context.Background()); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+	if len(fake.calls) != 1 || fake.calls[0] != "hourly|posts_no_replies|true" {
+		t.Fatalf("Unexpected calls: %v", fake.calls)
+	}
+
+	watched, err := storage.IsWatchedDID(db, "did:plc:daily")
+	if err != nil || !watched {
+		t.Errorf("Expected did:plc:daily to be watched (err %v)", err)
+	}
+}
+
+func TestWatchlist_RecordsFailure(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	if err := storage.SyncWatchedAccounts(db, []string{"gone.test"}); err != nil {
+		t.Fatalf("SyncWatchedAccounts failed: %v", err)
+	}
+
+	fake := &fakePublicArchiver{err: fmt.Errorf("profile not found")}
+	w, err := NewWatchlist(db, fake, config.WatchlistConfig{
+		Accounts: []config.WatchAccount{{Actor: "gone.test", Cron: "@hourly"}},
+	}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewWatchlist failed: %v", err)
+	}
+
+	if err := w.tick(context.Background()); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+
+	account, err := storage.GetWatchedAccount(db, "gone.test")
+	if err != nil {
+		t.Fatalf("GetWatchedAccount failed: %v", err)
+	}
+	if account.LastStatus != models.WatchRunStatusFailed || account.LastError != "profile not found" {
+		t.Errorf("Expected failed run to be recorded, got %+v", account)
+	}
+	if account.NextRunAt == nil {
+		t.Error("Expected a retry to be scheduled")
+	}
+}
+
+func TestNewWatchlist_MissingSchedule(t *testing.T) {
+	_, err := NewWatchlist(nil, &fakePublicArchiver{}, config.WatchlistConfig{
+		Accounts: []config.WatchAccount{{Actor: "bsky.app"}},
+	}, log.New(io.Discard, "", 0))
+	if err == nil {
+		t.Error("Expected error for entry without interval or cron")
+	}
+}
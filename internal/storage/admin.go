@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// GetInstanceStats aggregates counts across every account for the admin page
+func GetInstanceStats(db *sql.DB) (*models.InstanceStats, error) {
+	var stats models.InstanceStats
+
+	query := `
+		SELECT
+			(SELECT COUNT(DISTINCT COALESCE(account_id, id)) FROM sessions),
+			(SELECT COUNT(*) FROM sessions),
+			(SELECT COUNT(*) FROM posts),
+			(SELECT COUNT(*) FROM media),
+			(SELECT COALESCE(SUM(size_bytes), 0) FROM media),
+			(SELECT COUNT(*) FROM exports),
+			(SELECT COUNT(*) FROM watched_accounts),
+			(SELECT COUNT(*) FROM operations WHERE status IN ('pending', 'running'))
+	`
+
+	err := db.QueryRow(query).Scan(
+		&stats.Accounts,
+		&stats.Identities,
+		&stats.Posts,
+		&stats.Media,
+		&stats.MediaBytes,
+		&stats.Exports,
+		&stats.WatchedAccounts,
+		&stats.ActiveOperations,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// ListUsers retrieves every signed-in identity with its post count and last archive time,
+// grouped by local account
+func ListUsers(db *sql.DB) ([]models.UserSummary, error) {
+	query := `
+		SELECT ` + sessionColumns + `,
+			(SELECT COUNT(*) FROM posts p WHERE p.did = sessions.did),
+			(SELECT MAX(started_at) FROM operations o WHERE o.did = sessions.did)
+		FROM sessions
+		ORDER BY COALESCE(account_id, id), created_at ASC
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.UserSummary
+	for rows.Next() {
+		var user models.UserSummary
+		var lastArchive sql.NullString
+		err := rows.Scan(
+			&user.ID,
+			&user.AccountID,
+			&user.DID,
+			&user.Handle,
+			&user.DisplayName,
+			&user.AccessToken,
+			&user.RefreshToken,
+			&user.ExpiresAt,
+			&user.CreatedAt,
+			&user.TotalPosts,
+			&lastArchive,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		if lastArchive.Valid && lastArchive.String != "" {
+			if t, err := parseTimestamp(lastArchive.String); err == nil {
+				user.LastArchiveAt = &t
+			}
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// RevokeUser signs out one identity and deletes its stored OAuth or app-password credential
+// Archived posts are kept; the identity can sign in again if the allowlist permits it
+func RevokeUser(db *sql.DB, did string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sessionID, accountID string
+	err = tx.QueryRow("SELECT access_token, COALESCE(account_id, id) FROM sessions WHERE did = ?", did).Scan(&sessionID, &accountID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found: %s", did)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user session: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM oauth_sessions WHERE id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete oauth session: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM app_password_sessions WHERE id = ?", sessionID); err != nil {
+		return fmt.Errorf("failed to delete app password session: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE did = ?", did); err != nil {
+		return fmt.Errorf("failed to delete user session: %w", err)
+	}
+	// The account's feed tokens for the identity's archive go with it, and all of
+	// its tokens once no identity is left in the account
+	if _, err := tx.Exec(`
+		DELETE FROM feed_tokens
+		WHERE account_id = ? AND (did = ? OR NOT EXISTS (SELECT 1 FROM sessions WHERE COALESCE(account_id, id) = ?))
+	`, accountID, did, accountID); err != nil {
+		return fmt.Errorf("failed to delete feed tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+func TestAdminStatsAndRevoke(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	expires := time.Now().Add(time.Hour)
+	for _, s := range []struct{ id, account, did, token string }{
+		{"s1", "acct-1", "did:plc:alice", "oauth-alice"},
+		{"s2", "acct-1", "did:plc:alt", "app-password:alt"},
+		{"s3", "acct-2", "did:plc:bob", "oauth-bob"},
+	} {
+		if _, err := db.Exec(`INSERT INTO sessions (id, account_id, did, handle, access_token, refresh_token, expires_at) VALUES (?, ?, ?, ?, ?, '', ?)`,
+			s.id, s.account, s.did, s.did, s.token, expires); err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+	}
+	if err := SaveOAuthSession(db, "oauth-bob", "did:plc:bob", []byte("secret")); err != nil {
+		t.Fatalf("SaveOAuthSession failed: %v", err)
+	}
+
+	now := time.Now()
+	for _, post := range []models.Post{
+		{URI: "at://did:plc:alice/app.bsky.feed.post/1", CID: "c1", DID: "did:plc:alice", Text: "alice", CreatedAt: now, IndexedAt: now},
+		{URI: "at://did:plc:bob/app.bsky.feed.post/1", CID: "c2", DID: "did:plc:bob", Text: "bob", CreatedAt: now, IndexedAt: now},
+	} {
+		post := post
+		if err := SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	hash := strings.Repeat("b", 64)
+	if err := SaveMedia(db, &models.Media{
+		Hash:      hash,
+		PostURI:   "at://did:plc:bob/app.bsky.feed.post/1",
+		MimeType:  "image/jpeg",
+		FilePath:  "media/" + hash + ".jpg",
+		SizeBytes: 2048,
+	}); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	stats, err := GetInstanceStats(db)
+	if err != nil {
+		t.Fatalf("GetInstanceStats failed: %v", err)
+	}
+	if stats.Accounts != 2 || stats.Identities != 3 || stats.Posts != 2 || stats.Media != 1 || stats.MediaBytes != 2048 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	users, err := ListUsers(db)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 3 || users[0].TotalPosts != 1 || users[0].AccountID != "acct-1" {
+		t.Errorf("Unexpected users: %+v", users)
+	}
+
+	// Media is only visible to the DIDs that own the post
+	if visible, err := MediaVisibleTo(db, hash, []string{"did:plc:alice", "did:plc:alt"}); err != nil || visible {
+		t.Errorf("Expected bob's media to be hidden from alice (visible %v, err %v)", visible, err)
+	}
+	if visible, err := MediaVisibleTo(db, hash, []string{"did:plc:bob"}); err != nil || !visible {
+		t.Errorf("Expected bob's media to be visible to bob (visible %v, err %v)", visible, err)
+	}
+
+	// Revoking removes the session and its stored credential but keeps posts
+	if err := RevokeUser(db, "did:plc:bob"); err != nil {
+		t.Fatalf("RevokeUser failed: %v", err)
+	}
+	if _, err := GetOAuthSession(db, "oauth-bob"); err == nil {
+		t.Error("Expected oauth session to be deleted")
+	}
+	if accountID, _ := GetSessionAccountID(db, "did:plc:bob"); accountID != "" {
+		t.Error("Expected bob's session to be deleted")
+	}
+	if _, err := GetPost(db, "at://did:plc:bob/app.bsky.feed.post/1"); err != nil {
+		t.Errorf("Expected bob's posts to be kept: %v", err)
+	}
+	if err := RevokeUser(db, "did:plc:bob"); err == nil {
+		t.Error("Expected error revoking an unknown user")
+	}
+}
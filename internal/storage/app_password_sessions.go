@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SaveAppPasswordSession inserts or updates an encrypted app-password session
+// data is the already-encrypted credential and token payload
+func SaveAppPasswordSession(db *sql.DB, id, did string, data []byte) error {
+	if id == "" {
+		return fmt.Errorf("app password session id is required")
+	}
+	if did == "" {
+		return fmt.Errorf("did is required")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO app_password_sessions (id, did, data, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			did = excluded.did,
+			data = excluded.data,
+			updated_at = excluded.updated_at
+	`, id, did, data, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save app password session: %w", err)
+	}
+
+	return nil
+}
+
+// GetAppPasswordSession retrieves the encrypted payload of an app-password session by ID
+func GetAppPasswordSession(db *sql.DB, id string) ([]byte, error) {
+	var data []byte
+	err := db.QueryRow("SELECT data FROM app_password_sessions WHERE id = ?", id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("app password session not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app password session: %w", err)
+	}
+
+	return data, nil
+}
+
+// DeleteAppPasswordSession removes an app-password session by ID
+func DeleteAppPasswordSession(db *sql.DB, id string) error {
+	if _, err := db.Exec("DELETE FROM app_password_sessions WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete app password session: %w", err)
+	}
+
+	return nil
+}
@@ -112,27 +112,7 @@ func runMigrations(db *sql.DB) error {
 			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
 
-		// Create FTS5 virtual table for full-text search
-		`CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
-			uri UNINDEXED,
-			text,
-			content='posts',
-			content_rowid='rowid'
-		)`,
-
-		// Create triggers to keep FTS index in sync
-		`CREATE TRIGGER IF NOT EXISTS posts_ai AFTER INSERT ON posts BEGIN
-			INSERT INTO posts_fts(rowid, uri, text)
-			VALUES (new.rowid, new.uri, new.text);
-		END`,
-
-		`CREATE TRIGGER IF NOT EXISTS posts_ad AFTER DELETE ON posts BEGIN
-			DELETE FROM posts_fts WHERE rowid = old.rowid;
-		END`,
-
-		`CREATE TRIGGER IF NOT EXISTS posts_au AFTER UPDATE ON posts BEGIN
-			UPDATE posts_fts SET text = new.text WHERE rowid = old.rowid;
-		END`,
+		// The posts_fts search index and its triggers are created by migration 10 (see fts.go)
 
 		// Create profiles table
 		`CREATE TABLE IF NOT EXISTS profiles (
@@ -180,8 +160,10 @@ func runMigrations(db *sql.DB) error {
 		// Create indices for common queries
 		`CREATE INDEX IF NOT EXISTS idx_posts_did ON posts(did)`,
 		`CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_did_created_at ON posts(did, created_at DESC, uri DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_posts_has_media ON posts(has_media) WHERE has_media = 1`,
 		`CREATE INDEX IF NOT EXISTS idx_posts_is_reply ON posts(is_reply) WHERE is_reply = 1`,
+		`CREATE INDEX IF NOT EXISTS idx_posts_reply_parent ON posts(reply_parent)`,
 		`CREATE INDEX IF NOT EXISTS idx_profiles_did ON profiles(did)`,
 		`CREATE INDEX IF NOT EXISTS idx_profiles_snapshot_at ON profiles(snapshot_at DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_media_post_uri ON media(post_uri)`,
@@ -215,12 +197,6 @@ func runMigrations(db *sql.DB) error {
 		return fmt.Errorf("failed to run incremental migrations: %w", err)
 	}
 
-	// Invalidate all existing sessions on startup
-	// This ensures clean state after app restarts and prevents issues with stale sessions
-	if err := invalidateAllSessions(db); err != nil {
-		return fmt.Errorf("failed to invalidate sessions: %w", err)
-	}
-
 	return nil
 }
 
@@ -334,25 +310,479 @@ func runIncrementalMigrations(db *sql.DB) error {
 		}
 	}
 
+	// Migration 4: Add oauth_sessions table for persisted bskyoauth sessions
+	if currentVersion < 4 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 4: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Encrypted bskyoauth session (DPoP key, tokens, nonce), keyed by the
+		// bskyoauth session ID stored in sessions.access_token
+		if _, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS oauth_sessions (
+				id TEXT PRIMARY KEY,
+				did TEXT NOT NULL,
+				data BLOB NOT NULL,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)
+		`); err != nil {
+			return fmt.Errorf("failed to create oauth_sessions table: %w", err)
+		}
+
+		if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_oauth_sessions_did ON oauth_sessions(did)"); err != nil {
+			return fmt.Errorf("failed to create idx_oauth_sessions_did: %w", err)
+		}
+
+		// Remove the bskyoauth session when its sessions row is deleted (logout, expiry, revoke)
+		if _, err := tx.Exec(`
+			CREATE TRIGGER IF NOT EXISTS sessions_ad_oauth AFTER DELETE ON sessions BEGIN
+				DELETE FROM oauth_sessions WHERE id = old.access_token;
+			END
+		`); err != nil {
+			return fmt.Errorf("failed to create sessions_ad_oauth trigger: %w", err)
+		}
+
+		// Remove the previous bskyoauth session when a user logs in again
+		if _, err := tx.Exec(`
+			CREATE TRIGGER IF NOT EXISTS sessions_au_oauth AFTER UPDATE OF access_token ON sessions
+			WHEN old.access_token != new.access_token BEGIN
+				DELETE FROM oauth_sessions WHERE id = old.access_token;
+			END
+		`); err != nil {
+			return fmt.Errorf("failed to create sessions_au_oauth trigger: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (4)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 4: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 4: %w", err)
+		}
+	}
+
+	if currentVersion < 5 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 5: %w", err)
+		}
+		defer tx.Rollback()
+
+		// History of scheduled sync attempts
+		if _, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS schedule_runs (
+				id TEXT PRIMARY KEY,
+				did TEXT NOT NULL,
+				scheduled_for TIMESTAMP NOT NULL,
+				started_at TIMESTAMP NOT NULL,
+				status TEXT NOT NULL CHECK(status IN ('started', 'skipped', 'failed')),
+				operation_id TEXT,
+				message TEXT
+			)
+		`); err != nil {
+			return fmt.Errorf("failed to create schedule_runs table: %w", err)
+		}
+
+		if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_schedule_runs_did ON schedule_runs(did, started_at DESC)"); err != nil {
+			return fmt.Errorf("failed to create idx_schedule_runs_did: %w", err)
+		}
+
+		// Next planned run per account, written by the scheduler
+		if _, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS schedule_state (
+				did TEXT PRIMARY KEY,
+				next_run_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)
+		`); err != nil {
+			return fmt.Errorf("failed to create schedule_state table: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (5)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 5: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 5: %w", err)
+		}
+	}
+
+	if currentVersion < 6 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 6: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Encrypted app-password credential and tokens, keyed by the session ID
+		// stored in sessions.access_token (same lifecycle as oauth_sessions)
+		if _, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS app_password_sessions (
+				id TEXT PRIMARY KEY,
+				did TEXT NOT NULL,
+				data BLOB NOT NULL,
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)
+		`); err != nil {
+			return fmt.Errorf("failed to create app_password_sessions table: %w", err)
+		}
+
+		if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_app_password_sessions_did ON app_password_sessions(did)"); err != nil {
+			return fmt.Errorf("failed to create idx_app_password_sessions_did: %w", err)
+		}
+
+		if _, err := tx.Exec(`
+			CREATE TRIGGER IF NOT EXISTS sessions_ad_app_password AFTER DELETE ON sessions BEGIN
+				DELETE FROM app_password_sessions WHERE id = old.access_token;
+			END
+		`); err != nil {
+			return fmt.Errorf("failed to create sessions_ad_app_password trigger: %w", err)
+		}
+
+		if _, err := tx.Exec(`
+			CREATE TRIGGER IF NOT EXISTS sessions_au_app_password AFTER UPDATE OF access_token ON sessions
+			WHEN old.access_token != new.access_token BEGIN
+				DELETE FROM app_password_sessions WHERE id = old.access_token;
+			END
+		`); err != nil {
+			return fmt.Errorf("failed to create sessions_au_app_password trigger: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (6)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 6: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 6: %w", err)
+		}
+	}
+
+	if currentVersion < 7 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 7: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Public accounts archived through the AppView without a login
+		// Rows mirror the watchlist config; did and handle are filled in after the first run
+		if _, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS watched_accounts (
+				actor TEXT PRIMARY KEY,
+				did TEXT,
+				handle TEXT,
+				next_run_at TIMESTAMP,
+				last_run_at TIMESTAMP,
+				last_status TEXT CHECK(last_status IN ('completed', 'failed')),
+				last_error TEXT,
+				last_post_count INTEGER DEFAULT 0,
+				added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)
+		`); err != nil {
+			return fmt.Errorf("failed to create watched_accounts table: %w", err)
+		}
+
+		if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_watched_accounts_did ON watched_accounts(did)"); err != nil {
+			return fmt.Errorf("failed to create idx_watched_accounts_did: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (7)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 7: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 7: %w", err)
+		}
+	}
+
+	if currentVersion < 8 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 8: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Group login sessions into local accounts so one user can link several identities
+		// Existing sessions each become their own account
+		if _, err := tx.Exec("ALTER TABLE sessions ADD COLUMN account_id TEXT"); err != nil {
+			return fmt.Errorf("failed to add account_id to sessions: %w", err)
+		}
+
+		if _, err := tx.Exec("UPDATE sessions SET account_id = id WHERE account_id IS NULL"); err != nil {
+			return fmt.Errorf("failed to backfill session account_id: %w", err)
+		}
+
+		if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_sessions_account_id ON sessions(account_id)"); err != nil {
+			return fmt.Errorf("failed to create idx_sessions_account_id: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (8)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 8: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 8: %w", err)
+		}
+	}
+
+	if currentVersion < 9 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 9: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Post languages and rich text facets back the lang: and has:link search filters
+		// Existing posts get them on their next refresh
+		if _, err := tx.Exec("ALTER TABLE posts ADD COLUMN langs JSON"); err != nil {
+			return fmt.Errorf("failed to add langs to posts: %w", err)
+		}
+
+		if _, err := tx.Exec("ALTER TABLE posts ADD COLUMN facets JSON"); err != nil {
+			return fmt.Errorf("failed to add facets to posts: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (9)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 9: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 9: %w", err)
+		}
+	}
+
+	if currentVersion < 10 {
+		// Keep the tokenizer chosen with search.tokenizer
+		tokenizer, err := readSearchTokenizer(db)
+		if err != nil {
+			return fmt.Errorf("failed to read search tokenizer for migration 10: %w", err)
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 10: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Index alt text, link titles and descriptions, and quoted post text alongside
+		// the post text; the index is rebuilt once for existing archives
+		if err := createPostsFTS(tx, tokenizer); err != nil {
+			return fmt.Errorf("failed to rebuild search index: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (10)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 10: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 10: %w", err)
+		}
+	}
+
+	if currentVersion < 11 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 11: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Saved searches belong to a local account; new matches are found by post rowid,
+		// which only grows for newly archived posts (refreshes keep the rowid)
+		if _, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS saved_searches (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				account_id TEXT NOT NULL,
+				name TEXT,
+				query TEXT NOT NULL,
+				checked_rowid INTEGER NOT NULL DEFAULT 0,
+				seen_rowid INTEGER NOT NULL DEFAULT 0,
+				new_matches INTEGER NOT NULL DEFAULT 0,
+				last_checked_at TIMESTAMP,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(account_id, query)
+			)
+		`); err != nil {
+			return fmt.Errorf("failed to create saved_searches table: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (11)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 11: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 11: %w", err)
+		}
+	}
+
+	if currentVersion < 12 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 12: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Engagement snapshots: one row when a post is archived and one each time a
+		// refresh changes its counts
+		if _, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS post_engagement (
+				post_uri TEXT NOT NULL,
+				like_count INTEGER DEFAULT 0,
+				repost_count INTEGER DEFAULT 0,
+				reply_count INTEGER DEFAULT 0,
+				quote_count INTEGER DEFAULT 0,
+				recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)
+		`); err != nil {
+			return fmt.Errorf("failed to create post_engagement table: %w", err)
+		}
+		if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_post_engagement_uri ON post_engagement(post_uri, recorded_at)"); err != nil {
+			return fmt.Errorf("failed to create post_engagement index: %w", err)
+		}
+
+		triggers := []string{
+			`CREATE TRIGGER IF NOT EXISTS post_engagement_ai AFTER INSERT ON posts BEGIN
+				INSERT INTO post_engagement (post_uri, like_count, repost_count, reply_count, quote_count)
+				VALUES (new.uri, new.like_count, new.repost_count, new.reply_count, new.quote_count);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS post_engagement_au AFTER UPDATE OF like_count, repost_count, reply_count, quote_count ON posts
+			WHEN old.like_count IS NOT new.like_count OR old.repost_count IS NOT new.repost_count
+				OR old.reply_count IS NOT new.reply_count OR old.quote_count IS NOT new.quote_count
+			BEGIN
+				INSERT INTO post_engagement (post_uri, like_count, repost_count, reply_count, quote_count)
+				VALUES (new.uri, new.like_count, new.repost_count, new.reply_count, new.quote_count);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS post_engagement_ad AFTER DELETE ON posts BEGIN
+				DELETE FROM post_engagement WHERE post_uri = old.uri;
+			END`,
+		}
+		for _, trigger := range triggers {
+			if _, err := tx.Exec(trigger); err != nil {
+				return fmt.Errorf("failed to create post_engagement trigger: %w", err)
+			}
+		}
+
+		// Existing posts start their history with the counts from their last archive run
+		if _, err := tx.Exec(`
+			INSERT INTO post_engagement (post_uri, like_count, repost_count, reply_count, quote_count, recorded_at)
+			SELECT uri, like_count, repost_count, reply_count, quote_count, COALESCE(indexed_at, archived_at)
+			FROM posts
+		`); err != nil {
+			return fmt.Errorf("failed to record existing engagement: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (12)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 12: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 12: %w", err)
+		}
+	}
+
+	if currentVersion < 13 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 13: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Feed tokens let feed readers fetch one archive's Atom/RSS feed without a session
+		if _, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS feed_tokens (
+				token TEXT PRIMARY KEY,
+				account_id TEXT NOT NULL,
+				did TEXT NOT NULL,
+				name TEXT,
+				include_replies BOOLEAN NOT NULL DEFAULT 1,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				last_used_at TIMESTAMP
+			)
+		`); err != nil {
+			return fmt.Errorf("failed to create feed_tokens table: %w", err)
+		}
+		if _, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_feed_tokens_account ON feed_tokens(account_id)"); err != nil {
+			return fmt.Errorf("failed to create feed_tokens index: %w", err)
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (13)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 13: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 13: %w", err)
+		}
+	}
+
+	if currentVersion < 14 {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration 14: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Media records where each file was downloaded from, for WARC exports
+		// Media archived earlier has no source URL
+		var columnExists bool
+		err = tx.QueryRow(`
+			SELECT COUNT(*) > 0
+			FROM pragma_table_info('media')
+			WHERE name = 'source_url'
+		`).Scan(&columnExists)
+		if err != nil {
+			return fmt.Errorf("failed to check if source_url exists: %w", err)
+		}
+
+		if !columnExists {
+			if _, err := tx.Exec("ALTER TABLE media ADD COLUMN source_url TEXT"); err != nil {
+				return fmt.Errorf("failed to add source_url to media: %w", err)
+			}
+		}
+
+		// Update schema version
+		if _, err := tx.Exec("INSERT OR REPLACE INTO schema_version (version) VALUES (14)"); err != nil {
+			return fmt.Errorf("failed to update schema version to 14: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration 14: %w", err)
+		}
+	}
+
 	return nil
 }
 
-// invalidateAllSessions clears all sessions on startup
-// This ensures a clean state and prevents issues with stale sessions after restarts
-func invalidateAllSessions(db *sql.DB) error {
+// RevokeAllSessions deletes every login session and its persisted OAuth or app-password session
+// Used by the revoke-sessions admin command; users must sign in again afterwards
+func RevokeAllSessions(db *sql.DB) (int64, error) {
 	result, err := db.Exec("DELETE FROM sessions")
 	if err != nil {
-		return fmt.Errorf("failed to delete sessions: %w", err)
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
-	if rows > 0 {
-		fmt.Printf("Invalidated %d existing session(s) on startup\n", rows)
+	// Also remove any bskyoauth sessions not linked to a sessions row
+	if _, err := db.Exec("DELETE FROM oauth_sessions"); err != nil {
+		return rows, fmt.Errorf("failed to delete oauth sessions: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM app_password_sessions"); err != nil {
+		return rows, fmt.Errorf("failed to delete app password sessions: %w", err)
 	}
 
-	return nil
+	return rows, nil
 }
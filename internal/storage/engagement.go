@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// ListPostEngagement returns a post's engagement snapshots, oldest first
+// Snapshots are recorded by triggers when a post is archived and when a refresh changes its counts
+func ListPostEngagement(db *sql.DB, uri string) ([]models.EngagementSnapshot, error) {
+	engagement, err := ListEngagementForPosts(db, []string{uri})
+	if err != nil {
+		return nil, err
+	}
+	return engagement[uri], nil
+}
+
+// ListEngagementForPosts retrieves the engagement snapshots of several posts in one query,
+// keyed by post URI and oldest first. Posts without snapshots are left out of the map
+func ListEngagementForPosts(db *sql.DB, postURIs []string) (map[string][]models.EngagementSnapshot, error) {
+	engagement := make(map[string][]models.EngagementSnapshot)
+	if len(postURIs) == 0 {
+		return engagement, nil
+	}
+
+	uriClause, args := inClause("post_uri", postURIs)
+	rows, err := db.Query(`
+		SELECT post_uri, like_count, repost_count, reply_count, quote_count, recorded_at
+		FROM post_engagement
+		WHERE `+uriClause+`
+		ORDER BY recorded_at ASC, rowid ASC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list engagement: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uri string
+		var snapshot models.EngagementSnapshot
+		if err := rows.Scan(&uri, &snapshot.LikeCount, &snapshot.RepostCount, &snapshot.ReplyCount, &snapshot.QuoteCount, &snapshot.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan engagement: %w", err)
+		}
+		engagement[uri] = append(engagement[uri], snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating engagement: %w", err)
+	}
+
+	return engagement, nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// exportTables are the archive tables copied into a standalone database, in
+// dependency order. App state (sessions, operations, exports, ...) is left out
+var exportTables = []string{"posts", "media", "profiles", "post_engagement"}
+
+// createPrefix matches the start of a CREATE TABLE or CREATE INDEX statement so the
+// object can be created in the attached export schema instead
+var createPrefix = regexp.MustCompile(`^(?i)(CREATE\s+(?:UNIQUE\s+)?(?:TABLE|INDEX)\s+(?:IF\s+NOT\s+EXISTS\s+)?)`)
+
+// ExportDatabase writes a self-contained SQLite database to path holding one account's
+// posts (optionally limited to a date range), their media metadata and engagement
+// history, the account's profile snapshots and a posts_fts search index. Tables and
+// indexes are created from the archive's own schema. Returns the number of posts written
+//
+// The copy runs on one pinned connection with the new file attached, so other queries
+// wait until it finishes
+func ExportDatabase(db *sql.DB, path, did string, dateRange *models.DateRange) (int, error) {
+	// Read before pinning the connection: the pool holds a single connection
+	tokenizer, err := searchTokenizer(db)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS export", path); err != nil {
+		return 0, fmt.Errorf("failed to create export database: %w", err)
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE export")
+
+	schema, err := exportSchema(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range schema {
+		if _, err := tx.Exec(createPrefix.ReplaceAllString(statement, "${1}export.")); err != nil {
+			return 0, fmt.Errorf("failed to create export schema: %w", err)
+		}
+	}
+
+	where, args := dateRangeWhere(did, dateRange)
+	copies := []struct {
+		table string
+		query string
+		args  []interface{}
+	}{
+		{"posts", "INSERT INTO export.posts SELECT * FROM main.posts" + where, args},
+		{"media", "INSERT INTO export.media SELECT * FROM main.media WHERE post_uri IN (SELECT uri FROM export.posts)", nil},
+		{"profiles", "INSERT INTO export.profiles SELECT * FROM main.profiles WHERE did = ?", []interface{}{did}},
+		{"post_engagement", "INSERT INTO export.post_engagement SELECT * FROM main.post_engagement WHERE post_uri IN (SELECT uri FROM export.posts)", nil},
+	}
+	for _, c := range copies {
+		if _, err := tx.Exec(c.query, c.args...); err != nil {
+			return 0, fmt.Errorf("failed to copy %s: %w", c.table, err)
+		}
+	}
+
+	// The search index uses the archive's tokenizer; without triggers it is a
+	// snapshot, which is all a read-only copy needs
+	fts := strings.Replace(postsFTSSchema(tokenizer), "VIRTUAL TABLE posts_fts", "VIRTUAL TABLE export.posts_fts", 1)
+	if _, err := tx.Exec(fts); err != nil {
+		return 0, fmt.Errorf("failed to create search index: %w", err)
+	}
+	populate := `INSERT INTO export.posts_fts(rowid, uri, text, alt_text, link_text, quote_text)
+		SELECT p.rowid, p.uri, p.text, ` + strings.ReplaceAll(searchFields("p"), "FROM media", "FROM export.media") + `
+		FROM export.posts p`
+	if _, err := tx.Exec(populate); err != nil {
+		return 0, fmt.Errorf("failed to build search index: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM export.posts").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count exported posts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit export database: %w", err)
+	}
+
+	return count, nil
+}
+
+// exportSchema returns the CREATE statements of exportTables and their indexes
+func exportSchema(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	tables, args := inClause("tbl_name", exportTables)
+	rows, err := conn.QueryContext(ctx, `
+		SELECT type, tbl_name, sql FROM main.sqlite_master
+		WHERE type IN ('table', 'index') AND sql IS NOT NULL AND `+tables, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	defer rows.Close()
+
+	// Tables in dependency order, then indexes
+	tableSQL := make(map[string]string)
+	var indexes []string
+	for rows.Next() {
+		var kind, table, statement string
+		if err := rows.Scan(&kind, &table, &statement); err != nil {
+			return nil, fmt.Errorf("failed to scan schema: %w", err)
+		}
+		if kind == "table" {
+			tableSQL[table] = statement
+		} else {
+			indexes = append(indexes, statement)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schema: %w", err)
+	}
+
+	var schema []string
+	for _, table := range exportTables {
+		statement, ok := tableSQL[table]
+		if !ok {
+			return nil, fmt.Errorf("table %s not found", table)
+		}
+		schema = append(schema, statement)
+	}
+	return append(schema, indexes...), nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+func TestExportDatabase(t *testing.T) {
+	did := "did:plc:analyst"
+	db := setupSearchDB(t, did, "Compost and soil", "A photo of the garden", "Late May notes")
+
+	// Another account's post, media and profile must stay out of the export
+	if err := SavePost(db, &models.Post{URI: "at://did:plc:other/app.bsky.feed.post/x", CID: "cid", DID: "did:plc:other",
+		Text: "garden elsewhere", CreatedAt: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), IndexedAt: time.Now()}); err != nil {
+		t.Fatalf("SavePost failed: %v", err)
+	}
+	if err := SaveMedia(db, &models.Media{Hash: strings.Repeat("c", 64), PostURI: "at://" + did + "/app.bsky.feed.post/b",
+		MimeType: "image/jpeg", FilePath: "media/cc/c.jpg", AltText: "Tomatoes on the vine", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+	for _, profile := range []*models.Profile{
+		{DID: did, Handle: "analyst.test", SnapshotAt: time.Now()},
+		{DID: "did:plc:other", Handle: "other.test", SnapshotAt: time.Now()},
+	} {
+		if err := SaveProfile(db, profile); err != nil {
+			t.Fatalf("SaveProfile failed: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "archive.db")
+	dateRange := &models.DateRange{EndDate: time.Date(2024, 5, 2, 23, 59, 59, 0, time.UTC)}
+	count, err := ExportDatabase(db, path, did, dateRange)
+	if err != nil {
+		t.Fatalf("ExportDatabase failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 posts in range, got %d", count)
+	}
+
+	// The archive keeps working after the export detaches
+	if _, err := GetPost(db, "at://"+did+"/app.bsky.feed.post/a"); err != nil {
+		t.Errorf("Archive unusable after export: %v", err)
+	}
+
+	export, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open export: %v", err)
+	}
+	defer export.Close()
+
+	rows, err := export.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'posts_fts_%' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		t.Fatalf("Failed to list tables: %v", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if got := strings.Join(tables, ","); got != "media,post_engagement,posts,posts_fts,profiles" {
+		t.Errorf("Unexpected tables: %s", got)
+	}
+
+	counts := map[string]int{
+		"SELECT COUNT(*) FROM posts WHERE did = ?":                                 2,
+		"SELECT COUNT(*) FROM posts WHERE did != ?":                                0,
+		"SELECT COUNT(*) FROM profiles WHERE did = ?":                              1,
+		"SELECT COUNT(*) FROM profiles WHERE did != ?":                             0,
+		"SELECT COUNT(*) FROM media WHERE post_uri LIKE '%' || ? || '%'":           1,
+		"SELECT COUNT(*) FROM post_engagement WHERE post_uri LIKE '%' || ? || '%'": 2,
+	}
+	for query, want := range counts {
+		var got int
+		if err := export.QueryRow(query, did).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if got != want {
+			t.Errorf("%s = %d, want %d", query, got, want)
+		}
+	}
+
+	// Full-text search over the copied posts, including media alt text
+	var uri string
+	if err := export.QueryRow("SELECT p.uri FROM posts_fts JOIN posts p ON p.rowid = posts_fts.rowid WHERE posts_fts MATCH 'tomatoes'").Scan(&uri); err != nil {
+		t.Fatalf("Search in export failed: %v", err)
+	}
+	if uri != "at://"+did+"/app.bsky.feed.post/b" {
+		t.Errorf("Search matched %s", uri)
+	}
+
+	if _, err := os.Stat(path + "-wal"); !os.IsNotExist(err) {
+		t.Error("Export should be a single file without a WAL")
+	}
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// CreateFeedToken creates a secret feed token for one of the archives an account can read
+func CreateFeedToken(db *sql.DB, accountID, did, name string, includeReplies bool) (*models.FeedToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	token := hex.EncodeToString(secret)
+
+	_, err := db.Exec(`
+		INSERT INTO feed_tokens (token, account_id, did, name, include_replies, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, token, accountID, did, strings.TrimSpace(name), includeReplies, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feed token: %w", err)
+	}
+
+	return GetFeedToken(db, token)
+}
+
+// GetFeedToken retrieves a feed token, for serving its feed
+func GetFeedToken(db *sql.DB, token string) (*models.FeedToken, error) {
+	rows, err := db.Query(feedTokenQuery+" WHERE token = ?", token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed token: %w", err)
+	}
+	defer rows.Close()
+
+	tokens, err := scanFeedTokens(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("feed token not found")
+	}
+
+	return &tokens[0], nil
+}
+
+// ListFeedTokens retrieves an account's feed tokens, newest first
+func ListFeedTokens(db *sql.DB, accountID string) ([]models.FeedToken, error) {
+	rows, err := db.Query(feedTokenQuery+" WHERE account_id = ? ORDER BY created_at DESC", accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feed tokens: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFeedTokens(rows)
+}
+
+// DeleteFeedToken revokes one of an account's feed tokens
+func DeleteFeedToken(db *sql.DB, accountID, token string) error {
+	result, err := db.Exec("DELETE FROM feed_tokens WHERE account_id = ? AND token = ?", accountID, token)
+	if err != nil {
+		return fmt.Errorf("failed to delete feed token: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("feed token not found")
+	}
+
+	return nil
+}
+
+// TouchFeedToken records that a feed reader fetched the token's feed
+func TouchFeedToken(db *sql.DB, token string) error {
+	if _, err := db.Exec("UPDATE feed_tokens SET last_used_at = ? WHERE token = ?", time.Now(), token); err != nil {
+		return fmt.Errorf("failed to update feed token: %w", err)
+	}
+
+	return nil
+}
+
+// feedTokenQuery selects feed tokens for scanFeedTokens
+const feedTokenQuery = `
+	SELECT token, account_id, did, name, include_replies, created_at, last_used_at
+	FROM feed_tokens
+`
+
+// scanFeedTokens reads rows produced by feedTokenQuery
+func scanFeedTokens(rows *sql.Rows) ([]models.FeedToken, error) {
+	var tokens []models.FeedToken
+	for rows.Next() {
+		var token models.FeedToken
+		var name sql.NullString
+		var lastUsed sql.NullTime
+
+		err := rows.Scan(
+			&token.Token, &token.AccountID, &token.DID, &name,
+			&token.IncludeReplies, &token.CreatedAt, &lastUsed,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feed token: %w", err)
+		}
+
+		token.Name = name.String
+		if lastUsed.Valid {
+			token.LastUsedAt = &lastUsed.Time
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feed tokens: %w", err)
+	}
+
+	return tokens, nil
+}
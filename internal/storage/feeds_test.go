@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFeedTokens(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	token, err := CreateFeedToken(db, "account-1", "did:plc:feeder", " Reader ", false)
+	if err != nil {
+		t.Fatalf("CreateFeedToken failed: %v", err)
+	}
+	if len(token.Token) != 64 || token.Name != "Reader" || token.IncludeReplies || token.LastUsedAt != nil {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	other, err := CreateFeedToken(db, "account-1", "did:plc:feeder", "", true)
+	if err != nil {
+		t.Fatalf("CreateFeedToken failed: %v", err)
+	}
+	if other.Token == token.Token {
+		t.Error("Tokens should be unique")
+	}
+
+	if err := TouchFeedToken(db, token.Token); err != nil {
+		t.Fatalf("TouchFeedToken failed: %v", err)
+	}
+	got, err := GetFeedToken(db, token.Token)
+	if err != nil {
+		t.Fatalf("GetFeedToken failed: %v", err)
+	}
+	if got.DID != "did:plc:feeder" || got.LastUsedAt == nil {
+		t.Errorf("Unexpected token after use: %+v", got)
+	}
+
+	tokens, err := ListFeedTokens(db, "account-1")
+	if err != nil {
+		t.Fatalf("ListFeedTokens failed: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Errorf("Expected 2 tokens, got %d", len(tokens))
+	}
+
+	// Another account cannot revoke the token
+	if err := DeleteFeedToken(db, "account-2", token.Token); err == nil {
+		t.Error("Expected an error deleting another account's token")
+	}
+	if err := DeleteFeedToken(db, "account-1", token.Token); err != nil {
+		t.Fatalf("DeleteFeedToken failed: %v", err)
+	}
+	if _, err := GetFeedToken(db, token.Token); err == nil {
+		t.Error("Deleted token should not be found")
+	}
+}
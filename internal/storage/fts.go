@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Search index tokenizers (search.tokenizer in config.yaml)
+const (
+	TokenizerWords   = "unicode61" // whole words (default)
+	TokenizerPrefix  = "prefix"    // words match from their start: "photo" finds "photography"
+	TokenizerTrigram = "trigram"   // any part of a word: "graph" finds "photography"
+)
+
+// Tokenizers lists the accepted search.tokenizer values
+var Tokenizers = []string{TokenizerWords, TokenizerPrefix, TokenizerTrigram}
+
+// searchTokenizers caches the tokenizer of each database's search index
+var searchTokenizers sync.Map // *sql.DB -> string
+
+// searchRank orders matches with bm25(), weighting the posts_fts columns
+// (uri, text, alt_text, link_text, quote_text): the post's own words count most
+const searchRank = "bm25(posts_fts, 0, 10.0, 4.0, 3.0, 2.0)"
+
+// postsFTSSchema returns the CREATE statement for posts_fts with a tokenizer
+// The index stores its own copy of each field because alt text, link titles and
+// quoted text are gathered from the media table and embed_data
+func postsFTSSchema(tokenizer string) string {
+	options := ""
+	switch tokenizer {
+	case TokenizerPrefix:
+		options = ",\n\t\t\tprefix='2 3 4'"
+	case TokenizerTrigram:
+		options = ",\n\t\t\ttokenize='trigram'"
+	}
+
+	return `CREATE VIRTUAL TABLE posts_fts USING fts5(
+			uri UNINDEXED,
+			text,
+			alt_text,
+			link_text,
+			quote_text` + options + `
+		)`
+}
+
+// searchFields returns the alt_text, link_text and quote_text expressions for a posts row
+// alias is the row name: "new" in triggers, "p" when repopulating
+func searchFields(alias string) string {
+	embed := "CASE WHEN json_valid(CAST(" + alias + ".embed_data AS TEXT)) THEN CAST(" + alias + ".embed_data AS TEXT) END"
+	field := func(path string) string {
+		return "json_extract(" + embed + ", '" + path + "')"
+	}
+
+	return `(SELECT group_concat(alt_text, ' ') FROM media WHERE post_uri = ` + alias + `.uri AND alt_text != ''),
+			concat_ws(' ', ` + field("$.external.title") + `, ` + field("$.external.description") + `,
+				` + field("$.media.external.title") + `, ` + field("$.media.external.description") + `),
+			coalesce(` + field("$.record.value.text") + `, ` + field("$.record.record.value.text") + `)`
+}
+
+// postsFTSTriggers keep posts_fts in sync with posts and media
+func postsFTSTriggers() []string {
+	altText := `UPDATE posts_fts
+			SET alt_text = (SELECT group_concat(alt_text, ' ') FROM media WHERE post_uri = %[1]s.post_uri AND alt_text != '')
+			WHERE rowid = (SELECT rowid FROM posts WHERE uri = %[1]s.post_uri);`
+
+	return []string{
+		`CREATE TRIGGER posts_ai AFTER INSERT ON posts BEGIN
+			INSERT INTO posts_fts(rowid, uri, text, alt_text, link_text, quote_text)
+			SELECT new.rowid, new.uri, new.text, ` + searchFields("new") + `;
+		END`,
+
+		`CREATE TRIGGER posts_ad AFTER DELETE ON posts BEGIN
+			DELETE FROM posts_fts WHERE rowid = old.rowid;
+		END`,
+
+		`CREATE TRIGGER posts_au AFTER UPDATE OF text, embed_data ON posts BEGIN
+			DELETE FROM posts_fts WHERE rowid = old.rowid;
+			INSERT INTO posts_fts(rowid, uri, text, alt_text, link_text, quote_text)
+			SELECT new.rowid, new.uri, new.text, ` + searchFields("new") + `;
+		END`,
+
+		`CREATE TRIGGER media_ai AFTER INSERT ON media BEGIN
+			` + fmt.Sprintf(altText, "new") + `
+		END`,
+
+		`CREATE TRIGGER media_au AFTER UPDATE OF alt_text, post_uri ON media BEGIN
+			` + fmt.Sprintf(altText, "old") + `
+			` + fmt.Sprintf(altText, "new") + `
+		END`,
+
+		`CREATE TRIGGER media_ad AFTER DELETE ON media BEGIN
+			` + fmt.Sprintf(altText, "old") + `
+		END`,
+	}
+}
+
+// createPostsFTS (re)creates posts_fts and its triggers and indexes every post
+func createPostsFTS(tx *sql.Tx, tokenizer string) error {
+	for _, trigger := range []string{"posts_ai", "posts_ad", "posts_au", "media_ai", "media_au", "media_ad"} {
+		if _, err := tx.Exec("DROP TRIGGER IF EXISTS " + trigger); err != nil {
+			return fmt.Errorf("failed to drop trigger %s: %w", trigger, err)
+		}
+	}
+	if _, err := tx.Exec("DROP TABLE IF EXISTS posts_fts"); err != nil {
+		return fmt.Errorf("failed to drop search index: %w", err)
+	}
+
+	if _, err := tx.Exec(postsFTSSchema(tokenizer)); err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+	for _, trigger := range postsFTSTriggers() {
+		if _, err := tx.Exec(trigger); err != nil {
+			return fmt.Errorf("failed to create search index trigger: %w", err)
+		}
+	}
+
+	populate := `INSERT INTO posts_fts(rowid, uri, text, alt_text, link_text, quote_text)
+		SELECT p.rowid, p.uri, p.text, ` + searchFields("p") + `
+		FROM posts p`
+	if _, err := tx.Exec(populate); err != nil {
+		return fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+	return nil
+}
+
+// ConfigureSearchIndex rebuilds posts_fts when its tokenizer differs from the configured one
+// Returns true if the index was rebuilt; an empty tokenizer means TokenizerWords
+func ConfigureSearchIndex(db *sql.DB, tokenizer string) (bool, error) {
+	if tokenizer == "" {
+		tokenizer = TokenizerWords
+	}
+
+	current, err := readSearchTokenizer(db)
+	if err != nil {
+		return false, err
+	}
+	if current == tokenizer {
+		searchTokenizers.Store(db, tokenizer)
+		return false, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := createPostsFTS(tx, tokenizer); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit search index: %w", err)
+	}
+
+	searchTokenizers.Store(db, tokenizer)
+	return true, nil
+}
+
+// searchTokenizer returns the tokenizer of the search index, reading it once per database
+func searchTokenizer(db *sql.DB) (string, error) {
+	if tokenizer, ok := searchTokenizers.Load(db); ok {
+		return tokenizer.(string), nil
+	}
+
+	tokenizer, err := readSearchTokenizer(db)
+	if err != nil {
+		return "", err
+	}
+	searchTokenizers.Store(db, tokenizer)
+	return tokenizer, nil
+}
+
+// readSearchTokenizer inspects the posts_fts definition
+func readSearchTokenizer(db *sql.DB) (string, error) {
+	var schema string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'posts_fts'").Scan(&schema)
+	if err == sql.ErrNoRows {
+		// Not created yet; the migration creates it with the default tokenizer
+		return TokenizerWords, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read search index: %w", err)
+	}
+
+	switch {
+	case strings.Contains(schema, "trigram"):
+		return TokenizerTrigram, nil
+	case strings.Contains(schema, "prefix="):
+		return TokenizerPrefix, nil
+	}
+	return TokenizerWords, nil
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// setupSearchDB creates a database with posts for search tests, oldest first
+func setupSearchDB(t *testing.T, did string, texts ...string) *sql.DB {
+	t.Helper()
+
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for i, text := range texts {
+		created := time.Date(2024, 5, 1+i, 12, 0, 0, 0, time.UTC)
+		post := &models.Post{
+			URI:       "at://" + did + "/app.bsky.feed.post/" + string(rune('a'+i)),
+			CID:       "cid",
+			DID:       did,
+			Text:      text,
+			CreatedAt: created,
+			IndexedAt: created,
+		}
+		if err := SavePost(db, post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	return db
+}
+
+func TestSearchPostsForDIDs_Relevance(t *testing.T) {
+	did := "did:plc:ranker"
+	db := setupSearchDB(t, did,
+		"Garden garden garden, all about the garden",
+		"A long day with many things going on and a short visit to the garden afterwards",
+		"Nothing relevant here",
+	)
+
+	newest, err := SearchPostsForDIDs(db, []string{did}, "garden", models.SearchSortNewest, 20, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(newest.Posts) != 2 || newest.Posts[0].URI != "at://"+did+"/app.bsky.feed.post/b" {
+		t.Fatalf("Expected newest post first, got %+v", newest.Posts)
+	}
+	if newest.Elapsed == "" {
+		t.Error("Expected Elapsed to be set")
+	}
+
+	relevant, err := SearchPostsForDIDs(db, []string{did}, "garden", models.SearchSortRelevance, 20, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if relevant.Sort != models.SearchSortRelevance {
+		t.Errorf("Sort = %q, want relevance", relevant.Sort)
+	}
+	if len(relevant.Posts) != 2 || relevant.Posts[0].URI != "at://"+did+"/app.bsky.feed.post/a" {
+		t.Fatalf("Expected best match first, got %+v", relevant.Posts)
+	}
+
+	// Filter-only queries have no ranking and fall back to newest first
+	filtered, err := SearchPostsForDIDs(db, []string{did}, "after:2024-05-01", models.SearchSortRelevance, 20, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(filtered.Posts) != 3 || filtered.Posts[0].URI != "at://"+did+"/app.bsky.feed.post/c" {
+		t.Errorf("Expected newest first for filter-only query, got %+v", filtered.Posts)
+	}
+	if len(filtered.Highlights) != 0 {
+		t.Errorf("Expected no highlights for filter-only query, got %v", filtered.Highlights)
+	}
+}
+
+func TestSearchPostsForDIDs_Highlights(t *testing.T) {
+	did := "did:plc:highlighter"
+	db := setupSearchDB(t, did, "Fish & <chips> by the sea")
+
+	result, err := SearchPosts(db, did, "chips", 20, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Posts) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(result.Posts))
+	}
+
+	uri := result.Posts[0].URI
+	want := "Fish &amp; &lt;<mark>chips</mark>&gt; by the sea"
+	if got := result.Highlights[uri]; got != want {
+		t.Errorf("Highlight = %q, want %q", got, want)
+	}
+	if got := result.Snippets[uri]; got != want {
+		t.Errorf("Snippet = %q, want %q", got, want)
+	}
+}
+
+func TestConfigureSearchIndex(t *testing.T) {
+	did := "did:plc:tokenizer"
+	db := setupSearchDB(t, did, "Photography walk downtown", "Go tips")
+
+	rebuilt, err := ConfigureSearchIndex(db, "")
+	if err != nil {
+		t.Fatalf("ConfigureSearchIndex failed: %v", err)
+	}
+	if rebuilt {
+		t.Error("Expected no rebuild for the default tokenizer")
+	}
+
+	count := func(query string) int {
+		t.Helper()
+		result, err := SearchPosts(db, did, query, 20, 0)
+		if err != nil {
+			t.Fatalf("SearchPosts(%q) failed: %v", query, err)
+		}
+		return result.Total
+	}
+
+	if n := count("photo"); n != 0 {
+		t.Errorf("Expected whole-word search to miss partial words, got %d", n)
+	}
+
+	rebuilt, err = ConfigureSearchIndex(db, TokenizerPrefix)
+	if err != nil {
+		t.Fatalf("ConfigureSearchIndex failed: %v", err)
+	}
+	if !rebuilt {
+		t.Error("Expected the index to be rebuilt")
+	}
+	if n := count("photo"); n != 1 {
+		t.Errorf("Expected prefix search to find 1 post, got %d", n)
+	}
+
+	if _, err := ConfigureSearchIndex(db, TokenizerTrigram); err != nil {
+		t.Fatalf("ConfigureSearchIndex failed: %v", err)
+	}
+	if n := count("graph"); n != 1 {
+		t.Errorf("Expected trigram search to find 1 post, got %d", n)
+	}
+
+	// New posts are indexed by the existing triggers after a rebuild
+	post := &models.Post{URI: "at://" + did + "/app.bsky.feed.post/new", CID: "cid", DID: did, Text: "Telegraph office", CreatedAt: time.Now(), IndexedAt: time.Now()}
+	if err := SavePost(db, post); err != nil {
+		t.Fatalf("SavePost failed: %v", err)
+	}
+	if n := count("graph"); n != 2 {
+		t.Errorf("Expected 2 posts after insert, got %d", n)
+	}
+
+	// Trigrams cannot match words shorter than three characters
+	_, err = SearchPosts(db, did, "go", 20, 0)
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Errorf("Expected QueryError for a short term, got %v", err)
+	}
+}
+
+func TestSearchPosts_EmbeddedFields(t *testing.T) {
+	did := "did:plc:fields"
+	db := setupSearchDB(t, did, "Look at this", "Worth reading", "So true", "Lighthouse at dusk")
+	uri := func(rkey string) string { return "at://" + did + "/app.bsky.feed.post/" + rkey }
+
+	// Alt text comes from the media table and follows later updates
+	media := &models.Media{Hash: strings.Repeat("c", 64), PostURI: uri("a"), MimeType: "image/jpeg", FilePath: "c.jpg", AltText: "A lighthouse on the cliffs", CreatedAt: time.Now()}
+	if err := SaveMedia(db, media); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	// Link titles and quoted posts come from embed_data
+	embeds := map[string]string{
+		"b": `{"$type":"app.bsky.embed.external#view","external":{"uri":"https://example.com","title":"Lighthouse keepers","description":"A history"}}`,
+		"c": `{"$type":"app.bsky.embed.record#view","record":{"$type":"app.bsky.embed.record#viewRecord","uri":"at://did:plc:other/app.bsky.feed.post/q","value":{"text":"Every lighthouse tells a story"}}}`,
+	}
+	for rkey, embed := range embeds {
+		post, err := GetPost(db, uri(rkey))
+		if err != nil {
+			t.Fatalf("GetPost failed: %v", err)
+		}
+		post.EmbedData = json.RawMessage(embed)
+		if err := SavePost(db, post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+
+	result, err := SearchPostsForDIDs(db, []string{did}, "lighthouse", models.SearchSortRelevance, 20, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Total != 4 {
+		t.Fatalf("Expected 4 results, got %d", result.Total)
+	}
+
+	// The post text is weighted above the other fields
+	if result.Posts[0].URI != uri("d") {
+		t.Errorf("Expected the post text match first, got %s", result.Posts[0].URI)
+	}
+	if snippet := result.Snippets[uri("a")]; !strings.Contains(snippet, "<mark>lighthouse</mark> on the cliffs") {
+		t.Errorf("Expected the alt text snippet, got %q", snippet)
+	}
+	if _, ok := result.Highlights[uri("a")]; ok {
+		t.Error("Expected no post text highlight for an alt text match")
+	}
+
+	media.AltText = "A boat"
+	if err := SaveMedia(db, media); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+	result, err = SearchPosts(db, did, "lighthouse", 20, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Total != 3 {
+		t.Errorf("Expected 3 results after the alt text changed, got %d", result.Total)
+	}
+}
+
+func TestSearchIndexMigration(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	did := "did:plc:migrated"
+
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	post := &models.Post{URI: "at://" + did + "/app.bsky.feed.post/1", CID: "cid", DID: did, Text: "Sunset", CreatedAt: time.Now(), IndexedAt: time.Now()}
+	if err := SavePost(db, post); err != nil {
+		t.Fatalf("SavePost failed: %v", err)
+	}
+	media := &models.Media{Hash: strings.Repeat("d", 64), PostURI: post.URI, MimeType: "image/jpeg", FilePath: "d.jpg", AltText: "Orange sky over the harbour", CreatedAt: time.Now()}
+	if err := SaveMedia(db, media); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	// Recreate the text-only index used before schema version 10
+	for _, stmt := range []string{
+		"DROP TRIGGER media_ai", "DROP TRIGGER media_au", "DROP TRIGGER media_ad",
+		"DROP TRIGGER posts_ai", "DROP TRIGGER posts_ad", "DROP TRIGGER posts_au",
+		"DROP TABLE posts_fts",
+		"CREATE VIRTUAL TABLE posts_fts USING fts5(uri UNINDEXED, text, content='posts', content_rowid='rowid')",
+		"INSERT INTO posts_fts(posts_fts) VALUES ('rebuild')",
+		"DELETE FROM schema_version WHERE version >= 10",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	db.Close()
+
+	db, err = InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen db: %v", err)
+	}
+	defer db.Close()
+
+	result, err := SearchPosts(db, did, "harbour", 20, 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Total != 1 {
+		t.Errorf("Expected the migrated index to include alt text, got %d results", result.Total)
+	}
+}
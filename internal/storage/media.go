@@ -16,8 +16,8 @@ func SaveMedia(db *sql.DB, media *models.Media) error {
 	query := `
 		INSERT INTO media (
 			hash, post_uri, mime_type, file_path, size_bytes,
-			width, height, alt_text, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			width, height, alt_text, source_url, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(hash) DO UPDATE SET
 			post_uri = excluded.post_uri,
 			mime_type = excluded.mime_type,
@@ -25,12 +25,13 @@ func SaveMedia(db *sql.DB, media *models.Media) error {
 			size_bytes = excluded.size_bytes,
 			width = excluded.width,
 			height = excluded.height,
-			alt_text = excluded.alt_text
+			alt_text = excluded.alt_text,
+			source_url = COALESCE(NULLIF(excluded.source_url, ''), media.source_url)
 	`
 
 	_, err := db.Exec(query,
 		media.Hash, media.PostURI, media.MimeType, media.FilePath, media.SizeBytes,
-		media.Width, media.Height, media.AltText, media.CreatedAt,
+		media.Width, media.Height, media.AltText, media.SourceURL, media.CreatedAt,
 	)
 
 	if err != nil {
@@ -44,7 +45,7 @@ func SaveMedia(db *sql.DB, media *models.Media) error {
 func ListMediaForPost(db *sql.DB, postURI string) ([]models.Media, error) {
 	query := `
 		SELECT hash, post_uri, mime_type, file_path, size_bytes,
-			   width, height, alt_text, created_at
+			   width, height, alt_text, COALESCE(source_url, ''), created_at
 		FROM media
 		WHERE post_uri = ?
 		ORDER BY created_at ASC
@@ -61,7 +62,7 @@ func ListMediaForPost(db *sql.DB, postURI string) ([]models.Media, error) {
 		var media models.Media
 		err := rows.Scan(
 			&media.Hash, &media.PostURI, &media.MimeType, &media.FilePath,
-			&media.SizeBytes, &media.Width, &media.Height, &media.AltText, &media.CreatedAt,
+			&media.SizeBytes, &media.Width, &media.Height, &media.AltText, &media.SourceURL, &media.CreatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan media: %w", err)
@@ -76,11 +77,53 @@ func ListMediaForPost(db *sql.DB, postURI string) ([]models.Media, error) {
 	return mediaList, nil
 }
 
+// ListMediaForPosts retrieves the media of several posts in one query, keyed by post URI
+// Posts without media are left out of the map
+func ListMediaForPosts(db *sql.DB, postURIs []string) (map[string][]models.Media, error) {
+	mediaMap := make(map[string][]models.Media)
+	if len(postURIs) == 0 {
+		return mediaMap, nil
+	}
+
+	uriClause, args := inClause("post_uri", postURIs)
+	query := `
+		SELECT hash, post_uri, mime_type, file_path, size_bytes,
+			   width, height, alt_text, COALESCE(source_url, ''), created_at
+		FROM media
+		WHERE ` + uriClause + `
+		ORDER BY created_at ASC
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var media models.Media
+		err := rows.Scan(
+			&media.Hash, &media.PostURI, &media.MimeType, &media.FilePath,
+			&media.SizeBytes, &media.Width, &media.Height, &media.AltText, &media.SourceURL, &media.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		mediaMap[media.PostURI] = append(mediaMap[media.PostURI], media)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating media: %w", err)
+	}
+
+	return mediaMap, nil
+}
+
 // GetMediaByHash retrieves media by its content hash
 func GetMediaByHash(db *sql.DB, hash string) (*models.Media, error) {
 	query := `
 		SELECT hash, post_uri, mime_type, file_path, size_bytes,
-			   width, height, alt_text, created_at
+			   width, height, alt_text, COALESCE(source_url, ''), created_at
 		FROM media
 		WHERE hash = ?
 	`
@@ -88,7 +131,7 @@ func GetMediaByHash(db *sql.DB, hash string) (*models.Media, error) {
 	var media models.Media
 	err := db.QueryRow(query, hash).Scan(
 		&media.Hash, &media.PostURI, &media.MimeType, &media.FilePath,
-		&media.SizeBytes, &media.Width, &media.Height, &media.AltText, &media.CreatedAt,
+		&media.SizeBytes, &media.Width, &media.Height, &media.AltText, &media.SourceURL, &media.CreatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -100,3 +143,26 @@ func GetMediaByHash(db *sql.DB, hash string) (*models.Media, error) {
 
 	return &media, nil
 }
+
+// MediaVisibleTo reports whether media belongs to a post by one of the given DIDs
+func MediaVisibleTo(db *sql.DB, hash string, dids []string) (bool, error) {
+	if len(dids) == 0 {
+		return false, nil
+	}
+
+	clause, args := inClause("p.did", dids)
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM media m
+			JOIN posts p ON p.uri = m.post_uri
+			WHERE m.hash = ? AND ` + clause + `
+		)
+	`
+
+	var visible bool
+	if err := db.QueryRow(query, append([]interface{}{hash}, args...)...).Scan(&visible); err != nil {
+		return false, fmt.Errorf("failed to check media access: %w", err)
+	}
+
+	return visible, nil
+}
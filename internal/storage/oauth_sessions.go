@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SaveOAuthSession inserts or updates an encrypted bskyoauth session
+// data is the already-encrypted session payload; storage never sees plaintext tokens
+func SaveOAuthSession(db *sql.DB, id, did string, data []byte) error {
+	if id == "" {
+		return fmt.Errorf("oauth session id is required")
+	}
+	if did == "" {
+		return fmt.Errorf("did is required")
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO oauth_sessions (id, did, data, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			did = excluded.did,
+			data = excluded.data,
+			updated_at = excluded.updated_at
+	`, id, did, data, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save oauth session: %w", err)
+	}
+
+	return nil
+}
+
+// GetOAuthSession retrieves the encrypted payload of a bskyoauth session by ID
+func GetOAuthSession(db *sql.DB, id string) ([]byte, error) {
+	var data []byte
+	err := db.QueryRow("SELECT data FROM oauth_sessions WHERE id = ?", id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("oauth session not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth session: %w", err)
+	}
+
+	return data, nil
+}
+
+// DeleteOAuthSession removes a bskyoauth session by ID
+// Deleting a session that does not exist is not an error
+func DeleteOAuthSession(db *sql.DB, id string) error {
+	if _, err := db.Exec("DELETE FROM oauth_sessions WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete oauth session: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestOAuthSessionsLifecycle verifies save, update, get and delete of persisted oauth sessions
+func TestOAuthSessionsLifecycle(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	if err := SaveOAuthSession(db, "oauth-1", "did:plc:test", []byte("first")); err != nil {
+		t.Fatalf("SaveOAuthSession() failed: %v", err)
+	}
+	if err := SaveOAuthSession(db, "oauth-1", "did:plc:test", []byte("second")); err != nil {
+		t.Fatalf("SaveOAuthSession() update failed: %v", err)
+	}
+
+	data, err := GetOAuthSession(db, "oauth-1")
+	if err != nil {
+		t.Fatalf("GetOAuthSession() failed: %v", err)
+	}
+	if string(data) != "second" {
+		t.Errorf("Expected updated data 'second', got %q", data)
+	}
+
+	if err := DeleteOAuthSession(db, "oauth-1"); err != nil {
+		t.Fatalf("DeleteOAuthSession() failed: %v", err)
+	}
+	if _, err := GetOAuthSession(db, "oauth-1"); err == nil {
+		t.Error("Expected error getting deleted oauth session")
+	}
+
+	if err := SaveOAuthSession(db, "", "did:plc:test", []byte("x")); err == nil {
+		t.Error("Expected error for empty session id")
+	}
+}
+
+// TestOAuthSessionsFollowSessionRows verifies oauth sessions are removed with their sessions row
+func TestOAuthSessionsFollowSessionRows(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	insertSession := func(id, did, oauthID string) {
+		t.Helper()
+		_, err := db.Exec(`
+			INSERT INTO sessions (id, did, handle, display_name, access_token, refresh_token, expires_at)
+			VALUES (?, ?, ?, ?, ?, '', ?)
+			ON CONFLICT(did) DO UPDATE SET id = excluded.id, access_token = excluded.access_token
+		`, id, did, did, did, oauthID, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+	}
+
+	// Re-login replaces the previous oauth session
+	SaveOAuthSession(db, "oauth-old", "did:plc:alice", []byte("old"))
+	insertSession("s1", "did:plc:alice", "oauth-old")
+	SaveOAuthSession(db, "oauth-new", "did:plc:alice", []byte("new"))
+	insertSession("s2", "did:plc:alice", "oauth-new")
+
+	if _, err := GetOAuthSession(db, "oauth-old"); err == nil {
+		t.Error("Expected previous oauth session to be removed on re-login")
+	}
+	if _, err := GetOAuthSession(db, "oauth-new"); err != nil {
+		t.Errorf("Expected current oauth session to remain: %v", err)
+	}
+
+	// Revoking all sessions clears everything
+	SaveOAuthSession(db, "oauth-bob", "did:plc:bob", []byte("bob"))
+	insertSession("s3", "did:plc:bob", "oauth-bob")
+
+	revoked, err := RevokeAllSessions(db)
+	if err != nil {
+		t.Fatalf("RevokeAllSessions() failed: %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("Expected 2 revoked sessions, got %d", revoked)
+	}
+
+	var remaining int
+	db.QueryRow("SELECT COUNT(*) FROM oauth_sessions").Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected no oauth sessions after revoke, got %d", remaining)
+	}
+}
+
+// TestSessionsPersistAcrossRestart verifies sessions are no longer wiped when the database is reopened
+func TestSessionsPersistAcrossRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO sessions (id, did, handle, access_token, refresh_token, expires_at)
+		VALUES ('s1', 'did:plc:alice', 'alice', 'oauth-1', '', ?)
+	`, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+	SaveOAuthSession(db, "oauth-1", "did:plc:alice", []byte("data"))
+	db.Close()
+
+	db, err = InitDB(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected session to survive restart, got %d sessions", count)
+	}
+	if _, err := GetOAuthSession(db, "oauth-1"); err != nil {
+		t.Errorf("Expected oauth session to survive restart: %v", err)
+	}
+}
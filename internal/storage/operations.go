@@ -145,6 +145,29 @@ func ListRecentOperations(db *sql.DB, did string, limit int) ([]models.ArchiveOp
 	}
 	defer rows.Close()
 
+	return scanOperations(rows)
+}
+
+// ListOperationHistory retrieves every operation for a user, oldest first
+func ListOperationHistory(db *sql.DB, did string) ([]models.ArchiveOperation, error) {
+	query := `
+		SELECT id, did, type, status, progress, total, error, started_at, completed_at
+		FROM operations
+		WHERE did = ?
+		ORDER BY started_at ASC
+	`
+
+	rows, err := db.Query(query, did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer rows.Close()
+
+	return scanOperations(rows)
+}
+
+// scanOperations reads operation rows in the column order used by the queries above
+func scanOperations(rows *sql.Rows) ([]models.ArchiveOperation, error) {
 	var operations []models.ArchiveOperation
 	for rows.Next() {
 		var op models.ArchiveOperation
@@ -165,7 +188,7 @@ func ListRecentOperations(db *sql.DB, did string, limit int) ([]models.ArchiveOp
 		operations = append(operations, op)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating operations: %w", err)
 	}
 
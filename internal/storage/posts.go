@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/shindakun/bskyarchive/internal/models"
 )
@@ -25,12 +27,23 @@ func SavePost(db *sql.DB, post *models.Post) error {
 		return fmt.Errorf("failed to marshal labels: %w", err)
 	}
 
+	langs, err := json.Marshal(post.Langs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal langs: %w", err)
+	}
+
+	facets, err := json.Marshal(post.Facets)
+	if err != nil {
+		return fmt.Errorf("failed to marshal facets: %w", err)
+	}
+
 	query := `
 		INSERT INTO posts (
 			uri, cid, did, text, created_at, indexed_at,
 			has_media, like_count, repost_count, reply_count, quote_count,
-			is_reply, reply_parent, embed_type, embed_data, labels, archived_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			is_reply, reply_parent, embed_type, embed_data, labels, archived_at,
+			langs, facets
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(uri) DO UPDATE SET
 			cid = excluded.cid,
 			text = excluded.text,
@@ -42,13 +55,16 @@ func SavePost(db *sql.DB, post *models.Post) error {
 			quote_count = excluded.quote_count,
 			embed_type = excluded.embed_type,
 			embed_data = excluded.embed_data,
-			labels = excluded.labels
+			labels = excluded.labels,
+			langs = excluded.langs,
+			facets = excluded.facets
 	`
 
 	_, err = db.Exec(query,
 		post.URI, post.CID, post.DID, post.Text, post.CreatedAt, post.IndexedAt,
 		post.HasMedia, post.LikeCount, post.RepostCount, post.ReplyCount, post.QuoteCount,
 		post.IsReply, post.ReplyParent, post.EmbedType, embedData, labels, post.ArchivedAt,
+		string(langs), string(facets), // stored as text so search filters can read them
 	)
 
 	if err != nil {
@@ -60,135 +76,369 @@ func SavePost(db *sql.DB, post *models.Post) error {
 
 // GetPost retrieves a post by its URI
 func GetPost(db *sql.DB, uri string) (*models.Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts p WHERE p.uri = ?`
+
+	post, err := scanPost(db.QueryRow(query, uri))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("post not found: %s", uri)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+
+	return post, nil
+}
+
+// PostExists checks whether a post with the given URI is already archived
+func PostExists(db *sql.DB, uri string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM posts WHERE uri = ?)", uri).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check post: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ListThreadAncestors returns the archived posts a reply answers, from the thread root down
+// to its direct parent. The walk stops at the first parent that is not archived, is not
+// from one of dids, or is more than maxDepth levels up
+func ListThreadAncestors(db *sql.DB, uri string, dids []string, maxDepth int) ([]models.Post, error) {
+	didClause, didArgs := inClause("p.did", dids)
 	query := `
-		SELECT uri, cid, did, text, created_at, indexed_at,
-			   has_media, like_count, repost_count, reply_count, quote_count,
-			   is_reply, reply_parent, embed_type, embed_data, labels, archived_at
-		FROM posts
-		WHERE uri = ?
+		WITH RECURSIVE ancestors(uri, parent, depth) AS (
+			SELECT uri, reply_parent, 0 FROM posts WHERE uri = ?
+			UNION ALL
+			SELECT p.uri, p.reply_parent, a.depth + 1
+			FROM ancestors a JOIN posts p ON p.uri = a.parent
+			WHERE a.depth < ? AND ` + didClause + `
+		)
+		SELECT ` + postColumns + `
+		FROM ancestors a JOIN posts p ON p.uri = a.uri
+		WHERE a.depth > 0
+		ORDER BY a.depth DESC
 	`
 
-	var post models.Post
-	var embedData, labels []byte
+	args := append([]interface{}{uri, maxDepth}, didArgs...)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list thread: %w", err)
+	}
+	defer rows.Close()
 
-	err := db.QueryRow(query, uri).Scan(
-		&post.URI, &post.CID, &post.DID, &post.Text, &post.CreatedAt, &post.IndexedAt,
-		&post.HasMedia, &post.LikeCount, &post.RepostCount, &post.ReplyCount, &post.QuoteCount,
-		&post.IsReply, &post.ReplyParent, &post.EmbedType, &embedData, &labels, &post.ArchivedAt,
-	)
+	return scanPosts(rows)
+}
 
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("post not found: %s", uri)
+// ListReplies returns the archived replies to a post from dids, oldest first
+func ListReplies(db *sql.DB, uri string, dids []string, limit int) ([]models.Post, error) {
+	if limit <= 0 {
+		limit = 100
 	}
+
+	didClause, args := inClause("p.did", dids)
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts p
+		WHERE p.reply_parent = ? AND ` + didClause + `
+		ORDER BY p.created_at ASC
+		LIMIT ?
+	`
+
+	rows, err := db.Query(query, append(append([]interface{}{uri}, args...), limit)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get post: %w", err)
+		return nil, fmt.Errorf("failed to list replies: %w", err)
 	}
+	defer rows.Close()
 
-	// Deserialize JSON fields
-	if len(embedData) > 0 {
-		post.EmbedData = json.RawMessage(embedData)
+	return scanPosts(rows)
+}
+
+// PostsExist checks several URIs in one query and returns the ones that are archived
+func PostsExist(db *sql.DB, uris []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(uris) == 0 {
+		return existing, nil
 	}
-	if len(labels) > 0 {
-		post.Labels = json.RawMessage(labels)
+
+	uriClause, args := inClause("uri", uris)
+	rows, err := db.Query("SELECT uri FROM posts WHERE "+uriClause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check posts: %w", err)
 	}
+	defer rows.Close()
 
-	return &post, nil
+	for rows.Next() {
+		var uri string
+		if err := rows.Scan(&uri); err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		existing[uri] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating posts: %w", err)
+	}
+
+	return existing, nil
+}
+
+// ListArchivedDIDs returns every DID that has posts in the archive
+func ListArchivedDIDs(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT did FROM posts ORDER BY did")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archived accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var dids []string
+	for rows.Next() {
+		var did string
+		if err := rows.Scan(&did); err != nil {
+			return nil, fmt.Errorf("failed to scan did: %w", err)
+		}
+		dids = append(dids, did)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating archived accounts: %w", err)
+	}
+
+	return dids, nil
+}
+
+// ListPosts retrieves a page of posts for one account
+// did is required so a caller can never list every user's posts by accident
+func ListPosts(db *sql.DB, did string, opts models.PostListOptions) (*models.PagedPostsResponse, error) {
+	if did == "" {
+		return nil, fmt.Errorf("did is required")
+	}
+
+	return ListPostsForDIDs(db, []string{did}, opts)
 }
 
-// ListPosts retrieves posts with pagination
-func ListPosts(db *sql.DB, did string, limit, offset int) (*models.PagedPostsResponse, error) {
+// isQuoteCondition matches quote posts (with or without media)
+const isQuoteCondition = "COALESCE(p.embed_type, '') IN ('record', 'record_with_media')"
+
+// postOrders lists the sort columns for each PostSort; uri breaks ties so every
+// post has a unique position for keyset pagination
+var postOrders = map[models.PostSort]struct {
+	columns []string
+	desc    bool
+}{
+	models.PostSortNewest:  {[]string{"created_at", "uri"}, true},
+	models.PostSortOldest:  {[]string{"created_at", "uri"}, false},
+	models.PostSortLikes:   {[]string{"like_count", "created_at", "uri"}, true},
+	models.PostSortReposts: {[]string{"repost_count", "created_at", "uri"}, true},
+}
+
+// ListPostsForDIDs retrieves a page of posts from several accounts (combined view)
+// Pages use keyset pagination: the cursor post's sort values bound the next page, so
+// deep pages cost the same as the first. An unknown cursor starts from the first page
+func ListPostsForDIDs(db *sql.DB, dids []string, opts models.PostListOptions) (*models.PagedPostsResponse, error) {
+	if len(dids) == 0 {
+		return nil, fmt.Errorf("at least one did is required")
+	}
+	limit := opts.Limit
 	if limit <= 0 || limit > 100 {
 		limit = 20 // Default page size
 	}
-	if offset < 0 {
-		offset = 0
+	order, ok := postOrders[opts.Sort]
+	if !ok {
+		order = postOrders[models.PostSortNewest]
 	}
 
-	// Get total count - if did is empty, count all posts
+	didClause, args := inClause("p.did", dids)
+	conditions := append([]string{didClause}, postFilterConditions(opts.Filter, &args)...)
+	where := strings.Join(conditions, " AND ")
+
 	var total int64
-	var countQuery string
-	var err error
-	if did == "" {
-		countQuery = `SELECT COUNT(*) FROM posts`
-		err = db.QueryRow(countQuery).Scan(&total)
-	} else {
-		countQuery = `SELECT COUNT(*) FROM posts WHERE did = ?`
-		err = db.QueryRow(countQuery, did).Scan(&total)
-	}
-	if err != nil {
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts p WHERE "+where, args...).Scan(&total); err != nil {
 		return nil, fmt.Errorf("failed to count posts: %w", err)
 	}
 
-	// Get posts - if did is empty, get all posts
-	var query string
-	var rows *sql.Rows
-	if did == "" {
-		query = `
-			SELECT uri, cid, did, text, created_at, indexed_at,
-				   has_media, like_count, repost_count, reply_count, quote_count,
-				   is_reply, reply_parent, embed_type, embed_data, labels, archived_at
-			FROM posts
-			ORDER BY created_at DESC
-			LIMIT ? OFFSET ?
-		`
-		rows, err = db.Query(query, limit, offset)
-	} else {
-		query = `
-			SELECT uri, cid, did, text, created_at, indexed_at,
-				   has_media, like_count, repost_count, reply_count, quote_count,
-				   is_reply, reply_parent, embed_type, embed_data, labels, archived_at
-			FROM posts
-			WHERE did = ?
-			ORDER BY created_at DESC
-			LIMIT ? OFFSET ?
-		`
-		rows, err = db.Query(query, did, limit, offset)
+	// Paging back walks the order in reverse from the cursor, then flips the page
+	cursor, backward := opts.After, false
+	if opts.Before != "" {
+		cursor, backward = opts.Before, true
+	}
+	if cursor != "" {
+		if exists, err := PostExists(db, cursor); err != nil {
+			return nil, err
+		} else if !exists {
+			cursor, backward = "", false
+		}
+	}
+
+	direction, comparison := " ASC", ">"
+	if order.desc != backward {
+		direction, comparison = " DESC", "<"
+	}
+
+	var keys, cursorKeys, orderBy []string
+	for _, column := range order.columns {
+		keys = append(keys, "p."+column)
+		cursorKeys = append(cursorKeys, "c."+column)
+		orderBy = append(orderBy, "p."+column+direction)
+	}
+
+	pageWhere, pageArgs := where, append([]interface{}{}, args...)
+	if cursor != "" {
+		pageWhere += fmt.Sprintf(" AND (%s) %s (SELECT %s FROM posts c WHERE c.uri = ?)",
+			strings.Join(keys, ", "), comparison, strings.Join(cursorKeys, ", "))
+		pageArgs = append(pageArgs, cursor)
 	}
+
+	// One extra row tells whether another page follows
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts p
+		WHERE ` + pageWhere + `
+		ORDER BY ` + strings.Join(orderBy, ", ") + `
+		LIMIT ?
+	`
+
+	rows, err := db.Query(query, append(pageArgs, limit+1)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list posts: %w", err)
 	}
 	defer rows.Close()
 
-	var posts []models.Post
-	for rows.Next() {
-		var post models.Post
-		var embedData, labels []byte
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, err
+	}
 
-		err := rows.Scan(
-			&post.URI, &post.CID, &post.DID, &post.Text, &post.CreatedAt, &post.IndexedAt,
-			&post.HasMedia, &post.LikeCount, &post.RepostCount, &post.ReplyCount, &post.QuoteCount,
-			&post.IsReply, &post.ReplyParent, &post.EmbedType, &embedData, &labels, &post.ArchivedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan post: %w", err)
+	more := len(posts) > limit
+	if more {
+		posts = posts[:limit]
+	}
+	if backward {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
 		}
+	}
 
-		// Deserialize JSON fields
-		if len(embedData) > 0 {
-			post.EmbedData = json.RawMessage(embedData)
-		}
-		if len(labels) > 0 {
-			post.Labels = json.RawMessage(labels)
+	response := &models.PagedPostsResponse{
+		Posts:    posts,
+		Total:    int(total),
+		PageSize: limit,
+	}
+	if len(posts) > 0 {
+		first, last := posts[0].URI, posts[len(posts)-1].URI
+		switch {
+		case backward:
+			response.NextCursor = last
+			if more {
+				response.PrevCursor = first
+			}
+		default:
+			if more {
+				response.NextCursor = last
+			}
+			if cursor != "" {
+				response.PrevCursor = first
+			}
 		}
+	}
 
-		posts = append(posts, post)
+	return response, nil
+}
+
+// postFilterConditions returns SQL conditions (on the posts alias p) for a filter,
+// appending their arguments to args
+func postFilterConditions(filter models.PostFilter, args *[]interface{}) []string {
+	var conditions []string
+	if filter.MediaOnly {
+		conditions = append(conditions, "p.has_media = 1")
+	}
+	switch filter.Replies {
+	case models.ReplyFilterOnly:
+		conditions = append(conditions, "p.is_reply = 1")
+	case models.ReplyFilterOriginals:
+		conditions = append(conditions, "COALESCE(p.is_reply, 0) = 0")
+	}
+	if filter.QuotesOnly {
+		conditions = append(conditions, isQuoteCondition)
 	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "p.created_at >= ?")
+		*args = append(*args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "p.created_at < ?")
+		*args = append(*args, filter.Until.UTC())
+	}
+	return conditions
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating posts: %w", err)
+// inClause builds "column IN (?, ...)" and its arguments
+func inClause(column string, values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
 	}
+	return column + " IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
 
-	// Calculate pagination metadata
-	page := (offset / limit) + 1
-	totalPages := int((total + int64(limit) - 1) / int64(limit))
+// postColumns lists the post columns read by scanPost, qualified with the alias p
+const postColumns = `p.uri, p.cid, p.did, p.text, p.created_at, p.indexed_at,
+	p.has_media, p.like_count, p.repost_count, p.reply_count, p.quote_count,
+	p.is_reply, p.reply_parent, p.embed_type, p.embed_data, p.labels, p.archived_at,
+	p.langs, p.facets`
 
-	return &models.PagedPostsResponse{
-		Posts:      posts,
-		Total:      int(total),
-		Page:       page,
-		PageSize:   limit,
-		TotalPages: totalPages,
-	}, nil
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost reads one row selected with postColumns
+func scanPost(row rowScanner) (*models.Post, error) {
+	var post models.Post
+	var embedData, labels, langs, facets []byte
+
+	err := row.Scan(
+		&post.URI, &post.CID, &post.DID, &post.Text, &post.CreatedAt, &post.IndexedAt,
+		&post.HasMedia, &post.LikeCount, &post.RepostCount, &post.ReplyCount, &post.QuoteCount,
+		&post.IsReply, &post.ReplyParent, &post.EmbedType, &embedData, &labels, &post.ArchivedAt,
+		&langs, &facets,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deserialize JSON fields
+	if len(embedData) > 0 {
+		post.EmbedData = json.RawMessage(embedData)
+	}
+	if len(labels) > 0 {
+		post.Labels = json.RawMessage(labels)
+	}
+	if len(langs) > 0 {
+		_ = json.Unmarshal(langs, &post.Langs)
+	}
+	if len(facets) > 0 {
+		post.Facets = json.RawMessage(facets)
+	}
+
+	return &post, nil
+}
+
+// scanPosts reads rows selected with postColumns
+func scanPosts(rows *sql.Rows) ([]models.Post, error) {
+	var posts []models.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, *post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating posts: %w", err)
+	}
+
+	return posts, nil
 }
 
 // ListPostsWithDateRange retrieves posts with optional date range filtering
@@ -201,83 +451,116 @@ func ListPostsWithDateRange(db *sql.DB, did string, dateRange *models.DateRange,
 		offset = 0
 	}
 
-	// Build query with date range filters
-	var query string
-	var args []interface{}
+	where, args := dateRangeWhere(did, dateRange)
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts p
+	` + where
 
-	selectClause := `
-		SELECT uri, cid, did, text, created_at, indexed_at,
-			   has_media, like_count, repost_count, reply_count, quote_count,
-			   is_reply, reply_parent, embed_type, embed_data, labels, archived_at
-		FROM posts
-	`
+	// Add deterministic ordering for stable pagination
+	// uri is the primary key, so it acts as a tie-breaker when created_at values are identical
+	query += " ORDER BY created_at DESC, uri ASC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
 
-	whereConditions := []string{}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posts with date range: %w", err)
+	}
+	defer rows.Close()
 
-	if did != "" {
-		whereConditions = append(whereConditions, "did = ?")
-		args = append(args, did)
+	return scanPosts(rows)
+}
+
+// CountPostsWithDateRange counts the posts ListPostsWithDateRange pages through
+func CountPostsWithDateRange(db *sql.DB, did string, dateRange *models.DateRange) (int, error) {
+	where, args := dateRangeWhere(did, dateRange)
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts "+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count posts: %w", err)
 	}
+	return count, nil
+}
 
-	if dateRange != nil {
-		if !dateRange.StartDate.IsZero() {
-			whereConditions = append(whereConditions, "created_at >= ?")
-			args = append(args, dateRange.StartDate)
-		}
-		if !dateRange.EndDate.IsZero() {
-			whereConditions = append(whereConditions, "created_at <= ?")
-			args = append(args, dateRange.EndDate)
-		}
+// PostDateRange returns the creation times of the oldest and newest posts of several DIDs
+// Both are zero when the DIDs have no posts
+func PostDateRange(db *sql.DB, dids []string) (time.Time, time.Time, error) {
+	var oldest, newest time.Time
+	if len(dids) == 0 {
+		return oldest, newest, nil
 	}
 
-	if len(whereConditions) > 0 {
-		query = selectClause + " WHERE " + whereConditions[0]
-		for i := 1; i < len(whereConditions); i++ {
-			query += " AND " + whereConditions[i]
+	clause, args := inClause("did", dids)
+	var oldestStr, newestStr sql.NullString
+	if err := db.QueryRow("SELECT MIN(created_at), MAX(created_at) FROM posts WHERE "+clause, args...).Scan(&oldestStr, &newestStr); err != nil {
+		return oldest, newest, fmt.Errorf("failed to get post date range: %w", err)
+	}
+	if oldestStr.Valid && oldestStr.String != "" {
+		if t, err := parseTimestamp(oldestStr.String); err == nil {
+			oldest = t
+		}
+	}
+	if newestStr.Valid && newestStr.String != "" {
+		if t, err := parseTimestamp(newestStr.String); err == nil {
+			newest = t
 		}
-	} else {
-		query = selectClause
 	}
+	return oldest, newest, nil
+}
 
-	// Add deterministic ordering for stable pagination
-	// uri is the primary key, so it acts as a tie-breaker when created_at values are identical
-	query += " ORDER BY created_at DESC, uri ASC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
+// ListPostDatesWithDateRange maps the URIs of the posts ListPostsWithDateRange pages through
+// to their creation time. Exports use it to tell whether a reply parent or quoted post is
+// part of the same export, and where its page or note is
+func ListPostDatesWithDateRange(db *sql.DB, did string, dateRange *models.DateRange) (map[string]time.Time, error) {
+	where, args := dateRangeWhere(did, dateRange)
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.Query("SELECT uri, created_at FROM posts "+where, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list posts with date range: %w", err)
+		return nil, fmt.Errorf("failed to list post URIs: %w", err)
 	}
 	defer rows.Close()
 
-	var posts []models.Post
+	dates := make(map[string]time.Time)
 	for rows.Next() {
-		var post models.Post
-		var embedData, labels []byte
-
-		err := rows.Scan(
-			&post.URI, &post.CID, &post.DID, &post.Text, &post.CreatedAt, &post.IndexedAt,
-			&post.HasMedia, &post.LikeCount, &post.RepostCount, &post.ReplyCount, &post.QuoteCount,
-			&post.IsReply, &post.ReplyParent, &post.EmbedType, &embedData, &labels, &post.ArchivedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan post: %w", err)
+		var uri string
+		var createdAt time.Time
+		if err := rows.Scan(&uri, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan post URI: %w", err)
 		}
+		dates[uri] = createdAt
+	}
 
-		// Deserialize JSON fields
-		if len(embedData) > 0 {
-			post.EmbedData = json.RawMessage(embedData)
-		}
-		if len(labels) > 0 {
-			post.Labels = json.RawMessage(labels)
-		}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post URIs: %w", err)
+	}
+
+	return dates, nil
+}
+
+// dateRangeWhere builds the WHERE clause shared by the date range queries
+// An empty did and nil dateRange match every post
+func dateRangeWhere(did string, dateRange *models.DateRange) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
 
-		posts = append(posts, post)
+	if did != "" {
+		conditions = append(conditions, "did = ?")
+		args = append(args, did)
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating posts: %w", err)
+	if dateRange != nil {
+		if !dateRange.StartDate.IsZero() {
+			conditions = append(conditions, "created_at >= ?")
+			args = append(args, dateRange.StartDate)
+		}
+		if !dateRange.EndDate.IsZero() {
+			conditions = append(conditions, "created_at <= ?")
+			args = append(args, dateRange.EndDate)
+		}
 	}
 
-	return posts, nil
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
 }
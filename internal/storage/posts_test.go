@@ -3,6 +3,8 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -38,7 +40,9 @@ func setupTestDB(t *testing.T) *sql.DB {
 			embed_type TEXT,
 			embed_data JSON,
 			labels JSON,
-			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			langs JSON,
+			facets JSON
 		);
 
 		CREATE INDEX idx_posts_did ON posts(did);
@@ -365,3 +369,340 @@ func TestListPostsWithDateRange_NegativeOffset(t *testing.T) {
 		t.Errorf("Negative offset did not behave like offset=0")
 	}
 }
+
+// TestListPostsForDIDs tests the combined view across linked accounts
+func TestListPostsForDIDs(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	insertTestPosts(t, db, "did:plc:alice", 5)
+	insertTestPosts(t, db, "did:plc:bob", 3)
+	insertTestPosts(t, db, "did:plc:carol", 4)
+
+	result, err := ListPostsForDIDs(db, []string{"did:plc:alice", "did:plc:bob"}, models.PostListOptions{Limit: 20})
+	if err != nil {
+		t.Fatalf("ListPostsForDIDs failed: %v", err)
+	}
+	if result.Total != 8 || len(result.Posts) != 8 {
+		t.Errorf("Expected 8 posts, got total %d with %d returned", result.Total, len(result.Posts))
+	}
+	for _, post := range result.Posts {
+		if post.DID == "did:plc:carol" {
+			t.Errorf("Unexpected post from an unlinked account: %s", post.URI)
+		}
+	}
+
+	if _, err := ListPostsForDIDs(db, nil, models.PostListOptions{Limit: 20}); err == nil {
+		t.Error("Expected error when no DIDs are given")
+	}
+}
+
+func TestPostDateRange(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	// Posts are an hour apart per group of 10, so alice's newest is two hours in
+	insertTestPosts(t, db, "did:plc:alice", 25)
+	insertTestPosts(t, db, "did:plc:bob", 5)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldest, newest, err := PostDateRange(db, []string{"did:plc:alice", "did:plc:bob"})
+	if err != nil {
+		t.Fatalf("PostDateRange failed: %v", err)
+	}
+	if !oldest.Equal(base) || !newest.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("Expected %v to %v, got %v to %v", base, base.Add(2*time.Hour), oldest, newest)
+	}
+
+	oldest, newest, err = PostDateRange(db, []string{"did:plc:carol"})
+	if err != nil {
+		t.Fatalf("PostDateRange failed: %v", err)
+	}
+	if !oldest.IsZero() || !newest.IsZero() {
+		t.Errorf("Expected zero times without posts, got %v to %v", oldest, newest)
+	}
+}
+
+// TestListPostsForDIDs_Keyset walks pages forward and back with each sort order
+func TestListPostsForDIDs_Keyset(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	did := "did:plc:pager"
+	insertTestPosts(t, db, did, 25)
+	if _, err := db.Exec("UPDATE posts SET like_count = CAST(substr(uri, -2) AS INTEGER) % 7"); err != nil {
+		t.Fatalf("Failed to set like counts: %v", err)
+	}
+
+	for _, sort := range []models.PostSort{models.PostSortNewest, models.PostSortOldest, models.PostSortLikes, models.PostSortReposts} {
+		t.Run(string(sort), func(t *testing.T) {
+			opts := models.PostListOptions{Sort: sort, Limit: 10}
+
+			var pages [][]models.Post
+			seen := make(map[string]bool)
+			for {
+				result, err := ListPostsForDIDs(db, []string{did}, opts)
+				if err != nil {
+					t.Fatalf("ListPostsForDIDs failed: %v", err)
+				}
+				if result.Total != 25 {
+					t.Errorf("Expected total 25, got %d", result.Total)
+				}
+				for _, post := range result.Posts {
+					if seen[post.URI] {
+						t.Fatalf("Post %s returned twice", post.URI)
+					}
+					seen[post.URI] = true
+				}
+				if len(pages) == 0 && result.PrevCursor != "" {
+					t.Error("First page should have no previous cursor")
+				}
+				pages = append(pages, result.Posts)
+				if result.NextCursor == "" {
+					break
+				}
+				opts.After = result.NextCursor
+			}
+			if len(pages) != 3 || len(seen) != 25 {
+				t.Fatalf("Expected 25 posts over 3 pages, got %d over %d", len(seen), len(pages))
+			}
+
+			// Paging back from the last page returns the middle page unchanged
+			back, err := ListPostsForDIDs(db, []string{did}, models.PostListOptions{Sort: sort, Limit: 10, Before: pages[2][0].URI})
+			if err != nil {
+				t.Fatalf("ListPostsForDIDs (before) failed: %v", err)
+			}
+			if len(back.Posts) != 10 {
+				t.Fatalf("Expected 10 posts paging back, got %d", len(back.Posts))
+			}
+			for i, post := range back.Posts {
+				if post.URI != pages[1][i].URI {
+					t.Errorf("Post %d paging back = %s, want %s", i, post.URI, pages[1][i].URI)
+				}
+			}
+			if back.PrevCursor == "" || back.NextCursor == "" {
+				t.Error("Middle page should have both cursors")
+			}
+		})
+	}
+
+	// Sorting by likes puts the most liked post first
+	result, err := ListPostsForDIDs(db, []string{did}, models.PostListOptions{Sort: models.PostSortLikes, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListPostsForDIDs failed: %v", err)
+	}
+	if result.Posts[0].LikeCount != 6 || result.Posts[len(result.Posts)-1].LikeCount > result.Posts[0].LikeCount {
+		t.Errorf("Posts not ordered by likes: first has %d", result.Posts[0].LikeCount)
+	}
+
+	// An unknown cursor starts from the first page
+	result, err = ListPostsForDIDs(db, []string{did}, models.PostListOptions{Limit: 10, After: "at://did:plc:gone/app.bsky.feed.post/1"})
+	if err != nil {
+		t.Fatalf("ListPostsForDIDs failed: %v", err)
+	}
+	if len(result.Posts) != 10 || result.PrevCursor != "" {
+		t.Errorf("Expected the first page for an unknown cursor, got %d posts", len(result.Posts))
+	}
+}
+
+// TestListPostsForDIDs_Filter tests the Browse filters
+func TestListPostsForDIDs_Filter(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	did := "did:plc:filter"
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	posts := []models.Post{
+		{URI: "at://" + did + "/app.bsky.feed.post/1", Text: "Plain", CreatedAt: day(1)},
+		{URI: "at://" + did + "/app.bsky.feed.post/2", Text: "Photo", CreatedAt: day(2), HasMedia: true, EmbedType: "images"},
+		{URI: "at://" + did + "/app.bsky.feed.post/3", Text: "Reply", CreatedAt: day(3), IsReply: true, ReplyParent: "at://did:plc:other/app.bsky.feed.post/9"},
+		{URI: "at://" + did + "/app.bsky.feed.post/4", Text: "Quote", CreatedAt: day(4), EmbedType: "record"},
+		{URI: "at://" + did + "/app.bsky.feed.post/5", Text: "Quote with photo", CreatedAt: day(5), HasMedia: true, EmbedType: "record_with_media"},
+	}
+	for i := range posts {
+		posts[i].CID = "cid"
+		posts[i].DID = did
+		posts[i].IndexedAt = posts[i].CreatedAt
+		if err := SavePost(db, &posts[i]); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter models.PostFilter
+		want   []string // rkeys, newest first
+	}{
+		{"all", models.PostFilter{}, []string{"5", "4", "3", "2", "1"}},
+		{"media", models.PostFilter{MediaOnly: true}, []string{"5", "2"}},
+		{"replies", models.PostFilter{Replies: models.ReplyFilterOnly}, []string{"3"}},
+		{"originals", models.PostFilter{Replies: models.ReplyFilterOriginals}, []string{"5", "4", "2", "1"}},
+		{"quotes", models.PostFilter{QuotesOnly: true}, []string{"5", "4"}},
+		{"date range", models.PostFilter{Since: day(2).Truncate(24 * time.Hour), Until: day(4).Truncate(24 * time.Hour)}, []string{"3", "2"}},
+		{"media quotes", models.PostFilter{MediaOnly: true, QuotesOnly: true}, []string{"5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ListPosts(db, did, models.PostListOptions{Filter: tt.filter, Limit: 20})
+			if err != nil {
+				t.Fatalf("ListPosts failed: %v", err)
+			}
+			if result.Total != len(tt.want) || len(result.Posts) != len(tt.want) {
+				t.Fatalf("Expected %d posts, got %d (total %d)", len(tt.want), len(result.Posts), result.Total)
+			}
+			for i, rkey := range tt.want {
+				if want := "at://" + did + "/app.bsky.feed.post/" + rkey; result.Posts[i].URI != want {
+					t.Errorf("Post %d = %s, want %s", i, result.Posts[i].URI, want)
+				}
+			}
+		})
+	}
+}
+
+// TestBatchedLookups tests the page-at-a-time media, existence and handle lookups used by Browse
+func TestBatchedLookups(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:batch"
+	posts := insertTestPosts(t, db, did, 3)
+
+	for i, uri := range []string{posts[0].URI, posts[0].URI, posts[2].URI} {
+		media := &models.Media{
+			Hash:      strings.Repeat(fmt.Sprint(i), 64),
+			PostURI:   uri,
+			MimeType:  "image/jpeg",
+			FilePath:  fmt.Sprintf("media/%d.jpg", i),
+			CreatedAt: time.Date(2025, 1, 1, i, 0, 0, 0, time.UTC),
+		}
+		if err := SaveMedia(db, media); err != nil {
+			t.Fatalf("SaveMedia failed: %v", err)
+		}
+	}
+
+	mediaMap, err := ListMediaForPosts(db, []string{posts[0].URI, posts[1].URI, posts[2].URI})
+	if err != nil {
+		t.Fatalf("ListMediaForPosts failed: %v", err)
+	}
+	if len(mediaMap) != 2 || len(mediaMap[posts[0].URI]) != 2 || len(mediaMap[posts[2].URI]) != 1 {
+		t.Errorf("Unexpected media map: %v", mediaMap)
+	}
+	if mediaMap[posts[0].URI][0].FilePath != "media/0.jpg" {
+		t.Errorf("Media not in creation order: %s first", mediaMap[posts[0].URI][0].FilePath)
+	}
+
+	missing := "at://did:plc:other/app.bsky.feed.post/gone"
+	existing, err := PostsExist(db, []string{posts[1].URI, missing})
+	if err != nil {
+		t.Fatalf("PostsExist failed: %v", err)
+	}
+	if !existing[posts[1].URI] || existing[missing] {
+		t.Errorf("Unexpected existence map: %v", existing)
+	}
+
+	for i, handle := range []string{"old.test", "new.test"} {
+		profile := &models.Profile{DID: did, Handle: handle, SnapshotAt: time.Date(2025, 1, i+1, 0, 0, 0, 0, time.UTC)}
+		if err := SaveProfile(db, profile); err != nil {
+			t.Fatalf("SaveProfile failed: %v", err)
+		}
+	}
+	if err := SaveProfile(db, &models.Profile{DID: "did:plc:unrelated", Handle: "unrelated.test", SnapshotAt: time.Now()}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+
+	handles, err := LatestHandles(db, []string{did, "did:plc:unknown"})
+	if err != nil {
+		t.Fatalf("LatestHandles failed: %v", err)
+	}
+	if len(handles) != 1 || handles[did] != "new.test" {
+		t.Errorf("Unexpected handles: %v", handles)
+	}
+
+	// Empty inputs need no query
+	if mediaMap, err := ListMediaForPosts(db, nil); err != nil || len(mediaMap) != 0 {
+		t.Errorf("Expected no media for no posts, got %v (%v)", mediaMap, err)
+	}
+}
+
+// TestThreadAndEngagement tests thread context and engagement history for the post page
+func TestThreadAndEngagement(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	alice, bob := "did:plc:alice", "did:plc:bob"
+	uri := func(did, rkey string) string { return "at://" + did + "/app.bsky.feed.post/" + rkey }
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	posts := []models.Post{
+		{URI: uri(alice, "1"), DID: alice, Text: "Root", CreatedAt: day(1), IsReply: true, ReplyParent: uri("did:plc:gone", "0")},
+		{URI: uri(bob, "2"), DID: bob, Text: "Hidden reply", CreatedAt: day(2), IsReply: true, ReplyParent: uri(alice, "1")},
+		{URI: uri(alice, "3"), DID: alice, Text: "Reply", CreatedAt: day(3), IsReply: true, ReplyParent: uri(alice, "1")},
+		{URI: uri(alice, "4"), DID: alice, Text: "Reply to reply", CreatedAt: day(4), IsReply: true, ReplyParent: uri(alice, "3")},
+		{URI: uri(alice, "5"), DID: alice, Text: "Under hidden", CreatedAt: day(5), IsReply: true, ReplyParent: uri(bob, "2")},
+	}
+	for i := range posts {
+		posts[i].CID = "cid"
+		posts[i].IndexedAt = posts[i].CreatedAt
+		if err := SavePost(db, &posts[i]); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+
+	// Ancestors run root first and stop at posts outside the visible DIDs
+	ancestors, err := ListThreadAncestors(db, uri(alice, "4"), []string{alice}, 20)
+	if err != nil {
+		t.Fatalf("ListThreadAncestors failed: %v", err)
+	}
+	if len(ancestors) != 2 || ancestors[0].Text != "Root" || ancestors[1].Text != "Reply" {
+		t.Errorf("Unexpected ancestors: %+v", ancestors)
+	}
+	if ancestors, _ := ListThreadAncestors(db, uri(alice, "4"), []string{alice}, 1); len(ancestors) != 1 || ancestors[0].Text != "Reply" {
+		t.Errorf("Expected only the direct parent with depth 1, got %+v", ancestors)
+	}
+	if ancestors, _ := ListThreadAncestors(db, uri(alice, "5"), []string{alice}, 20); len(ancestors) != 0 {
+		t.Errorf("Expected no ancestors through a hidden post, got %+v", ancestors)
+	}
+
+	replies, err := ListReplies(db, uri(alice, "1"), []string{alice}, 0)
+	if err != nil {
+		t.Fatalf("ListReplies failed: %v", err)
+	}
+	if len(replies) != 1 || replies[0].Text != "Reply" {
+		t.Errorf("Unexpected replies: %+v", replies)
+	}
+
+	// Counts are recorded on insert and when a refresh changes them
+	post := posts[0]
+	if err := SavePost(db, &post); err != nil {
+		t.Fatalf("SavePost failed: %v", err)
+	}
+	post.LikeCount, post.RepostCount = 7, 2
+	if err := SavePost(db, &post); err != nil {
+		t.Fatalf("SavePost failed: %v", err)
+	}
+
+	snapshots, err := ListPostEngagement(db, post.URI)
+	if err != nil {
+		t.Fatalf("ListPostEngagement failed: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %d", len(snapshots))
+	}
+	if snapshots[0].LikeCount != 0 || snapshots[1].LikeCount != 7 || snapshots[1].RepostCount != 2 {
+		t.Errorf("Unexpected snapshots: %+v", snapshots)
+	}
+
+	engagement, err := ListEngagementForPosts(db, []string{post.URI, uri(alice, "3"), uri(alice, "missing")})
+	if err != nil {
+		t.Fatalf("ListEngagementForPosts failed: %v", err)
+	}
+	if len(engagement) != 2 || len(engagement[post.URI]) != 2 || len(engagement[uri(alice, "3")]) != 1 {
+		t.Errorf("Unexpected batched engagement: %+v", engagement)
+	}
+}
@@ -60,3 +60,71 @@ func GetLatestProfile(db *sql.DB, did string) (*models.Profile, error) {
 
 	return &profile, nil
 }
+
+// ListProfileSnapshots retrieves every profile snapshot for a DID, oldest first
+func ListProfileSnapshots(db *sql.DB, did string) ([]models.Profile, error) {
+	query := `
+		SELECT did, handle, display_name, description, avatar_url, banner_url,
+			   followers_count, follows_count, posts_count, snapshot_at
+		FROM profiles
+		WHERE did = ?
+		ORDER BY snapshot_at ASC
+	`
+
+	rows, err := db.Query(query, did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []models.Profile
+	for rows.Next() {
+		var profile models.Profile
+		if err := rows.Scan(
+			&profile.DID, &profile.Handle, &profile.DisplayName, &profile.Description,
+			&profile.AvatarURL, &profile.BannerURL, &profile.FollowersCount,
+			&profile.FollowsCount, &profile.PostsCount, &profile.SnapshotAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan profile: %w", err)
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating profiles: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// LatestHandles maps each DID to the handle of its most recent profile snapshot
+// DIDs without a snapshot are left out of the map
+func LatestHandles(db *sql.DB, dids []string) (map[string]string, error) {
+	handles := make(map[string]string)
+	if len(dids) == 0 {
+		return handles, nil
+	}
+
+	// SQLite returns the handle from the row holding MAX(snapshot_at)
+	didClause, args := inClause("did", dids)
+	rows, err := db.Query("SELECT did, handle, MAX(snapshot_at) FROM profiles WHERE "+didClause+" GROUP BY did", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get handles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var did, handle string
+		var snapshotAt interface{}
+		if err := rows.Scan(&did, &handle, &snapshotAt); err != nil {
+			return nil, fmt.Errorf("failed to scan handle: %w", err)
+		}
+		handles[did] = handle
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating handles: %w", err)
+	}
+
+	return handles, nil
+}
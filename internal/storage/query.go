@@ -0,0 +1,445 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SearchOperators lists the filters understood by ParseSearchQuery, for help text
+var SearchOperators = []string{
+	"from:handle", "before:YYYY-MM-DD", "after:YYYY-MM-DD",
+	"has:media", "has:link", "is:reply", "is:quote",
+	"min_likes:N", "lang:xx", "#hashtag",
+}
+
+// QueryError reports a search query that cannot be parsed
+// Message is written for users and safe to show in the UI
+type QueryError struct {
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return "invalid search query: " + e.Message
+}
+
+// queryErrorf creates a QueryError with a formatted message
+func queryErrorf(format string, args ...interface{}) error {
+	return &QueryError{Message: fmt.Sprintf(format, args...)}
+}
+
+// SearchQuery is a parsed search string: full-text terms plus structured filters
+//
+// Plain words and "quoted phrases" must all match; OR between two terms matches
+// either one, a trailing * matches a prefix and a leading - excludes a term
+// Filters (from:, has:, is:, ...) narrow the results and can also be negated with -
+type SearchQuery struct {
+	groups   [][]ftsTerm // full-text groups that must all match; terms within a group are ORed
+	excluded []ftsTerm   // full-text terms that must not match
+
+	conditions []string
+	args       []interface{}
+}
+
+// ftsTerm is a word or phrase for the full-text index
+type ftsTerm struct {
+	text     string
+	prefix   bool // trailing *
+	optional bool // only narrows the search (hashtags are also matched with LIKE)
+}
+
+// phrase quotes the term as an FTS5 phrase for the given tokenizer, so punctuation is never
+// parsed as syntax. Returns "" if the term cannot be searched with this tokenizer
+func (t ftsTerm) phrase(tokenizer string) (string, error) {
+	if strings.IndexFunc(t.text, isSearchable) < 0 {
+		return "", nil
+	}
+	phrase := `"` + strings.ReplaceAll(t.text, `"`, `""`) + `"`
+
+	switch tokenizer {
+	case TokenizerTrigram:
+		// Trigrams match any part of a word, so * is not needed
+		if utf8.RuneCountInString(t.text) < 3 {
+			if t.optional {
+				return "", nil
+			}
+			return "", queryErrorf("partial-word search needs at least 3 characters per word, got %q", t.text)
+		}
+		return phrase, nil
+	case TokenizerPrefix:
+		return phrase + "*", nil
+	}
+
+	if t.prefix {
+		phrase += "*"
+	}
+	return phrase, nil
+}
+
+// Match returns the FTS5 MATCH expression for the required terms, or "" if there are none
+func (q *SearchQuery) Match(tokenizer string) (string, error) {
+	parts := make([]string, 0, len(q.groups))
+	for _, group := range q.groups {
+		var phrases []string
+		for _, term := range group {
+			phrase, err := term.phrase(tokenizer)
+			if err != nil {
+				return "", err
+			}
+			if phrase != "" {
+				phrases = append(phrases, phrase)
+			}
+		}
+		switch len(phrases) {
+		case 0:
+		case 1:
+			parts = append(parts, phrases[0])
+		default:
+			parts = append(parts, "("+strings.Join(phrases, " OR ")+")")
+		}
+	}
+	return strings.Join(parts, " AND "), nil
+}
+
+// Where returns the SQL conditions (on the posts alias p) and their arguments
+// Excluded terms become a NOT IN subquery against posts_fts
+func (q *SearchQuery) Where(tokenizer string) ([]string, []interface{}, error) {
+	conditions := append([]string{}, q.conditions...)
+	args := append([]interface{}{}, q.args...)
+
+	var phrases []string
+	for _, term := range q.excluded {
+		phrase, err := term.phrase(tokenizer)
+		if err != nil {
+			return nil, nil, err
+		}
+		if phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+	}
+	if len(phrases) > 0 {
+		conditions = append(conditions, "p.rowid NOT IN (SELECT rowid FROM posts_fts WHERE posts_fts MATCH ?)")
+		args = append(args, strings.Join(phrases, " OR "))
+	}
+
+	return conditions, args, nil
+}
+
+// where adds a condition with its arguments
+func (q *SearchQuery) where(condition string, args ...interface{}) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+// ParseSearchQuery parses a search string into full-text terms and filters
+// Errors are *QueryError with a message suitable for users
+func ParseSearchQuery(input string) (*SearchQuery, error) {
+	tokens, err := tokenizeQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, queryErrorf("search query is required")
+	}
+
+	q := &SearchQuery{}
+	var from, langs []string
+	pendingOr := false
+
+	for i, tok := range tokens {
+		if tok.text == "OR" && !tok.quoted {
+			if i == 0 || i == len(tokens)-1 || pendingOr || len(q.groups) == 0 ||
+				!tokens[i-1].isTerm() || !tokens[i+1].isTerm() || tokens[i-1].negated || tokens[i+1].negated {
+				return nil, queryErrorf("OR must be placed between two search words, as in: cats OR dogs")
+			}
+			pendingOr = true
+			continue
+		}
+		if tok.text == "AND" && !tok.quoted {
+			continue // terms are ANDed by default
+		}
+		if tok.text == "NOT" && !tok.quoted {
+			return nil, queryErrorf("put - in front of a word to exclude it, as in: cats -dogs")
+		}
+
+		if tok.isTerm() {
+			term := ftsTerm{text: tok.text, prefix: tok.prefix}
+			if strings.IndexFunc(term.text, isSearchable) < 0 {
+				pendingOr = false
+				continue
+			}
+			switch {
+			case tok.negated:
+				q.excluded = append(q.excluded, term)
+			case pendingOr:
+				last := len(q.groups) - 1
+				q.groups[last] = append(q.groups[last], term)
+			default:
+				q.groups = append(q.groups, []ftsTerm{term})
+			}
+			pendingOr = false
+			continue
+		}
+
+		if tok.hashtag {
+			tag := strings.TrimPrefix(tok.text, "#")
+			if tag == "" {
+				return nil, queryErrorf("# must be followed by a hashtag, as in: #photography")
+			}
+			like := "%#" + escapeLike(tag) + "%"
+			if tok.negated {
+				q.where(`p.text NOT LIKE ? ESCAPE '\'`, like)
+				continue
+			}
+			q.groups = append(q.groups, []ftsTerm{{text: tag, optional: true}})
+			q.where(`p.text LIKE ? ESCAPE '\'`, like)
+			continue
+		}
+
+		key, value := tok.key, tok.value
+		if value == "" {
+			return nil, queryErrorf("%s: needs a value, as in: %s", key, operatorExample(key))
+		}
+
+		switch key {
+		case "from":
+			if tok.negated {
+				return nil, queryErrorf("-from: is not supported; use from: to pick accounts")
+			}
+			from = append(from, strings.TrimPrefix(value, "@"))
+
+		case "before", "after":
+			day, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return nil, queryErrorf("%s: expects a date like 2024-01-31, got %q", key, value)
+			}
+			if tok.negated {
+				return nil, queryErrorf("-%s: is not supported; use %s: instead", key, oppositeDate(key))
+			}
+			if key == "before" {
+				q.where("p.created_at < ?", day.UTC())
+			} else {
+				q.where("p.created_at >= ?", day.UTC())
+			}
+
+		case "has":
+			var condition string
+			switch strings.ToLower(value) {
+			case "media":
+				condition = "p.has_media = 1"
+			case "link", "links":
+				condition = "(COALESCE(p.embed_type, '') = 'external' OR COALESCE(p.facets, '') LIKE '%app.bsky.richtext.facet#link%')"
+			default:
+				return nil, queryErrorf("has: supports media and link, got %q", value)
+			}
+			q.where(negate(condition, tok.negated))
+
+		case "is":
+			var condition string
+			switch strings.ToLower(value) {
+			case "reply":
+				condition = "p.is_reply = 1"
+			case "quote":
+				condition = isQuoteCondition
+			default:
+				return nil, queryErrorf("is: supports reply and quote, got %q", value)
+			}
+			q.where(negate(condition, tok.negated))
+
+		case "min_likes":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, queryErrorf("min_likes: expects a whole number, got %q", value)
+			}
+			if tok.negated {
+				q.where("p.like_count < ?", n)
+			} else {
+				q.where("p.like_count >= ?", n)
+			}
+
+		case "lang":
+			lang := strings.ToLower(value)
+			if tok.negated {
+				q.where("NOT EXISTS (SELECT 1 FROM json_each(p.langs) WHERE lower(value) = ? OR lower(value) LIKE ?)", lang, lang+"-%")
+			} else {
+				langs = append(langs, lang)
+			}
+
+		default:
+			return nil, queryErrorf("unknown filter %s:; supported filters are %s", key, strings.Join(SearchOperators, ", "))
+		}
+	}
+
+	// Several from: or lang: filters match any of them
+	if len(from) > 0 {
+		var parts []string
+		for _, actor := range from {
+			if strings.HasPrefix(actor, "did:") {
+				parts = append(parts, "p.did = ?")
+				q.args = append(q.args, actor)
+				continue
+			}
+			parts = append(parts, `p.did IN (
+				SELECT did FROM profiles WHERE lower(handle) = ?
+				UNION SELECT did FROM sessions WHERE lower(handle) = ?
+				UNION SELECT did FROM watched_accounts WHERE lower(handle) = ?)`)
+			handle := strings.ToLower(actor)
+			q.args = append(q.args, handle, handle, handle)
+		}
+		q.conditions = append(q.conditions, "("+strings.Join(parts, " OR ")+")")
+	}
+	if len(langs) > 0 {
+		var parts []string
+		for _, lang := range langs {
+			parts = append(parts, "lower(value) = ? OR lower(value) LIKE ?")
+			q.args = append(q.args, lang, lang+"-%")
+		}
+		q.conditions = append(q.conditions, "EXISTS (SELECT 1 FROM json_each(p.langs) WHERE "+strings.Join(parts, " OR ")+")")
+	}
+
+	if len(q.groups) == 0 && len(q.excluded) == 0 && len(q.conditions) == 0 {
+		return nil, queryErrorf("search query is required")
+	}
+
+	return q, nil
+}
+
+// queryToken is one whitespace-separated piece of a search string
+type queryToken struct {
+	text    string // term text, hashtag or raw token
+	key     string // filter name for key:value tokens
+	value   string // filter value
+	quoted  bool
+	negated bool
+	prefix  bool // term ends with *
+	hashtag bool
+}
+
+// isTerm reports whether the token is a full-text term (not a filter or hashtag)
+func (t queryToken) isTerm() bool {
+	return t.key == "" && !t.hashtag && !(t.text == "OR" && !t.quoted) && !(t.text == "AND" && !t.quoted)
+}
+
+// tokenizeQuery splits a search string on whitespace, keeping "quoted phrases" together
+func tokenizeQuery(input string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(strings.TrimSpace(input))
+
+	for i := 0; i < len(runes); {
+		if runes[i] == ' ' || runes[i] == '\t' || runes[i] == '\n' {
+			i++
+			continue
+		}
+
+		var tok queryToken
+		if runes[i] == '-' && i+1 < len(runes) && runes[i+1] != ' ' {
+			tok.negated = true
+			i++
+		}
+
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, queryErrorf("a quoted phrase is missing its closing quote")
+			}
+			tok.text = string(runes[i+1 : end])
+			tok.quoted = true
+			i = end + 1
+			if i < len(runes) && runes[i] == '*' {
+				tok.prefix = true
+				i++
+			}
+			tokens = append(tokens, tok)
+			continue
+		}
+
+		start := i
+		for i < len(runes) && runes[i] != ' ' && runes[i] != '\t' && runes[i] != '\n' {
+			if runes[i] == '"' {
+				return nil, queryErrorf("quotes must surround a whole phrase, as in: \"good morning\"")
+			}
+			i++
+		}
+		text := string(runes[start:i])
+
+		switch {
+		case strings.HasPrefix(text, "#"):
+			tok.hashtag = true
+			tok.text = text
+		case isFilterToken(text):
+			idx := strings.Index(text, ":")
+			tok.key = strings.ToLower(text[:idx])
+			tok.value = text[idx+1:]
+		default:
+			if strings.HasSuffix(text, "*") {
+				tok.prefix = true
+				text = strings.TrimRight(text, "*")
+			}
+			tok.text = text
+		}
+		tokens = append(tokens, tok)
+	}
+
+	return tokens, nil
+}
+
+// isFilterToken reports whether a token looks like key:value
+// URLs, times and words ending in a colon ("note:") are plain text unless the key is a known filter
+func isFilterToken(text string) bool {
+	idx := strings.Index(text, ":")
+	if idx <= 0 || strings.Contains(text, "://") {
+		return false
+	}
+	for _, r := range text[:idx] {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && r != '_' {
+			return false
+		}
+	}
+	return idx < len(text)-1 || searchFilters[strings.ToLower(text[:idx])]
+}
+
+// searchFilters is the set of filter names accepted by ParseSearchQuery
+var searchFilters = map[string]bool{
+	"from": true, "before": true, "after": true, "has": true, "is": true, "min_likes": true, "lang": true,
+}
+
+// isSearchable reports whether a rune is indexed by the FTS5 tokenizer
+func isSearchable(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127
+}
+
+// negate wraps a condition in NOT when negated is true
+func negate(condition string, negated bool) string {
+	if negated {
+		return "NOT (" + condition + ")"
+	}
+	return condition
+}
+
+// escapeLike escapes LIKE wildcards using \ as the escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// operatorExample returns an example for a filter name
+func operatorExample(key string) string {
+	for _, op := range SearchOperators {
+		if strings.HasPrefix(op, key+":") {
+			return op
+		}
+	}
+	return key + ":value"
+}
+
+// oppositeDate returns the other date filter
+func oppositeDate(key string) string {
+	if key == "before" {
+		return "after"
+	}
+	return "before"
+}
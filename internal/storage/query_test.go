@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+func TestParseSearchQuery_Match(t *testing.T) {
+	tests := []struct {
+		query    string
+		match    string
+		excluded string
+	}{
+		{"hello world", `"hello" AND "world"`, ""},
+		{`"good morning" coffee`, `"good morning" AND "coffee"`, ""},
+		{"cats OR dogs pets", `("cats" OR "dogs") AND "pets"`, ""},
+		{"photo* -draft", `"photo"*`, `"draft"`},
+		{"c++ (beta)", `"c++" AND "(beta)"`, ""},
+		{"note: release", `"note:" AND "release"`, ""},
+		{"https://example.com", `"https://example.com"`, ""},
+		{"#golang tips", `"golang" AND "tips"`, ""},
+		{"has:media", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery(%q) failed: %v", tt.query, err)
+			}
+			match, err := q.Match(TokenizerWords)
+			if err != nil {
+				t.Fatalf("Match failed: %v", err)
+			}
+			if match != tt.match {
+				t.Errorf("Match() = %q, want %q", match, tt.match)
+			}
+
+			conditions, args, err := q.Where(TokenizerWords)
+			if err != nil {
+				t.Fatalf("Where failed: %v", err)
+			}
+			excluded := ""
+			if n := len(conditions); n > 0 && strings.Contains(conditions[n-1], "NOT IN") {
+				excluded = args[len(args)-1].(string)
+			}
+			if excluded != tt.excluded {
+				t.Errorf("Excluded = %q, want %q", excluded, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestSearchQuery_Tokenizers(t *testing.T) {
+	tests := []struct {
+		query     string
+		tokenizer string
+		match     string
+		wantErr   bool
+	}{
+		{"photo", TokenizerWords, `"photo"`, false},
+		{"photo", TokenizerPrefix, `"photo"*`, false},
+		{"graph", TokenizerTrigram, `"graph"`, false},
+		{"photo*", TokenizerTrigram, `"photo"`, false},
+		{"go", TokenizerTrigram, "", true},
+		{"#go tips", TokenizerTrigram, `"tips"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tokenizer+"/"+tt.query, func(t *testing.T) {
+			q, err := ParseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseSearchQuery(%q) failed: %v", tt.query, err)
+			}
+			match, err := q.Match(tt.tokenizer)
+			if tt.wantErr {
+				var queryErr *QueryError
+				if !errors.As(err, &queryErr) {
+					t.Fatalf("Expected QueryError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Match failed: %v", err)
+			}
+			if match != tt.match {
+				t.Errorf("Match() = %q, want %q", match, tt.match)
+			}
+		})
+	}
+}
+
+func TestParseSearchQuery_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		`"unclosed phrase`,
+		"OR cats",
+		"cats OR",
+		"cats NOT dogs",
+		"before:yesterday",
+		"after:",
+		"has:video",
+		"is:repost",
+		"min_likes:many",
+		"form:alice",
+		"#",
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			_, err := ParseSearchQuery(query)
+			var queryErr *QueryError
+			if !errors.As(err, &queryErr) {
+				t.Fatalf("Expected QueryError for %q, got %v", query, err)
+			}
+			if queryErr.Message == "" {
+				t.Error("Expected a message")
+			}
+		})
+	}
+}
+
+func TestSearchPosts_Filters(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:searcher"
+	if err := SaveProfile(db, &models.Profile{DID: did, Handle: "searcher.test", SnapshotAt: time.Now()}); err != nil {
+		t.Fatalf("SaveProfile failed: %v", err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	linkFacet := json.RawMessage(`[{"features":[{"$type":"app.bsky.richtext.facet#link","uri":"https://example.com"}]}]`)
+	posts := []models.Post{
+		{URI: "at://" + did + "/app.bsky.feed.post/1", Text: "Morning coffee #coffee", CreatedAt: day(1), LikeCount: 10, Langs: []string{"en"}},
+		{URI: "at://" + did + "/app.bsky.feed.post/2", Text: "Café da manhã", CreatedAt: day(2), HasMedia: true, EmbedType: "images", Langs: []string{"pt-BR"}},
+		{URI: "at://" + did + "/app.bsky.feed.post/3", Text: "Replying about coffee", CreatedAt: day(3), IsReply: true, ReplyParent: "at://did:plc:other/app.bsky.feed.post/9"},
+		{URI: "at://" + did + "/app.bsky.feed.post/4", Text: "Read this coffee guide", CreatedAt: day(4), Facets: linkFacet, LikeCount: 3},
+		{URI: "at://" + did + "/app.bsky.feed.post/5", Text: "Quoting a friend", CreatedAt: day(5), EmbedType: "record"},
+	}
+	for i := range posts {
+		posts[i].CID = "cid"
+		posts[i].DID = did
+		posts[i].IndexedAt = posts[i].CreatedAt
+		if err := SavePost(db, &posts[i]); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string // rkeys, newest first
+	}{
+		{"coffee", []string{"4", "3", "1"}},
+		{"coffee -reply*", []string{"4", "1"}},
+		{"coffee is:reply", []string{"3"}},
+		{"coffee -is:reply", []string{"4", "1"}},
+		{"has:media", []string{"2"}},
+		{"has:link", []string{"4"}},
+		{"is:quote", []string{"5"}},
+		{"min_likes:5", []string{"1"}},
+		{"lang:pt", []string{"2"}},
+		{"lang:en lang:pt", []string{"2", "1"}},
+		{"#coffee", []string{"1"}},
+		{"after:2024-03-02 before:2024-03-04", []string{"3", "2"}},
+		{"from:searcher.test coffee OR quoting", []string{"5", "4", "3", "1"}},
+		{"from:@other.test coffee", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			result, err := SearchPosts(db, did, tt.query, 20, 0)
+			if err != nil {
+				t.Fatalf("SearchPosts(%q) failed: %v", tt.query, err)
+			}
+			if result.Total != len(tt.want) || len(result.Posts) != len(tt.want) {
+				t.Fatalf("Expected %d results, got %d (total %d)", len(tt.want), len(result.Posts), result.Total)
+			}
+			for i, rkey := range tt.want {
+				if want := "at://" + did + "/app.bsky.feed.post/" + rkey; result.Posts[i].URI != want {
+					t.Errorf("Result %d = %s, want %s", i, result.Posts[i].URI, want)
+				}
+			}
+		})
+	}
+
+	// Languages and facets round-trip through storage
+	post, err := GetPost(db, posts[1].URI)
+	if err != nil {
+		t.Fatalf("GetPost failed: %v", err)
+	}
+	if len(post.Langs) != 1 || post.Langs[0] != "pt-BR" {
+		t.Errorf("Unexpected langs: %v", post.Langs)
+	}
+
+	// Invalid queries return a QueryError instead of a SQL error
+	_, err = SearchPosts(db, did, `"coffee`, 20, 0)
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Errorf("Expected QueryError, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// SaveSearch stores a search query for an account, or renames it if it is already saved
+// Only posts archived from now on count as new matches
+// Invalid queries return a *QueryError
+func SaveSearch(db *sql.DB, accountID, name, query string) (*models.SavedSearch, error) {
+	query = strings.TrimSpace(query)
+	if strings.HasPrefix(query, "at://") {
+		return nil, queryErrorf("post links cannot be saved as a search")
+	}
+	// No accounts: checks the query without matching anything
+	if _, err := buildSearch(db, nil, query); err != nil {
+		return nil, err
+	}
+
+	maxRowID, err := MaxPostRowID(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err = db.QueryRow(`
+		INSERT INTO saved_searches (account_id, name, query, checked_rowid, seen_rowid)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(account_id, query) DO UPDATE SET name = excluded.name
+		RETURNING id
+	`, accountID, strings.TrimSpace(name), query, maxRowID, maxRowID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save search: %w", err)
+	}
+
+	return GetSavedSearch(db, accountID, id)
+}
+
+// GetSavedSearch retrieves one of an account's saved searches
+func GetSavedSearch(db *sql.DB, accountID string, id int64) (*models.SavedSearch, error) {
+	rows, err := db.Query(savedSearchQuery+" WHERE account_id = ? AND id = ?", accountID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+	defer rows.Close()
+
+	searches, err := scanSavedSearches(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(searches) == 0 {
+		return nil, fmt.Errorf("saved search not found: %d", id)
+	}
+
+	return &searches[0], nil
+}
+
+// ListSavedSearches retrieves an account's saved searches, those with new matches first
+func ListSavedSearches(db *sql.DB, accountID string) ([]models.SavedSearch, error) {
+	rows, err := db.Query(savedSearchQuery+" WHERE account_id = ? ORDER BY new_matches > 0 DESC, COALESCE(NULLIF(name, ''), query)", accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSavedSearches(rows)
+}
+
+// ListAllSavedSearches retrieves every saved search, for checking after archive runs
+func ListAllSavedSearches(db *sql.DB) ([]models.SavedSearch, error) {
+	rows, err := db.Query(savedSearchQuery + " ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSavedSearches(rows)
+}
+
+// DeleteSavedSearch removes one of an account's saved searches
+func DeleteSavedSearch(db *sql.DB, accountID string, id int64) error {
+	result, err := db.Exec("DELETE FROM saved_searches WHERE account_id = ? AND id = ?", accountID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("saved search not found: %d", id)
+	}
+
+	return nil
+}
+
+// RecordSavedSearchCheck adds new matches found up to checkedRowID
+func RecordSavedSearchCheck(db *sql.DB, id, checkedRowID int64, newMatches int) error {
+	_, err := db.Exec(`
+		UPDATE saved_searches SET
+			checked_rowid = ?,
+			new_matches = new_matches + ?,
+			last_checked_at = ?
+		WHERE id = ?
+	`, checkedRowID, newMatches, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to record saved search check: %w", err)
+	}
+
+	return nil
+}
+
+// MarkSavedSearchSeen clears the new match count once the user has viewed the matches
+func MarkSavedSearchSeen(db *sql.DB, accountID string, id int64) error {
+	_, err := db.Exec(`
+		UPDATE saved_searches SET seen_rowid = checked_rowid, new_matches = 0
+		WHERE account_id = ? AND id = ?
+	`, accountID, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark saved search seen: %w", err)
+	}
+
+	return nil
+}
+
+// NewSavedSearchMatches returns posts from dids matching query that were archived after
+// afterRowID, up to and including throughRowID, with their total count (newest first)
+func NewSavedSearchMatches(db *sql.DB, dids []string, query string, afterRowID, throughRowID int64, limit int) ([]models.Post, int, error) {
+	search, err := buildSearch(db, dids, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	where := search.where + " AND p.rowid > ? AND p.rowid <= ?"
+	args := append(search.args, afterRowID, throughRowID)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM "+search.from+" WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, searchError("failed to count new matches", err)
+	}
+	if total == 0 {
+		return []models.Post{}, 0, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT `+postColumns+`
+		FROM `+search.from+`
+		WHERE `+where+`
+		ORDER BY p.created_at DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, 0, searchError("failed to find new matches", err)
+	}
+	defer rows.Close()
+
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return posts, total, nil
+}
+
+// MaxPostRowID returns the rowid of the most recently archived post
+func MaxPostRowID(db *sql.DB) (int64, error) {
+	var rowID int64
+	if err := db.QueryRow("SELECT COALESCE(MAX(rowid), 0) FROM posts").Scan(&rowID); err != nil {
+		return 0, fmt.Errorf("failed to get latest post: %w", err)
+	}
+
+	return rowID, nil
+}
+
+// AccountDIDs returns the DIDs an account can browse: its linked identities and the watchlist
+func AccountDIDs(db *sql.DB, accountID string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT did FROM sessions WHERE COALESCE(account_id, id) = ?
+		UNION
+		SELECT did FROM watched_accounts WHERE did IS NOT NULL AND did != ''
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account DIDs: %w", err)
+	}
+	defer rows.Close()
+
+	var dids []string
+	for rows.Next() {
+		var did string
+		if err := rows.Scan(&did); err != nil {
+			return nil, fmt.Errorf("failed to scan DID: %w", err)
+		}
+		dids = append(dids, did)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating DIDs: %w", err)
+	}
+
+	return dids, nil
+}
+
+// savedSearchQuery selects saved searches for scanSavedSearches
+const savedSearchQuery = `
+	SELECT id, account_id, name, query, checked_rowid, seen_rowid, new_matches, last_checked_at, created_at
+	FROM saved_searches
+`
+
+// scanSavedSearches reads rows produced by savedSearchQuery
+func scanSavedSearches(rows *sql.Rows) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	for rows.Next() {
+		var search models.SavedSearch
+		var name sql.NullString
+		var lastChecked sql.NullTime
+
+		err := rows.Scan(
+			&search.ID, &search.AccountID, &name, &search.Query, &search.CheckedRowID,
+			&search.SeenRowID, &search.NewMatches, &lastChecked, &search.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+
+		search.Name = name.String
+		if lastChecked.Valid {
+			search.LastCheckedAt = &lastChecked.Time
+		}
+
+		searches = append(searches, search)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating saved searches: %w", err)
+	}
+
+	return searches, nil
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+func TestSavedSearches(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:saver"
+	savePost := func(rkey, text string) {
+		t.Helper()
+		post := &models.Post{URI: "at://" + did + "/app.bsky.feed.post/" + rkey, CID: "cid", DID: did, Text: text, CreatedAt: time.Now(), IndexedAt: time.Now()}
+		if err := SavePost(db, post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	savePost("1", "Release notes for v1")
+
+	search, err := SaveSearch(db, "account-1", "Releases", "release")
+	if err != nil {
+		t.Fatalf("SaveSearch failed: %v", err)
+	}
+
+	// Saving the same query again renames it
+	again, err := SaveSearch(db, "account-1", "Release watch", " release ")
+	if err != nil {
+		t.Fatalf("SaveSearch failed: %v", err)
+	}
+	if again.ID != search.ID || again.Name != "Release watch" {
+		t.Errorf("Expected the search to be renamed, got %+v", again)
+	}
+
+	var queryErr *QueryError
+	if _, err := SaveSearch(db, "account-1", "", `"unclosed`); !errors.As(err, &queryErr) {
+		t.Errorf("Expected QueryError, got %v", err)
+	}
+
+	// Posts archived before saving are not new
+	savePost("2", "Another release today")
+	savePost("3", "Unrelated")
+	savePost("1", "Release notes for v1 (edited)") // refreshes keep their rowid
+
+	maxRowID, err := MaxPostRowID(db)
+	if err != nil {
+		t.Fatalf("MaxPostRowID failed: %v", err)
+	}
+	posts, total, err := NewSavedSearchMatches(db, []string{did}, again.Query, again.CheckedRowID, maxRowID, 10)
+	if err != nil {
+		t.Fatalf("NewSavedSearchMatches failed: %v", err)
+	}
+	if total != 1 || len(posts) != 1 || posts[0].URI != "at://"+did+"/app.bsky.feed.post/2" {
+		t.Fatalf("Expected one new match, got %d: %+v", total, posts)
+	}
+
+	// Other accounts' posts never match
+	if _, total, _ := NewSavedSearchMatches(db, []string{"did:plc:other"}, again.Query, again.CheckedRowID, maxRowID, 10); total != 0 {
+		t.Errorf("Expected no matches for another DID, got %d", total)
+	}
+
+	if err := RecordSavedSearchCheck(db, search.ID, maxRowID, total); err != nil {
+		t.Fatalf("RecordSavedSearchCheck failed: %v", err)
+	}
+	searches, err := ListSavedSearches(db, "account-1")
+	if err != nil {
+		t.Fatalf("ListSavedSearches failed: %v", err)
+	}
+	if len(searches) != 1 || searches[0].NewMatches != 1 || searches[0].CheckedRowID != maxRowID || searches[0].LastCheckedAt == nil {
+		t.Fatalf("Unexpected saved searches: %+v", searches)
+	}
+
+	if err := MarkSavedSearchSeen(db, "account-1", search.ID); err != nil {
+		t.Fatalf("MarkSavedSearchSeen failed: %v", err)
+	}
+	seen, err := GetSavedSearch(db, "account-1", search.ID)
+	if err != nil {
+		t.Fatalf("GetSavedSearch failed: %v", err)
+	}
+	if seen.NewMatches != 0 || seen.SeenRowID != maxRowID {
+		t.Errorf("Expected the search to be seen, got %+v", seen)
+	}
+
+	// Searches are private to their account
+	if _, err := GetSavedSearch(db, "account-2", search.ID); err == nil {
+		t.Error("Expected another account's search to be not found")
+	}
+	if err := DeleteSavedSearch(db, "account-2", search.ID); err == nil {
+		t.Error("Expected deleting another account's search to fail")
+	}
+	if err := DeleteSavedSearch(db, "account-1", search.ID); err != nil {
+		t.Fatalf("DeleteSavedSearch failed: %v", err)
+	}
+}
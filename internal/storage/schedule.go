@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// CreateScheduleRun records a scheduled sync attempt
+func CreateScheduleRun(db *sql.DB, run *models.ScheduleRun) error {
+	if run.ID == "" {
+		return fmt.Errorf("schedule run id is required")
+	}
+	if run.DID == "" {
+		return fmt.Errorf("did is required")
+	}
+
+	query := `
+		INSERT INTO schedule_runs (id, did, scheduled_for, started_at, status, operation_id, message)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := db.Exec(query,
+		run.ID, run.DID, run.ScheduledFor, run.StartedAt, run.Status, run.OperationID, run.Message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create schedule run: %w", err)
+	}
+
+	return nil
+}
+
+// ListScheduleRuns retrieves the most recent scheduled sync attempts for a user
+func ListScheduleRuns(db *sql.DB, did string, limit int) ([]models.ScheduleRun, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := `
+		SELECT id, did, scheduled_for, started_at, status, operation_id, message
+		FROM schedule_runs
+		WHERE did = ?
+		ORDER BY started_at DESC
+		LIMIT ?
+	`
+
+	rows, err := db.Query(query, did, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedule runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.ScheduleRun
+	for rows.Next() {
+		var run models.ScheduleRun
+		var operationID, message sql.NullString
+
+		err := rows.Scan(
+			&run.ID, &run.DID, &run.ScheduledFor, &run.StartedAt, &run.Status, &operationID, &message,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule run: %w", err)
+		}
+
+		run.OperationID = operationID.String
+		run.Message = message.String
+		runs = append(runs, run)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schedule runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// SetNextScheduledRun stores when the next scheduled sync for a user is due
+func SetNextScheduledRun(db *sql.DB, did string, next time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO schedule_state (did, next_run_at, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(did) DO UPDATE SET
+			next_run_at = excluded.next_run_at,
+			updated_at = excluded.updated_at
+	`, did, next, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to set next scheduled run: %w", err)
+	}
+
+	return nil
+}
+
+// GetNextScheduledRun retrieves when the next scheduled sync for a user is due
+// Returns nil if no run is planned
+func GetNextScheduledRun(db *sql.DB, did string) (*time.Time, error) {
+	var next time.Time
+	err := db.QueryRow("SELECT next_run_at FROM schedule_state WHERE did = ?", did).Scan(&next)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next scheduled run: %w", err)
+	}
+
+	return &next, nil
+}
+
+// ClearScheduleState removes all planned runs (used when scheduling is disabled)
+// Run history is kept
+func ClearScheduleState(db *sql.DB) error {
+	if _, err := db.Exec("DELETE FROM schedule_state"); err != nil {
+		return fmt.Errorf("failed to clear schedule state: %w", err)
+	}
+
+	return nil
+}
+
+// GetScheduleStatus retrieves the next planned run and recent history for a user
+func GetScheduleStatus(db *sql.DB, did string) (*models.ScheduleStatus, error) {
+	next, err := GetNextScheduledRun(db, did)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := ListScheduleRuns(db, did, 5)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.ScheduleStatus{
+		DID:        did,
+		NextRunAt:  next,
+		RecentRuns: runs,
+	}
+	if len(runs) > 0 {
+		status.LastRun = &runs[0]
+	}
+
+	return status, nil
+}
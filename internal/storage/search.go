@@ -2,18 +2,56 @@ package storage
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"html"
 	"strings"
+	"time"
 
 	"github.com/shindakun/bskyarchive/internal/models"
 )
 
+// Markers wrapped around matches by highlight() and snippet(); converted to <mark> by markMatches
+const (
+	matchStart = "\x02"
+	matchEnd   = "\x03"
+)
+
+// snippetTokens is the approximate number of words in a snippet
+const snippetTokens = 24
+
+// searchOrders maps each SearchSort to its ORDER BY clause
+// Relevance adds bm25() in front when the query has words, otherwise it is newest first
+var searchOrders = map[models.SearchSort]string{
+	models.SearchSortNewest:    "p.created_at DESC",
+	models.SearchSortOldest:    "p.created_at ASC",
+	models.SearchSortLikes:     "p.like_count DESC, p.created_at DESC",
+	models.SearchSortReposts:   "p.repost_count DESC, p.created_at DESC",
+	models.SearchSortRelevance: "p.created_at DESC",
+}
+
 // SearchPosts performs full-text search using FTS5, or direct URI lookup for AT protocol URIs
+// Results are limited to one account, newest first; did is required
 func SearchPosts(db *sql.DB, did, query string, limit, offset int) (*models.SearchPostsResponse, error) {
+	if did == "" {
+		return nil, fmt.Errorf("did is required")
+	}
+
+	return SearchPostsForDIDs(db, []string{did}, query, models.SearchSortNewest, limit, offset)
+}
+
+// SearchPostsForDIDs performs full-text search across several accounts (combined view)
+// AT URI lookups only return posts that belong to one of the accounts
+// Words match the post text, image alt text, link titles and descriptions, and quoted posts
+// Relevance sorting uses bm25() and falls back to newest first when the query has no words
+func SearchPostsForDIDs(db *sql.DB, dids []string, query string, sort models.SearchSort, limit, offset int) (*models.SearchPostsResponse, error) {
+	start := time.Now()
+
 	if query == "" {
 		return nil, fmt.Errorf("search query is required")
 	}
+	if len(dids) == 0 {
+		return nil, fmt.Errorf("at least one did is required")
+	}
 
 	if limit <= 0 || limit > 100 {
 		limit = 20 // Default page size
@@ -21,95 +59,172 @@ func SearchPosts(db *sql.DB, did, query string, limit, offset int) (*models.Sear
 	if offset < 0 {
 		offset = 0
 	}
+	orderBy, ok := searchOrders[sort]
+	if !ok {
+		sort, orderBy = models.SearchSortNewest, searchOrders[models.SearchSortNewest]
+	}
 
-	// Check if query is an AT protocol URI
 	if strings.HasPrefix(query, "at://") {
-		// Direct URI lookup
+		response := &models.SearchPostsResponse{Posts: []models.Post{}, Query: query, Sort: sort}
 		post, err := GetPost(db, query)
-		if err != nil {
-			// Post not found, return empty results
-			return &models.SearchPostsResponse{
-				Posts:   []models.Post{},
-				Query:   query,
-				Total:   0,
-				Elapsed: "",
-			}, nil
+		if err == nil {
+			for _, did := range dids {
+				if post.DID == did {
+					response.Posts = []models.Post{*post}
+					response.Total = 1
+					break
+				}
+			}
 		}
+		response.Elapsed = elapsed(start)
+		return response, nil
+	}
 
-		// Return single post result
-		return &models.SearchPostsResponse{
-			Posts:   []models.Post{*post},
-			Query:   query,
-			Total:   1,
-			Elapsed: "",
-		}, nil
-	}
-
-	// Get total count of matching posts
-	countQuery := `
-		SELECT COUNT(*)
-		FROM posts_fts
-		WHERE posts_fts MATCH ?
-		AND uri IN (SELECT uri FROM posts WHERE did = ?)
-	`
-	var total int64
-	err := db.QueryRow(countQuery, query, did).Scan(&total)
+	search, err := buildSearch(db, dids, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count search results: %w", err)
+		return nil, err
+	}
+	from, where, args := search.from, search.where, search.args
+
+	columns := postColumns + ", '', ''"
+	if search.match != "" {
+		columns = postColumns + fmt.Sprintf(`,
+			highlight(posts_fts, 1, char(2), char(3)),
+			snippet(posts_fts, -1, char(2), char(3), '…', %d)`, snippetTokens)
+		if sort == models.SearchSortRelevance {
+			orderBy = searchRank + ", " + orderBy
+		}
+	}
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM "+from+" WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, searchError("failed to count search results", err)
 	}
 
-	// Search posts using FTS5
 	searchQuery := `
-		SELECT p.uri, p.cid, p.did, p.text, p.created_at, p.indexed_at,
-			   p.has_media, p.like_count, p.repost_count, p.reply_count,
-			   p.is_reply, p.reply_parent, p.embed_type, p.embed_data, p.labels, p.archived_at
-		FROM posts_fts
-		JOIN posts p ON posts_fts.uri = p.uri
-		WHERE posts_fts MATCH ?
-		AND p.did = ?
-		ORDER BY p.created_at DESC
+		SELECT ` + columns + `
+		FROM ` + from + `
+		WHERE ` + where + `
+		ORDER BY ` + orderBy + `
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := db.Query(searchQuery, query, did, limit, offset)
+	rows, err := db.Query(searchQuery, append(args, limit, offset)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search posts: %w", err)
+		return nil, searchError("failed to search posts", err)
 	}
 	defer rows.Close()
 
-	var posts []models.Post
+	response := &models.SearchPostsResponse{
+		Posts: []models.Post{},
+		Query: query,
+		Sort:  sort,
+		Total: int(total),
+	}
+	if search.match != "" {
+		response.Highlights = make(map[string]string)
+		response.Snippets = make(map[string]string)
+	}
+
 	for rows.Next() {
-		var post models.Post
-		var embedData, labels []byte
-
-		err := rows.Scan(
-			&post.URI, &post.CID, &post.DID, &post.Text, &post.CreatedAt, &post.IndexedAt,
-			&post.HasMedia, &post.LikeCount, &post.RepostCount, &post.ReplyCount,
-			&post.IsReply, &post.ReplyParent, &post.EmbedType, &embedData, &labels, &post.ArchivedAt,
-		)
+		var highlight, snippet string
+		post, err := scanPost(extraScanner{rows, []interface{}{&highlight, &snippet}})
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan search result: %w", err)
+			return nil, fmt.Errorf("failed to scan post: %w", err)
 		}
+		response.Posts = append(response.Posts, *post)
 
-		// Deserialize JSON fields
-		if len(embedData) > 0 {
-			post.EmbedData = json.RawMessage(embedData)
+		// The snippet comes from the best matching field, which may be alt text,
+		// a link title or a quoted post; the highlight is always the post text
+		if strings.Contains(highlight, matchStart) {
+			response.Highlights[post.URI] = markMatches(highlight)
 		}
-		if len(labels) > 0 {
-			post.Labels = json.RawMessage(labels)
+		if strings.Contains(snippet, matchStart) {
+			response.Snippets[post.URI] = markMatches(snippet)
 		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating posts: %w", err)
+	}
+
+	response.Elapsed = elapsed(start)
+	return response, nil
+}
 
-		posts = append(posts, post)
+// searchSQL is a parsed search query as SQL against posts p
+// Queries with search words join posts_fts and set match
+type searchSQL struct {
+	match string
+	from  string
+	where string
+	args  []interface{}
+}
+
+// buildSearch parses query and limits it to posts from dids
+func buildSearch(db *sql.DB, dids []string, query string) (*searchSQL, error) {
+	parsed, err := ParseSearchQuery(query)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating search results: %w", err)
+	tokenizer, err := searchTokenizer(db)
+	if err != nil {
+		return nil, err
 	}
+	match, err := parsed.Match(tokenizer)
+	if err != nil {
+		return nil, err
+	}
+
+	didClause, didArgs := inClause("p.did", dids)
+	conditions, args, err := parsed.Where(tokenizer)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append([]string{didClause}, conditions...)
+	args = append(didArgs, args...)
 
-	return &models.SearchPostsResponse{
-		Posts:   posts,
-		Query:   query,
-		Total:   int(total),
-		Elapsed: "", // Set by caller if needed
+	// Required terms join the FTS index; filter-only queries read posts directly
+	from := "posts p"
+	if match != "" {
+		from = "posts_fts JOIN posts p ON p.rowid = posts_fts.rowid"
+		conditions = append([]string{"posts_fts MATCH ?"}, conditions...)
+		args = append([]interface{}{match}, args...)
+	}
+
+	return &searchSQL{
+		match: match,
+		from:  from,
+		where: strings.Join(conditions, " AND "),
+		args:  args,
 	}, nil
 }
+
+// extraScanner scans additional columns selected after postColumns
+type extraScanner struct {
+	rowScanner
+	extra []interface{}
+}
+
+func (s extraScanner) Scan(dest ...interface{}) error {
+	return s.rowScanner.Scan(append(dest, s.extra...)...)
+}
+
+// markMatches escapes text for HTML and wraps matches in <mark>
+func markMatches(text string) string {
+	text = html.EscapeString(text)
+	return strings.NewReplacer(matchStart, "<mark>", matchEnd, "</mark>").Replace(text)
+}
+
+// elapsed formats the time since start for SearchPostsResponse.Elapsed
+func elapsed(start time.Time) string {
+	return time.Since(start).Round(time.Microsecond).String()
+}
+
+// searchError reports FTS5 query errors as a QueryError instead of a raw SQL error
+func searchError(message string, err error) error {
+	if strings.Contains(err.Error(), "fts5") {
+		return queryErrorf("the search could not be run; try removing special characters")
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// ListActiveSessions retrieves all login sessions that have not expired
+// Background jobs use these to find the bskyoauth session ID for each account
+func ListActiveSessions(db *sql.DB) ([]models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE expires_at > ?
+		ORDER BY created_at ASC
+	`
+
+	rows, err := db.Query(query, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSessions(rows)
+}
+
+// ListAccountSessions retrieves every identity linked to a local account, oldest first
+func ListAccountSessions(db *sql.DB, accountID string) ([]models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE COALESCE(account_id, id) = ?
+		ORDER BY created_at ASC
+	`
+
+	rows, err := db.Query(query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account sessions: %w", err)
+	}
+	defer rows.Close()
+
+	return scanSessions(rows)
+}
+
+// GetAccountSession retrieves the session for one identity linked to a local account
+func GetAccountSession(db *sql.DB, accountID, did string) (*models.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE COALESCE(account_id, id) = ? AND did = ?
+	`
+
+	rows, err := db.Query(query, accountID, did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account session: %w", err)
+	}
+	defer rows.Close()
+
+	sessions, err := scanSessions(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, fmt.Errorf("linked account not found: %s", did)
+	}
+
+	return &sessions[0], nil
+}
+
+// GetSessionAccountID returns the local account an identity belongs to
+// Returns an empty string if the identity has never signed in
+func GetSessionAccountID(db *sql.DB, did string) (string, error) {
+	var accountID string
+	err := db.QueryRow("SELECT COALESCE(account_id, id) FROM sessions WHERE did = ?", did).Scan(&accountID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get session account: %w", err)
+	}
+
+	return accountID, nil
+}
+
+// DeleteAccountSession removes one identity from a local account
+// The account's feed tokens for the identity's archive are revoked with it
+func DeleteAccountSession(db *sql.DB, accountID, did string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM sessions WHERE COALESCE(account_id, id) = ? AND did = ?", accountID, did)
+	if err != nil {
+		return fmt.Errorf("failed to delete account session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("linked account not found: %s", did)
+	}
+
+	if _, err := tx.Exec("DELETE FROM feed_tokens WHERE account_id = ? AND did = ?", accountID, did); err != nil {
+		return fmt.Errorf("failed to delete feed tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// sessionColumns lists the columns read by scanSessions
+const sessionColumns = `id, COALESCE(account_id, id), did, handle, COALESCE(display_name, ''), access_token, COALESCE(refresh_token, ''), expires_at, created_at`
+
+// scanSessions reads rows selected with sessionColumns
+func scanSessions(rows *sql.Rows) ([]models.Session, error) {
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(
+			&session.ID,
+			&session.AccountID,
+			&session.DID,
+			&session.Handle,
+			&session.DisplayName,
+			&session.AccessToken,
+			&session.RefreshToken,
+			&session.ExpiresAt,
+			&session.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
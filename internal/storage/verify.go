@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// VerifyDatabase runs SQLite and full-text index integrity checks
+// Returns a description of each problem found; an empty slice means the database is healthy
+func VerifyDatabase(db *sql.DB) ([]string, error) {
+	var problems []string
+
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run integrity check: %w", err)
+	}
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan integrity check: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, "database: "+result)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating integrity check: %w", err)
+	}
+
+	// FTS5 reports index/content mismatches as an error from the integrity-check command
+	if _, err := db.Exec("INSERT INTO posts_fts(posts_fts) VALUES('integrity-check')"); err != nil {
+		problems = append(problems, fmt.Sprintf("search index: %v", err))
+	}
+
+	var orphaned int
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM media
+		WHERE post_uri NOT IN (SELECT uri FROM posts)
+	`).Scan(&orphaned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count orphaned media: %w", err)
+	}
+	if orphaned > 0 {
+		problems = append(problems, fmt.Sprintf("media: %d record(s) reference posts that are not archived", orphaned))
+	}
+
+	return problems, nil
+}
+
+// VerifyMedia checks that every media record has a file on disk with the expected size
+// If checkHashes is true, each file is re-hashed and compared to its content hash
+func VerifyMedia(db *sql.DB, checkHashes bool) ([]string, error) {
+	rows, err := db.Query("SELECT hash, file_path, size_bytes FROM media ORDER BY hash")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var hash, filePath string
+		var size int64
+		if err := rows.Scan(&hash, &filePath, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+
+		info, err := os.Stat(filePath)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("media %s: missing file %s", hash, filePath))
+			continue
+		}
+		if size > 0 && info.Size() != size {
+			problems = append(problems, fmt.Sprintf("media %s: size %d does not match recorded %d", hash, info.Size(), size))
+			continue
+		}
+
+		if checkHashes {
+			sum, err := hashFile(filePath)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("media %s: %v", hash, err))
+				continue
+			}
+			if !strings.EqualFold(sum, hash) {
+				problems = append(problems, fmt.Sprintf("media %s: content hash is %s", hash, sum))
+			}
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating media: %w", err)
+	}
+
+	return problems, nil
+}
+
+// hashFile returns the hex SHA-256 of a file's contents
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// SyncWatchedAccounts makes the watched_accounts table match the configured actors
+// New actors are added and removed ones are dropped; archived posts are kept either way
+func SyncWatchedAccounts(db *sql.DB, actors []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, actor := range actors {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO watched_accounts (actor) VALUES (?)", actor); err != nil {
+			return fmt.Errorf("failed to add watched account: %w", err)
+		}
+	}
+
+	if len(actors) == 0 {
+		_, err = tx.Exec("DELETE FROM watched_accounts")
+	} else {
+		actorClause, args := inClause("actor", actors)
+		_, err = tx.Exec("DELETE FROM watched_accounts WHERE NOT "+actorClause, args...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove watched accounts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit watched accounts: %w", err)
+	}
+
+	return nil
+}
+
+// GetWatchedAccount retrieves a watched account by its configured actor
+func GetWatchedAccount(db *sql.DB, actor string) (*models.WatchedAccount, error) {
+	rows, err := db.Query(watchedAccountQuery+" WHERE w.actor = ?", actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watched account: %w", err)
+	}
+	defer rows.Close()
+
+	accounts, err := scanWatchedAccounts(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("watched account not found: %s", actor)
+	}
+
+	return &accounts[0], nil
+}
+
+// ListWatchedAccounts retrieves all watched accounts with their archived post counts
+func ListWatchedAccounts(db *sql.DB) ([]models.WatchedAccount, error) {
+	rows, err := db.Query(watchedAccountQuery + " ORDER BY COALESCE(w.handle, w.actor)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watched accounts: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWatchedAccounts(rows)
+}
+
+// IsWatchedDID checks whether a DID belongs to a watched account
+func IsWatchedDID(db *sql.DB, did string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM watched_accounts WHERE did = ?)", did).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check watched account: %w", err)
+	}
+
+	return exists, nil
+}
+
+// SetWatchedAccountNextRun stores when the next run for a watched account is due
+func SetWatchedAccountNextRun(db *sql.DB, actor string, next time.Time) error {
+	_, err := db.Exec("UPDATE watched_accounts SET next_run_at = ? WHERE actor = ?", next, actor)
+	if err != nil {
+		return fmt.Errorf("failed to set next watchlist run: %w", err)
+	}
+
+	return nil
+}
+
+// RecordWatchedAccountRun stores the outcome of a watchlist run
+// did and handle are only updated when non-empty, so a failed lookup keeps the last known identity
+func RecordWatchedAccountRun(db *sql.DB, account *models.WatchedAccount) error {
+	_, err := db.Exec(`
+		UPDATE watched_accounts SET
+			did = COALESCE(NULLIF(?, ''), did),
+			handle = COALESCE(NULLIF(?, ''), handle),
+			last_run_at = ?,
+			last_status = ?,
+			last_error = ?,
+			last_post_count = ?
+		WHERE actor = ?
+	`, account.DID, account.Handle, account.LastRunAt, account.LastStatus, account.LastError, account.LastPostCount, account.Actor)
+	if err != nil {
+		return fmt.Errorf("failed to record watchlist run: %w", err)
+	}
+
+	return nil
+}
+
+// watchedAccountQuery selects watched accounts with the number of posts archived for each DID
+const watchedAccountQuery = `
+	SELECT w.actor, w.did, w.handle, w.next_run_at, w.last_run_at, w.last_status, w.last_error,
+		   COALESCE(w.last_post_count, 0),
+		   (SELECT COUNT(*) FROM posts p WHERE p.did = w.did)
+	FROM watched_accounts w
+`
+
+// scanWatchedAccounts reads rows produced by watchedAccountQuery
+func scanWatchedAccounts(rows *sql.Rows) ([]models.WatchedAccount, error) {
+	var accounts []models.WatchedAccount
+	for rows.Next() {
+		var account models.WatchedAccount
+		var did, handle, status, lastError sql.NullString
+		var nextRun, lastRun sql.NullTime
+
+		err := rows.Scan(
+			&account.Actor, &did, &handle, &nextRun, &lastRun, &status, &lastError,
+			&account.LastPostCount, &account.PostCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watched account: %w", err)
+		}
+
+		account.DID = did.String
+		account.Handle = handle.String
+		account.LastStatus = models.WatchRunStatus(status.String)
+		account.LastError = lastError.String
+		if nextRun.Valid {
+			account.NextRunAt = &nextRun.Time
+		}
+		if lastRun.Valid {
+			account.LastRunAt = &lastRun.Time
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating watched accounts: %w", err)
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+func TestSyncWatchedAccounts(t *testing.T) {
+	db, err := InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Close()
+
+	if err := SyncWatchedAccounts(db, []string{"a.test", "b.test"}); err != nil {
+		t.Fatalf("SyncWatchedAccounts failed: %v", err)
+	}
+
+	// Run state survives a re-sync
+	now := time.Now()
+	if err := RecordWatchedAccountRun(db, &models.WatchedAccount{
+		Actor:         "a.test",
+		DID:           "did:plc:a",
+		Handle:        "a.test",
+		LastRunAt:     &now,
+		LastStatus:    models.WatchRunStatusCompleted,
+		LastPostCount: 5,
+	}); err != nil {
+		t.Fatalf("RecordWatchedAccountRun failed: %v", err)
+	}
+
+	if err := SyncWatchedAccounts(db, []string{"a.test", "c.test"}); err != nil {
+		t.Fatalf("SyncWatchedAccounts failed: %v", err)
+	}
+
+	accounts, err := ListWatchedAccounts(db)
+	if err != nil {
+		t.Fatalf("ListWatchedAccounts failed: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0].Actor != "a.test" || accounts[1].Actor != "c.test" {
+		t.Fatalf("Unexpected accounts: %+v", accounts)
+	}
+	if accounts[0].DID != "did:plc:a" || accounts[0].LastPostCount != 5 || !accounts[0].HasRun() {
+		t.Errorf("Run state was not kept: %+v", accounts[0])
+	}
+	if accounts[1].HasRun() || accounts[1].DisplayName() != "c.test" {
+		t.Errorf("Unexpected state for new account: %+v", accounts[1])
+	}
+
+	// A failed run keeps the last known identity
+	if err := RecordWatchedAccountRun(db, &models.WatchedAccount{
+		Actor:      "a.test",
+		LastRunAt:  &now,
+		LastStatus: models.WatchRunStatusFailed,
+		LastError:  "timeout",
+	}); err != nil {
+		t.Fatalf("RecordWatchedAccountRun failed: %v", err)
+	}
+	account, err := GetWatchedAccount(db, "a.test")
+	if err != nil {
+		t.Fatalf("GetWatchedAccount failed: %v", err)
+	}
+	if account.DID != "did:plc:a" || account.LastError != "timeout" {
+		t.Errorf("Unexpected account after failure: %+v", account)
+	}
+
+	if err := SyncWatchedAccounts(db, nil); err != nil {
+		t.Fatalf("SyncWatchedAccounts failed: %v", err)
+	}
+	if accounts, _ := ListWatchedAccounts(db); len(accounts) != 0 {
+		t.Errorf("Expected watchlist to be empty, got %+v", accounts)
+	}
+}
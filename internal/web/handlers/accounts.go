@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/shindakun/bskyarchive/internal/auth"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// Accounts renders the linked accounts page
+func (h *Handlers) Accounts(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+		return
+	}
+
+	errMessage := ""
+	if r.URL.Query().Get("error") == "already_linked" {
+		errMessage = alreadyLinkedMessage
+	}
+	h.renderAccounts(w, r, session, errMessage, "")
+}
+
+// renderAccounts renders the linked accounts page with an optional error or message
+func (h *Handlers) renderAccounts(w http.ResponseWriter, r *http.Request, session *models.Session, errMessage, message string) {
+	linked, err := storage.ListAccountSessions(h.db, session.AccountID)
+	if err != nil {
+		h.logger.Printf("Error listing linked accounts: %v", err)
+	}
+
+	// Archive status per identity
+	statuses := make(map[string]*models.ArchiveStatus)
+	for _, account := range linked {
+		status, err := storage.GetArchiveStatus(h.db, account.DID)
+		if err != nil {
+			h.logger.Printf("Error fetching archive status for %s: %v", account.DID, err)
+			continue
+		}
+		statuses[account.DID] = status
+	}
+
+	data := TemplateData{
+		Session:         session,
+		Error:           errMessage,
+		Message:         message,
+		LinkedAccounts:  linked,
+		AccountStatuses: statuses,
+	}
+
+	if err := h.renderTemplate(w, r, "accounts", data); err != nil {
+		h.logger.Printf("Error rendering accounts template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// SwitchAccount makes another linked identity the active one
+func (h *Handlers) SwitchAccount(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	target, err := h.sessionManager.SwitchAccount(w, r, r.FormValue("did"))
+	if err != nil {
+		h.logger.Printf("Failed to switch account for %s: %v", session.DID, err)
+		h.renderAccounts(w, r, session, "Could not switch accounts. Sign in to that account again to re-link it.", "")
+		return
+	}
+
+	h.logger.Printf("Switched active account from %s to %s", session.DID, target.DID)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// LinkAccount links another Bluesky identity to the signed-in account
+// With an app password the identity is linked immediately; otherwise the OAuth flow
+// is started and the callback links the identity
+func (h *Handlers) LinkAccount(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	handle := r.FormValue("handle")
+	password := r.FormValue("app_password")
+	if handle == "" {
+		h.renderAccounts(w, r, session, "Bluesky handle is required", "")
+		return
+	}
+
+	if err := h.sessionManager.BeginLink(w, r); err != nil {
+		h.logger.Printf("Failed to begin account link: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if password != "" {
+		if h.appPasswords == nil {
+			h.renderAccounts(w, r, session, "App password login is not available.", "")
+			return
+		}
+
+		login, err := h.appPasswords.Login(r.Context(), handle, password)
+		if err != nil {
+			h.logger.Printf("App password link failed for handle %s: %v", handle, err)
+			h.renderAccounts(w, r, session, "Sign-in failed. Check the handle and app password and try again.", "")
+			return
+		}
+
+		// SaveSession sees the pending link and makes the new identity active
+		if err := h.sessionManager.SaveSession(w, r, login.DID, login.Handle, login.Handle, login.SessionID, ""); err != nil {
+			if errors.Is(err, auth.ErrLoginNotAllowed) {
+				h.discardAppPassword(login.SessionID)
+				h.renderAccounts(w, r, session, notAllowedMessage, "")
+				return
+			}
+			if errors.Is(err, auth.ErrAlreadyLinked) {
+				h.discardAppPassword(login.SessionID)
+				h.renderAccounts(w, r, session, alreadyLinkedMessage, "")
+				return
+			}
+			h.logger.Printf("Failed to save linked session: %v", err)
+			http.Error(w, "Failed to save session", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, "/accounts", http.StatusSeeOther)
+		return
+	}
+
+	authURL, err := h.oauthManager.StartOAuthFlow(r.Context(), handle)
+	if err != nil {
+		h.logger.Printf("Failed to start OAuth flow for handle %s: %v", handle, err)
+		h.renderAccounts(w, r, session, "Failed to connect to Bluesky. Please try again.", "")
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+// UnlinkAccount signs out one linked identity
+func (h *Handlers) UnlinkAccount(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	did := r.FormValue("did")
+	if err := h.sessionManager.UnlinkAccount(r, did); err != nil {
+		h.logger.Printf("Failed to unlink %s: %v", did, err)
+		h.renderAccounts(w, r, session, "Could not unlink that account. Switch to another account before unlinking the active one.", "")
+		return
+	}
+
+	h.renderAccounts(w, r, session, "", "Account unlinked. Its archived posts are kept.")
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/shindakun/bskyarchive/internal/auth"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// Admin renders instance-wide statistics and the user list (admin role only)
+func (h *Handlers) Admin(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+		return
+	}
+
+	h.renderAdmin(w, r, session, "", "")
+}
+
+// renderAdmin renders the admin page with an optional error or message
+func (h *Handlers) renderAdmin(w http.ResponseWriter, r *http.Request, session *models.Session, errMessage, message string) {
+	stats, err := storage.GetInstanceStats(h.db)
+	if err != nil {
+		h.logger.Printf("Error fetching instance stats: %v", err)
+	}
+
+	users, err := storage.ListUsers(h.db)
+	if err != nil {
+		h.logger.Printf("Error listing users: %v", err)
+	}
+
+	data := TemplateData{
+		Session:       session,
+		Error:         errMessage,
+		Message:       message,
+		InstanceStats: stats,
+		Users:         users,
+	}
+
+	if err := h.renderTemplate(w, r, "admin", data); err != nil {
+		h.logger.Printf("Error rendering admin template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// RevokeUser signs out an identity and deletes its stored credentials (admin role only)
+// Admins cannot revoke their own session; log out instead
+func (h *Handlers) RevokeUser(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	did := r.FormValue("did")
+	if did == "" || did == session.DID {
+		h.renderAdmin(w, r, session, "Choose another user to sign out.", "")
+		return
+	}
+
+	if err := storage.RevokeUser(h.db, did); err != nil {
+		h.logger.Printf("Failed to revoke user %s: %v", did, err)
+		h.renderAdmin(w, r, session, "Could not sign out that user.", "")
+		return
+	}
+
+	h.logger.Printf("Admin %s signed out user %s", session.DID, did)
+	h.renderAdmin(w, r, session, "", "User signed out. Their archived posts were kept.")
+}
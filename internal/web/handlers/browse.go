@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// browseParams are the Browse query parameters kept by pagination and the date navigator
+var browseParams = []string{"q", "view", "did", "sort", "media", "replies", "quotes", "from", "to"}
+
+// browseSorts lists the Browse sort orders; relevance only applies to searches
+var browseSorts = []string{"newest", "oldest", "likes", "reposts", "relevance"}
+
+// browseYear is one row of the Browse date navigator
+type browseYear struct {
+	Year   int
+	Months []browseMonth
+}
+
+// browseMonth links to the posts of one month
+type browseMonth struct {
+	Label  string
+	URL    string
+	Active bool
+}
+
+// browseURL returns a /browse link keeping the current parameters, with set applied
+// on top; an empty value in set removes that parameter
+func browseURL(current url.Values, set map[string]string) string {
+	values := url.Values{}
+	for _, key := range browseParams {
+		if value := current.Get(key); value != "" {
+			values.Set(key, value)
+		}
+	}
+	for key, value := range set {
+		if value == "" {
+			values.Del(key)
+		} else {
+			values.Set(key, value)
+		}
+	}
+
+	if len(values) == 0 {
+		return "/browse"
+	}
+	return "/browse?" + values.Encode()
+}
+
+// browseSort returns the sort parameter, or newest if it is missing or unknown
+func browseSort(value string) string {
+	for _, sort := range browseSorts {
+		if value == sort {
+			return sort
+		}
+	}
+	return "newest"
+}
+
+// parseBrowseFilter reads the Browse filter parameters
+// from and to are inclusive dates (YYYY-MM-DD); an invalid date returns a message for the user
+func parseBrowseFilter(query url.Values) (models.PostFilter, string) {
+	filter := models.PostFilter{
+		MediaOnly:  query.Get("media") == "1",
+		QuotesOnly: query.Get("quotes") == "1",
+	}
+
+	switch replies := models.ReplyFilter(query.Get("replies")); replies {
+	case models.ReplyFilterOnly, models.ReplyFilterOriginals:
+		filter.Replies = replies
+	}
+
+	if from := query.Get("from"); from != "" {
+		day, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return filter, "Dates must look like 2024-01-31."
+		}
+		filter.Since = day
+	}
+	if to := query.Get("to"); to != "" {
+		day, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return filter, "Dates must look like 2024-01-31."
+		}
+		filter.Until = day.AddDate(0, 0, 1)
+	}
+
+	return filter, ""
+}
+
+// searchFilterQuery appends the filter to a search query as search operators
+func searchFilterQuery(query string, filter models.PostFilter) string {
+	operators := []string{query}
+	if filter.MediaOnly {
+		operators = append(operators, "has:media")
+	}
+	switch filter.Replies {
+	case models.ReplyFilterOnly:
+		operators = append(operators, "is:reply")
+	case models.ReplyFilterOriginals:
+		operators = append(operators, "-is:reply")
+	}
+	if filter.QuotesOnly {
+		operators = append(operators, "is:quote")
+	}
+	if !filter.Since.IsZero() {
+		operators = append(operators, "after:"+filter.Since.Format("2006-01-02"))
+	}
+	if !filter.Until.IsZero() {
+		operators = append(operators, "before:"+filter.Until.Format("2006-01-02"))
+	}
+	return strings.Join(operators, " ")
+}
+
+// browseYears builds the date navigator from the oldest to the newest post, newest year first
+func browseYears(oldest, newest time.Time, current url.Values) []browseYear {
+	if oldest.IsZero() || newest.IsZero() {
+		return nil
+	}
+	oldest, newest = oldest.UTC(), newest.UTC()
+
+	var years []browseYear
+	for year := newest.Year(); year >= oldest.Year(); year-- {
+		row := browseYear{Year: year}
+		for month := time.January; month <= time.December; month++ {
+			start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+			if start.After(newest) || start.AddDate(0, 1, 0).Before(oldest) {
+				continue
+			}
+
+			from := start.Format("2006-01-02")
+			to := start.AddDate(0, 1, -1).Format("2006-01-02")
+			row.Months = append(row.Months, browseMonth{
+				Label:  month.String()[:3],
+				URL:    browseURL(current, map[string]string{"from": from, "to": to}),
+				Active: current.Get("from") == from && current.Get("to") == to,
+			})
+		}
+		years = append(years, row)
+	}
+
+	return years
+}
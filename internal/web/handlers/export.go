@@ -55,10 +55,17 @@ func (h *Handlers) ExportPage(w http.ResponseWriter, r *http.Request) {
 		// Continue rendering page even if exports can't be loaded
 	}
 
+	templates, err := exporter.ListExportTemplates(h.exportTemplatesDir)
+	if err != nil {
+		h.logger.Printf("Error listing export templates: %v", err)
+	}
+
 	data := TemplateData{
-		Session: session,
-		Status:  status,
-		Exports: exports, // Pass exports to template
+		Session:         session,
+		Status:          status,
+		Exports:         exports, // Pass exports to template
+		ExportTemplates: templates,
+		CSVColumns:      models.CSVColumns,
 	}
 
 	if err := h.renderTemplate(w, r, "export", data); err != nil {
@@ -83,14 +90,24 @@ func (h *Handlers) StartExport(w http.ResponseWriter, r *http.Request) {
 
 	format := r.FormValue("format")
 	includeMedia := r.FormValue("include_media") == "true"
+	companions := r.FormValue("companions") == "true"
+	markdownByDay := r.FormValue("markdown_by_day") == "true"
+	excludeReplies := r.FormValue("exclude_replies") == "true"
+	csvColumns := r.Form["csv_columns"]
+	csvDelimiter := r.FormValue("csv_delimiter")
+	csvNoBOM := r.FormValue("csv_no_bom") == "true"
 	startDateStr := r.FormValue("start_date")
 	endDateStr := r.FormValue("end_date")
 
+	// Custom templates are chosen as "template:NAME"
+	templateName := ""
+	if name, ok := strings.CutPrefix(format, "template:"); ok {
+		format, templateName = string(models.ExportFormatTemplate), name
+	}
+
 	// Validate format
-	var exportFormat models.ExportFormat
-	if format == "csv" {
-		exportFormat = models.ExportFormatCSV
-	} else {
+	exportFormat := models.ExportFormat(format)
+	if !exportFormat.Valid() {
 		exportFormat = models.ExportFormatJSON // default
 	}
 
@@ -153,11 +170,24 @@ func (h *Handlers) StartExport(w http.ResponseWriter, r *http.Request) {
 
 	// Create export options
 	opts := models.ExportOptions{
-		Format:       exportFormat,
-		OutputDir:    "./exports",
-		IncludeMedia: includeMedia,
-		DID:          session.DID,
-		DateRange:    dateRange,
+		Format:         exportFormat,
+		OutputDir:      "./exports",
+		IncludeMedia:   includeMedia,
+		Companions:     companions && exportFormat == models.ExportFormatJSONL,
+		MarkdownByDay:  markdownByDay && exportFormat == models.ExportFormatMarkdown,
+		ExcludeReplies: excludeReplies && exportFormat == models.ExportFormatFeed,
+		CSVNoBOM:       csvNoBOM && exportFormat == models.ExportFormatCSV,
+		Template:       templateName,
+		TemplatesDir:   h.exportTemplatesDir,
+		BaseURL:        requestBaseURL(r),
+		DID:            session.DID,
+		DateRange:      dateRange,
+	}
+
+	// The form always sends CSV columns and a delimiter; only CSV exports use them
+	if exportFormat == models.ExportFormatCSV {
+		opts.CSVColumns = csvColumns
+		opts.CSVDelimiter = csvDelimiter
 	}
 
 	// Validate options
@@ -165,6 +195,10 @@ func (h *Handlers) StartExport(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Invalid export options: %v", err), http.StatusBadRequest)
 		return
 	}
+	if exportFormat == models.ExportFormatTemplate && !h.hasExportTemplate(templateName) {
+		http.Error(w, fmt.Sprintf("Unknown export template: %s", templateName), http.StatusBadRequest)
+		return
+	}
 
 	// Check for concurrent exports (prevent multiple exports running at once)
 	exportJobsMu.RLock()
@@ -317,6 +351,21 @@ func (h *Handlers) ExportProgress(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// hasExportTemplate reports whether name is one of the custom export templates
+func (h *Handlers) hasExportTemplate(name string) bool {
+	templates, err := exporter.ListExportTemplates(h.exportTemplatesDir)
+	if err != nil {
+		h.logger.Printf("Error listing export templates: %v", err)
+		return false
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // sanitizeID converts an export ID to a valid CSS selector ID
 // by replacing special characters with hyphens
 func sanitizeID(id string) string {
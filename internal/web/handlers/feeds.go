@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shindakun/bskyarchive/internal/auth"
+	"github.com/shindakun/bskyarchive/internal/exporter"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+const (
+	defaultFeedLimit = 50  // Posts in a feed unless ?limit= asks for more
+	maxFeedLimit     = 500 // Most posts a feed request can return
+)
+
+// feedContentTypes maps each feed format to its media type
+var feedContentTypes = map[models.FeedFormat]string{
+	models.FeedFormatAtom: "application/atom+xml; charset=utf-8",
+	models.FeedFormatRSS:  "application/rss+xml; charset=utf-8",
+}
+
+// Feed serves an archive as an Atom or RSS feed to a signed-in user
+// Query parameters: did (default: the current identity), replies=false, since and
+// until (YYYY-MM-DD, inclusive) and limit
+func (h *Handlers) Feed(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	did := r.URL.Query().Get("did")
+	if did == "" {
+		did = session.DID
+	}
+	if !containsDID(h.visibleDIDs(session), did) {
+		http.Error(w, "Feed not found", http.StatusNotFound)
+		return
+	}
+
+	opts, err := feedOptions(r, did, r.URL.Query().Get("replies") != "false")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	base := requestBaseURL(r)
+	opts.SelfURL = base + r.URL.RequestURI()
+	opts.MediaURL = func(m models.Media) string { return base + "/media/" + m.Hash }
+
+	h.writeFeed(w, opts)
+}
+
+// TokenFeed serves the feed of a feed token to readers that cannot sign in
+// The token decides the archive and whether replies are included; since, until and
+// limit work as for Feed
+func (h *Handlers) TokenFeed(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.feedTokenParam(w, r)
+	if !ok {
+		return
+	}
+
+	opts, err := feedOptions(r, token.DID, token.IncludeReplies)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	base := requestBaseURL(r)
+	opts.SelfURL = base + r.URL.RequestURI()
+	opts.MediaURL = func(m models.Media) string { return base + "/feeds/" + token.Token + "/media/" + m.Hash }
+
+	if err := storage.TouchFeedToken(h.db, token.Token); err != nil {
+		h.logger.Printf("Warning: %v", err)
+	}
+	h.writeFeed(w, opts)
+}
+
+// TokenFeedMedia serves a media file of a feed token's archive
+func (h *Handlers) TokenFeedMedia(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.feedTokenParam(w, r)
+	if !ok {
+		return
+	}
+
+	hash := chi.URLParam(r, "hash")
+	media, err := storage.GetMediaByHash(h.db, hash)
+	if err != nil {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	// Only media of the token's archive is served
+	visible, err := storage.MediaVisibleTo(h.db, hash, []string{token.DID})
+	if err != nil {
+		h.logger.Printf("Error checking media access: %v", err)
+	}
+	if !visible {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	h.serveMediaFile(w, r, media)
+}
+
+// Feeds lists the account's feed tokens with their feed addresses
+func (h *Handlers) Feeds(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+		return
+	}
+
+	h.renderFeeds(w, r, session, "", "")
+}
+
+// CreateFeedToken creates a feed token for one of the archives the user can read
+func (h *Handlers) CreateFeedToken(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	did := r.FormValue("did")
+	if !containsDID(h.visibleDIDs(session), did) {
+		h.renderFeeds(w, r, session, "Choose one of your archives for the feed.", "")
+		return
+	}
+
+	token, err := storage.CreateFeedToken(h.db, session.AccountID, did, r.FormValue("name"), r.FormValue("include_replies") == "true")
+	if err != nil {
+		h.logger.Printf("Failed to create feed token for %s: %v", session.DID, err)
+		h.renderFeeds(w, r, session, "Could not create the feed.", "")
+		return
+	}
+
+	h.logger.Printf("Feed token created for %s by %s", token.DID, session.DID)
+	h.renderFeeds(w, r, session, "", "Feed created. Anyone with its address can read it, so share it only with your feed reader.")
+}
+
+// DeleteFeedToken revokes a feed token; readers using it stop receiving the feed
+func (h *Handlers) DeleteFeedToken(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := storage.DeleteFeedToken(h.db, session.AccountID, chi.URLParam(r, "token")); err != nil {
+		h.logger.Printf("Failed to delete feed token: %v", err)
+		h.renderFeeds(w, r, session, "Could not revoke the feed.", "")
+		return
+	}
+
+	h.renderFeeds(w, r, session, "", "Feed revoked.")
+}
+
+// renderFeeds renders the feeds page with an optional error or message
+func (h *Handlers) renderFeeds(w http.ResponseWriter, r *http.Request, session *models.Session, errMessage, message string) {
+	tokens, err := storage.ListFeedTokens(h.db, session.AccountID)
+	if err != nil {
+		h.logger.Printf("Error listing feed tokens: %v", err)
+	}
+
+	// Archives without a profile snapshot are listed by DID
+	dids := h.visibleDIDs(session)
+	handles, err := storage.LatestHandles(h.db, dids)
+	if err != nil {
+		h.logger.Printf("Warning: failed to fetch handles: %v", err)
+		handles = map[string]string{}
+	}
+	for _, did := range dids {
+		if handles[did] == "" {
+			handles[did] = did
+		}
+	}
+
+	data := TemplateData{
+		Session:    session,
+		Error:      errMessage,
+		Message:    message,
+		FeedTokens: tokens,
+		Profiles:   handles,
+		BaseURL:    requestBaseURL(r),
+	}
+
+	if err := h.renderTemplate(w, r, "feeds", data); err != nil {
+		h.logger.Printf("Error rendering feeds template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// writeFeed renders a feed and sends it; feeds are capped at maxFeedLimit posts, so
+// they are rendered in memory and a failure can still return an error status
+func (h *Handlers) writeFeed(w http.ResponseWriter, opts exporter.FeedOptions) {
+	var buf bytes.Buffer
+	if err := exporter.WriteFeed(&buf, h.db, opts); err != nil {
+		h.logger.Printf("Error writing %s feed for %s: %v", opts.Format, opts.DID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", feedContentTypes[opts.Format])
+	w.Write(buf.Bytes())
+}
+
+// feedTokenParam loads the feed token named by the {token} URL parameter
+// A token only works while its account can still read the token's archive
+func (h *Handlers) feedTokenParam(w http.ResponseWriter, r *http.Request) (*models.FeedToken, bool) {
+	token, err := storage.GetFeedToken(h.db, chi.URLParam(r, "token"))
+	if err != nil || !h.feedTokenReadable(token) {
+		http.Error(w, "Feed not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return token, true
+}
+
+// feedTokenReadable reports whether a token's account may still read its archive
+// Feed readers have no cookie session, so the access policy is checked here: only
+// identities it still allows count, and an account without one reads nothing
+func (h *Handlers) feedTokenReadable(token *models.FeedToken) bool {
+	linked, err := storage.ListAccountSessions(h.db, token.AccountID)
+	if err != nil {
+		h.logger.Printf("Error fetching linked accounts: %v", err)
+		return false
+	}
+
+	var dids []string
+	for _, account := range linked {
+		if h.sessionManager.CanLogin(account.DID, account.Handle) {
+			dids = append(dids, account.DID)
+		}
+	}
+	if len(dids) == 0 {
+		return false
+	}
+
+	return containsDID(append(dids, h.watchedDIDs()...), token.DID)
+}
+
+// feedOptions reads the format URL parameter and the since, until and limit query
+// parameters of a feed request
+func feedOptions(r *http.Request, did string, includeReplies bool) (exporter.FeedOptions, error) {
+	opts := exporter.FeedOptions{
+		Format: models.FeedFormat(chi.URLParam(r, "format")),
+		DID:    did,
+		Limit:  defaultFeedLimit,
+	}
+	if !includeReplies {
+		opts.Filter.Replies = models.ReplyFilterOriginals
+	}
+
+	query := r.URL.Query()
+	if since := query.Get("since"); since != "" {
+		date, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			return opts, fmt.Errorf("invalid since date: use YYYY-MM-DD")
+		}
+		opts.Filter.Since = date
+	}
+	if until := query.Get("until"); until != "" {
+		date, err := time.Parse("2006-01-02", until)
+		if err != nil {
+			return opts, fmt.Errorf("invalid until date: use YYYY-MM-DD")
+		}
+		// Include the whole end day
+		opts.Filter.Until = date.AddDate(0, 0, 1)
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxFeedLimit {
+			return opts, fmt.Errorf("limit must be between 1 and %d", maxFeedLimit)
+		}
+		opts.Limit = n
+	}
+
+	return opts, nil
+}
+
+// requestBaseURL is the scheme and host a request was made to, for absolute feed links
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
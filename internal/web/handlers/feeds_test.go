@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shindakun/bskyarchive/internal/auth"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// TestTokenFeed verifies feeds opened with a feed token
+func TestTokenFeed(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	did := "did:plc:reader"
+	created := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	for _, post := range []models.Post{
+		{URI: "at://" + did + "/app.bsky.feed.post/1", Text: "Original post", CreatedAt: created},
+		{URI: "at://" + did + "/app.bsky.feed.post/2", Text: "Reply post", CreatedAt: created.Add(time.Hour), IsReply: true},
+		{URI: "at://" + did + "/app.bsky.feed.post/3", Text: "Later post", CreatedAt: created.AddDate(0, 0, 5)},
+		{URI: "at://did:plc:other/app.bsky.feed.post/1", DID: "did:plc:other", Text: "Someone else", CreatedAt: created, HasMedia: true},
+	} {
+		post.CID = "cid"
+		if post.DID == "" {
+			post.DID = did
+		}
+		post.IndexedAt = post.CreatedAt
+		if err := storage.SavePost(db, &post); err != nil {
+			t.Fatalf("SavePost failed: %v", err)
+		}
+	}
+	otherMedia := &models.Media{Hash: strings.Repeat("f", 64), PostURI: "at://did:plc:other/app.bsky.feed.post/1",
+		MimeType: "image/jpeg", FilePath: "media/ff/other.jpg", CreatedAt: created}
+	if err := storage.SaveMedia(db, otherMedia); err != nil {
+		t.Fatalf("SaveMedia failed: %v", err)
+	}
+
+	// The token's account reads the archive through its linked session
+	if _, err := db.Exec(`INSERT INTO sessions (id, account_id, did, handle, access_token, refresh_token, expires_at) VALUES (?, ?, ?, ?, ?, '', ?)`,
+		"account-1", "account-1", did, "reader.test", "oauth-reader", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+
+	token, err := storage.CreateFeedToken(db, "account-1", did, "", false)
+	if err != nil {
+		t.Fatalf("CreateFeedToken failed: %v", err)
+	}
+
+	h := &Handlers{db: db, logger: log.Default()}
+	r := chi.NewRouter()
+	r.Get("/feeds/{token}/{format:atom|rss}", h.TokenFeed)
+	r.Get("/feeds/{token}/media/{hash}", h.TokenFeedMedia)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://archive.example"+path, nil))
+		return rec
+	}
+
+	rec := get("/feeds/" + token.Token + "/rss?until=2025-02-02")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/rss+xml; charset=utf-8" {
+		t.Fatalf("Expected an RSS feed, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Original post") || strings.Contains(body, "Reply post") || strings.Contains(body, "Later post") {
+		t.Errorf("Feed should hold the original post before the end date:\n%s", body)
+	}
+	if !strings.Contains(body, `href="http://archive.example/feeds/`+token.Token+`/rss?until=2025-02-02"`) {
+		t.Errorf("Feed should link to itself:\n%s", body)
+	}
+
+	if rec := get("/feeds/" + token.Token + "/atom"); !strings.Contains(rec.Body.String(), "Later post") {
+		t.Errorf("Atom feed without filters should hold the latest post: %d", rec.Code)
+	}
+	if rec := get("/feeds/" + token.Token + "/atom?limit=0"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid limit, got %d", rec.Code)
+	}
+	if rec := get("/feeds/unknown/atom"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown token, got %d", rec.Code)
+	}
+	// Tokens for archives their account cannot read are refused
+	unreadable, err := storage.CreateFeedToken(db, "account-1", "did:plc:other", "", false)
+	if err != nil {
+		t.Fatalf("CreateFeedToken failed: %v", err)
+	}
+	if rec := get("/feeds/" + unreadable.Token + "/atom"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an archive the account cannot read, got %d", rec.Code)
+	}
+	// Media of other archives is not served with the token
+	if rec := get("/feeds/" + token.Token + "/media/" + otherMedia.Hash); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another archive's media, got %d", rec.Code)
+	}
+
+	used, err := storage.GetFeedToken(db, token.Token)
+	if err != nil || used.LastUsedAt == nil {
+		t.Errorf("Fetching the feed should record its use: %v", err)
+	}
+}
+
+// TestTokenFeed_Unlinked verifies a feed token stops working once its archive is unlinked
+func TestTokenFeed_Unlinked(t *testing.T) {
+	// Templates are found relative to the repository root
+	t.Chdir("../../..")
+
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	sm := auth.InitSessions("test-secret-that-is-at-least-32-chars", 3600, false, http.SameSiteLaxMode, db)
+	withCookies := func(rec *httptest.ResponseRecorder, req *http.Request) *http.Request {
+		for _, cookie := range rec.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+	get := func() *http.Request { return httptest.NewRequest(http.MethodGet, "/", nil) }
+
+	// Sign in as alice, link bob and switch back to alice
+	rec := httptest.NewRecorder()
+	if err := sm.SaveSession(rec, get(), "did:plc:alice", "alice.test", "Alice", "oauth-alice", ""); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	req := withCookies(rec, get())
+	rec = httptest.NewRecorder()
+	if err := sm.BeginLink(rec, req); err != nil {
+		t.Fatalf("BeginLink failed: %v", err)
+	}
+	req = withCookies(rec, get())
+	rec = httptest.NewRecorder()
+	if err := sm.SaveSession(rec, req, "did:plc:bob", "bob.test", "Bob", "oauth-bob", ""); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	req = withCookies(rec, get())
+	rec = httptest.NewRecorder()
+	alice, err := sm.SwitchAccount(rec, req, "did:plc:alice")
+	if err != nil {
+		t.Fatalf("SwitchAccount failed: %v", err)
+	}
+
+	token, err := storage.CreateFeedToken(db, alice.AccountID, "did:plc:bob", "", false)
+	if err != nil {
+		t.Fatalf("CreateFeedToken failed: %v", err)
+	}
+
+	h := &Handlers{db: db, sessionManager: sm, logger: log.Default()}
+	r := chi.NewRouter()
+	r.Get("/feeds/{token}/{format:atom|rss}", h.TokenFeed)
+
+	feed := func() int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feeds/"+token.Token+"/atom", nil))
+		return rec.Code
+	}
+	if code := feed(); code != http.StatusOK {
+		t.Fatalf("Expected the linked archive's feed, got %d", code)
+	}
+
+	// Unlink bob from alice's account
+	unlink := withCookies(rec, httptest.NewRequest(http.MethodPost, "/accounts/unlink", strings.NewReader(url.Values{"did": {"did:plc:bob"}}.Encode())))
+	unlink.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	unlink = unlink.WithContext(auth.SetSessionInContext(unlink.Context(), alice))
+	rec = httptest.NewRecorder()
+	h.UnlinkAccount(rec, unlink)
+	if !strings.Contains(rec.Body.String(), "Account unlinked") {
+		t.Fatalf("Expected bob to be unlinked, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+
+	if code := feed(); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unlinked archive's feed, got %d", code)
+	}
+	if tokens, err := storage.ListFeedTokens(db, alice.AccountID); err != nil || len(tokens) != 0 {
+		t.Errorf("Expected unlinking to revoke the archive's feed tokens, got %d (err %v)", len(tokens), err)
+	}
+}
+
+// TestTokenFeed_AccessPolicy verifies feed tokens follow the access policy, which
+// feed readers never meet at sign-in
+func TestTokenFeed_AccessPolicy(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO sessions (id, account_id, did, handle, access_token, refresh_token, expires_at) VALUES (?, ?, ?, ?, ?, '', ?)`,
+		"account-1", "account-1", "did:plc:alice", "alice.test", "oauth-alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+	if err := storage.SyncWatchedAccounts(db, []string{"watched.test"}); err != nil {
+		t.Fatalf("SyncWatchedAccounts failed: %v", err)
+	}
+	if err := storage.RecordWatchedAccountRun(db, &models.WatchedAccount{Actor: "watched.test", DID: "did:plc:watched", LastStatus: models.WatchRunStatusCompleted}); err != nil {
+		t.Fatalf("RecordWatchedAccountRun failed: %v", err)
+	}
+
+	own, err := storage.CreateFeedToken(db, "account-1", "did:plc:alice", "", false)
+	if err != nil {
+		t.Fatalf("CreateFeedToken failed: %v", err)
+	}
+	// An account whose sessions are all gone
+	orphan, err := storage.CreateFeedToken(db, "account-2", "did:plc:watched", "", false)
+	if err != nil {
+		t.Fatalf("CreateFeedToken failed: %v", err)
+	}
+
+	sm := auth.InitSessions("test-secret-that-is-at-least-32-chars", 3600, false, http.SameSiteLaxMode, db)
+	sm.SetAccessPolicy(auth.NewAccessPolicy(nil, []string{"alice.test"}))
+	h := &Handlers{db: db, sessionManager: sm, logger: log.Default()}
+	r := chi.NewRouter()
+	r.Get("/feeds/{token}/{format:atom|rss}", h.TokenFeed)
+
+	feed := func(token string) int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feeds/"+token+"/atom", nil))
+		return rec.Code
+	}
+	if code := feed(own.Token); code != http.StatusOK {
+		t.Fatalf("Expected the allowed account's feed, got %d", code)
+	}
+	if code := feed(orphan.Token); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a token whose account has no sessions, got %d", code)
+	}
+
+	// Removing alice from the allowlist stops its tokens without a sign-in
+	sm.SetAccessPolicy(auth.NewAccessPolicy(nil, []string{"someone-else.test"}))
+	if code := feed(own.Token); code != http.StatusNotFound {
+		t.Errorf("Expected 404 once the account is off the allowlist, got %d", code)
+	}
+}
+
+// TestTokenFeed_Revoked verifies revoking a user ends its tokens, watched archives included
+func TestTokenFeed_Revoked(t *testing.T) {
+	db, err := storage.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO sessions (id, account_id, did, handle, access_token, refresh_token, expires_at) VALUES (?, ?, ?, ?, ?, '', ?)`,
+		"account-1", "account-1", "did:plc:alice", "alice.test", "oauth-alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+	if err := storage.SyncWatchedAccounts(db, []string{"watched.test"}); err != nil {
+		t.Fatalf("SyncWatchedAccounts failed: %v", err)
+	}
+	if err := storage.RecordWatchedAccountRun(db, &models.WatchedAccount{Actor: "watched.test", DID: "did:plc:watched", LastStatus: models.WatchRunStatusCompleted}); err != nil {
+		t.Fatalf("RecordWatchedAccountRun failed: %v", err)
+	}
+
+	token, err := storage.CreateFeedToken(db, "account-1", "did:plc:watched", "", false)
+	if err != nil {
+		t.Fatalf("CreateFeedToken failed: %v", err)
+	}
+
+	h := &Handlers{db: db, logger: log.Default()}
+	r := chi.NewRouter()
+	r.Get("/feeds/{token}/{format:atom|rss}", h.TokenFeed)
+
+	feed := func() int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feeds/"+token.Token+"/atom", nil))
+		return rec.Code
+	}
+	if code := feed(); code != http.StatusOK {
+		t.Fatalf("Expected the watched archive's feed, got %d", code)
+	}
+
+	if err := storage.RevokeUser(db, "did:plc:alice"); err != nil {
+		t.Fatalf("RevokeUser failed: %v", err)
+	}
+	if code := feed(); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after the user is revoked, got %d", code)
+	}
+	if tokens, err := storage.ListFeedTokens(db, "account-1"); err != nil || len(tokens) != 0 {
+		t.Errorf("Expected revoking to delete the account's feed tokens, got %d (err %v)", len(tokens), err)
+	}
+}
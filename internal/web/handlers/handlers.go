@@ -3,6 +3,8 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"html/template"
 	"log"
 	"net/http"
 	"os"
@@ -23,21 +25,30 @@ type Handlers struct {
 	db             *sql.DB
 	sessionManager *auth.SessionManager
 	oauthManager   *auth.OAuthManager
+	appPasswords   *auth.AppPasswordManager
 	worker         *archiver.Worker
 	logger         *log.Logger
+
+	exportTemplatesDir string // Directory of custom export templates; empty disables them
 }
 
 // New creates a new Handlers instance
-func New(db *sql.DB, sessionManager *auth.SessionManager, oauthManager *auth.OAuthManager, worker *archiver.Worker, logger *log.Logger) *Handlers {
+func New(db *sql.DB, sessionManager *auth.SessionManager, oauthManager *auth.OAuthManager, appPasswords *auth.AppPasswordManager, worker *archiver.Worker, logger *log.Logger) *Handlers {
 	return &Handlers{
 		db:             db,
 		sessionManager: sessionManager,
 		oauthManager:   oauthManager,
+		appPasswords:   appPasswords,
 		worker:         worker,
 		logger:         logger,
 	}
 }
 
+// SetExportTemplatesDir sets the directory custom export templates are listed from
+func (h *Handlers) SetExportTemplatesDir(dir string) {
+	h.exportTemplatesDir = dir
+}
+
 // Landing renders the landing page (check auth, redirect if authenticated)
 func (h *Handlers) Landing(w http.ResponseWriter, r *http.Request) {
 	// Check if user is already authenticated
@@ -80,6 +91,9 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 			Error:   "",
 			Message: "",
 		}
+		if r.URL.Query().Get("error") == "not_allowed" {
+			data.Error = notAllowedMessage
+		}
 		if err := h.renderTemplate(w, r, "login", data); err != nil {
 			h.logger.Printf("Error rendering login template: %v", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -124,6 +138,66 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, authURL, http.StatusSeeOther)
 }
 
+// LoginAppPassword signs in with a handle and app password
+// Unlike OAuth this needs no redirect back to the app, so it works without a public BASE_URL
+func (h *Handlers) LoginAppPassword(w http.ResponseWriter, r *http.Request) {
+	handle := r.FormValue("handle")
+	password := r.FormValue("app_password")
+
+	renderError := func(message string) {
+		data := TemplateData{
+			Error:  message,
+			Handle: handle, // Repopulate form; the password is never echoed back
+		}
+		if err := h.renderTemplate(w, r, "login", data); err != nil {
+			h.logger.Printf("Error rendering login template with error: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}
+
+	if h.appPasswords == nil {
+		renderError("App password login is not available.")
+		return
+	}
+	if handle == "" || password == "" {
+		renderError("Bluesky handle and app password are required")
+		return
+	}
+
+	login, err := h.appPasswords.Login(r.Context(), handle, password)
+	if err != nil {
+		h.logger.Printf("App password login failed for handle %s: %v", handle, err)
+		renderError("Sign-in failed. Check your handle and app password and try again.")
+		return
+	}
+
+	if err := h.sessionManager.SaveSession(w, r, login.DID, login.Handle, login.Handle, login.SessionID, ""); err != nil {
+		if errors.Is(err, auth.ErrLoginNotAllowed) {
+			h.discardAppPassword(login.SessionID)
+			renderError(notAllowedMessage)
+			return
+		}
+		h.logger.Printf("Failed to save app password session: %v", err)
+		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+// notAllowedMessage is shown when an identity is not on the access allowlist
+const notAllowedMessage = "This account is not allowed to sign in to this archive. Ask the administrator to add it to the allowlist."
+
+// alreadyLinkedMessage is shown when a link flow signs in as an identity of another account
+const alreadyLinkedMessage = "That Bluesky account is already linked to another account on this archive. Unlink it there first."
+
+// discardAppPassword removes the stored credential of a rejected app-password login
+func (h *Handlers) discardAppPassword(sessionID string) {
+	if err := h.appPasswords.Discard(sessionID); err != nil {
+		h.logger.Printf("Warning: failed to discard app password session: %v", err)
+	}
+}
+
 // Callback handles OAuth callback
 func (h *Handlers) Callback(w http.ResponseWriter, r *http.Request) {
 	h.oauthManager.HandleOAuthCallback(w, r)
@@ -151,9 +225,38 @@ func (h *Handlers) Dashboard(w http.ResponseWriter, r *http.Request) {
 		status = nil
 	}
 
+	// Fetch scheduled sync state (only shown when a run is planned or has happened)
+	schedule, err := storage.GetScheduleStatus(h.db, session.DID)
+	if err != nil {
+		h.logger.Printf("Error fetching schedule status: %v", err)
+		schedule = nil
+	}
+
+	// Fetch watchlist accounts (only shown when the watchlist is configured)
+	watched, err := storage.ListWatchedAccounts(h.db)
+	if err != nil {
+		h.logger.Printf("Error fetching watchlist: %v", err)
+		watched = nil
+	}
+
+	// Saved searches with unseen matches get a badge
+	searches, err := storage.ListSavedSearches(h.db, session.AccountID)
+	if err != nil {
+		h.logger.Printf("Error fetching saved searches: %v", err)
+		searches = nil
+	}
+	newMatches := 0
+	for _, search := range searches {
+		newMatches += search.NewMatches
+	}
+
 	data := TemplateData{
-		Session: session,
-		Status:  status,
+		Session:          session,
+		Status:           status,
+		Schedule:         schedule,
+		Watched:          watched,
+		SavedSearches:    searches,
+		NewSearchMatches: newMatches,
 	}
 
 	if err := h.renderTemplate(w, r, "dashboard", data); err != nil {
@@ -304,9 +407,13 @@ func (h *Handlers) Browse(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get query parameters
-	query := r.URL.Query().Get("q")
-	pageStr := r.URL.Query().Get("page")
-	showAll := r.URL.Query().Get("all") == "true"
+	params := r.URL.Query()
+	query := params.Get("q")
+	pageStr := params.Get("page")
+	combined := params.Get("view") == "combined"
+	viewDID := params.Get("did")
+	sort := browseSort(params.Get("sort"))
+	filter, filterError := parseBrowseFilter(params)
 
 	page := 1
 	if pageStr != "" {
@@ -321,92 +428,168 @@ func (h *Handlers) Browse(w http.ResponseWriter, r *http.Request) {
 	var posts []models.Post
 	var total int
 	var totalPages int
+	var prevPageURL, nextPageURL string
+	var searchError string
+	var elapsed string
+	var highlights, snippets map[string]template.HTML
 
-	// Determine which DID to filter by
-	filterDID := session.DID
-	if showAll {
-		filterDID = "" // Empty string means show all posts
+	// Watched accounts are listed so Browse can switch to them
+	watched, err := storage.ListWatchedAccounts(h.db)
+	if err != nil {
+		h.logger.Printf("Error fetching watchlist: %v", err)
+		watched = nil
 	}
 
-	// Fetch posts (search or list)
-	if query != "" {
-		// Search posts
-		result, err := storage.SearchPosts(h.db, filterDID, query, pageSize, offset)
+	// Determine which accounts to show: the active identity, a watched account,
+	// or every identity linked to the user's account (combined view)
+	filterDIDs := []string{session.DID}
+	var viewAccount *models.WatchedAccount
+	if combined {
+		linked, err := storage.ListAccountSessions(h.db, session.AccountID)
 		if err != nil {
+			h.logger.Printf("Error fetching linked accounts: %v", err)
+		} else if len(linked) > 0 {
+			filterDIDs = filterDIDs[:0]
+			for _, account := range linked {
+				filterDIDs = append(filterDIDs, account.DID)
+			}
+		}
+	} else if viewDID != "" && viewDID != session.DID {
+		// Only watched accounts can be browsed individually
+		for i := range watched {
+			if watched[i].DID == viewDID {
+				viewAccount = &watched[i]
+				break
+			}
+		}
+		if viewAccount == nil {
+			h.NotFound(w, r)
+			return
+		}
+		filterDIDs = []string{viewDID}
+	}
+
+	// Fetch posts (search or list)
+	if filterError != "" {
+		searchError = filterError
+		posts = []models.Post{}
+	} else if query != "" {
+		// Search posts; filters become search operators
+		result, err := storage.SearchPostsForDIDs(h.db, filterDIDs, searchFilterQuery(query, filter), models.SearchSort(sort), pageSize, offset)
+		var queryErr *storage.QueryError
+		if errors.As(err, &queryErr) {
+			searchError = queryErr.Message
+			posts = []models.Post{}
+		} else if err != nil {
 			h.logger.Printf("Error searching posts: %v", err)
 			posts = []models.Post{}
 		} else {
 			posts = result.Posts
 			total = result.Total
+			elapsed = result.Elapsed
+
+			// Highlights are escaped by storage; only <mark> tags are added
+			highlights = make(map[string]template.HTML, len(result.Highlights))
+			for uri, text := range result.Highlights {
+				highlights[uri] = template.HTML(text)
+			}
+			snippets = make(map[string]template.HTML, len(result.Snippets))
+			for uri, text := range result.Snippets {
+				snippets[uri] = template.HTML(text)
+			}
+		}
+
+		// Search results are ranked, so they keep page numbers
+		totalPages = (total + pageSize - 1) / pageSize
+		if page > 1 {
+			prevPageURL = browseURL(params, map[string]string{"page": strconv.Itoa(page - 1)})
+		}
+		if page < totalPages {
+			nextPageURL = browseURL(params, map[string]string{"page": strconv.Itoa(page + 1)})
 		}
 	} else {
-		// List all posts
-		result, err := storage.ListPosts(h.db, filterDID, pageSize, offset)
+		// List posts a page at a time from the keyset cursor
+		result, err := storage.ListPostsForDIDs(h.db, filterDIDs, models.PostListOptions{
+			Filter: filter,
+			Sort:   models.PostSort(sort),
+			Limit:  pageSize,
+			After:  params.Get("after"),
+			Before: params.Get("before"),
+		})
 		if err != nil {
 			h.logger.Printf("Error listing posts: %v", err)
 			posts = []models.Post{}
 		} else {
 			posts = result.Posts
 			total = result.Total
-			totalPages = result.TotalPages
+			if result.PrevCursor != "" {
+				prevPageURL = browseURL(params, map[string]string{"before": result.PrevCursor})
+			}
+			if result.NextCursor != "" {
+				nextPageURL = browseURL(params, map[string]string{"after": result.NextCursor})
+			}
 		}
 	}
 
-	// Calculate total pages if not already set
-	if totalPages == 0 && total > 0 {
-		totalPages = (total + pageSize - 1) / pageSize
+	// The date navigator spans the archived posts of the accounts shown
+	oldest, newest, err := storage.PostDateRange(h.db, filterDIDs)
+	if err != nil {
+		h.logger.Printf("Error fetching post date range: %v", err)
 	}
 
-	// Fetch media for all posts
-	mediaMap := make(map[string][]models.Media)
+	// Fetch media and reply parents for the whole page at once
+	uris := make([]string, 0, len(posts))
+	var parentURIs []string
 	for _, post := range posts {
-		media, err := storage.ListMediaForPost(h.db, post.URI)
-		if err != nil {
-			h.logger.Printf("Warning: failed to fetch media for post %s: %v", post.URI, err)
-			continue
-		}
-		if len(media) > 0 {
-			mediaMap[post.URI] = media
+		uris = append(uris, post.URI)
+		if post.IsReply && post.ReplyParent != "" {
+			parentURIs = append(parentURIs, post.ReplyParent)
 		}
 	}
 
-	// Check which parent posts exist in the archive
-	parentPostsInArchive := make(map[string]bool)
-	for _, post := range posts {
-		if post.IsReply && post.ReplyParent != "" {
-			_, err := storage.GetPost(h.db, post.ReplyParent)
-			if err == nil {
-				// Parent post exists in archive
-				parentPostsInArchive[post.ReplyParent] = true
-			}
-		}
+	mediaMap, err := storage.ListMediaForPosts(h.db, uris)
+	if err != nil {
+		h.logger.Printf("Warning: failed to fetch media for posts: %v", err)
+		mediaMap = map[string][]models.Media{}
 	}
 
-	// Fetch profiles for all DIDs in posts (for handle display)
-	profilesMap := make(map[string]string)
-	rows, err := h.db.Query("SELECT did, handle FROM profiles")
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var did, handle string
-			if err := rows.Scan(&did, &handle); err == nil {
-				profilesMap[did] = handle
-			}
-		}
+	// Check which parent posts exist in the archive
+	parentPostsInArchive, err := storage.PostsExist(h.db, parentURIs)
+	if err != nil {
+		h.logger.Printf("Warning: failed to check parent posts: %v", err)
+		parentPostsInArchive = map[string]bool{}
 	}
 
+	// Fetch handles for the DIDs on this page (for handle display)
+	profilesMap := h.profileHandles(posts)
+
 	data := TemplateData{
 		Session:              session,
 		Posts:                posts,
 		Media:                mediaMap,
 		ParentPostsInArchive: parentPostsInArchive,
 		Profiles:             profilesMap,
+		Error:                searchError,
 		Query:                query,
+		Sort:                 string(sort),
+		Elapsed:              elapsed,
+		Highlights:           highlights,
+		Snippets:             snippets,
+		SearchOperators:      storage.SearchOperators,
+		Filter:               filter,
+		FilterFrom:           params.Get("from"),
+		FilterTo:             params.Get("to"),
+		ArchiveYears:         browseYears(oldest, newest, params),
+		ClearFiltersURL:      browseURL(params, map[string]string{"media": "", "replies": "", "quotes": "", "from": "", "to": ""}),
 		Page:                 page,
 		Total:                total,
 		PageSize:             pageSize,
 		TotalPages:           totalPages,
-		ShowAll:              showAll,
+		PrevPageURL:          prevPageURL,
+		NextPageURL:          nextPageURL,
+		Combined:             combined,
+		Watched:              watched,
+		ViewAccount:          viewAccount,
 	}
 
 	if err := h.renderTemplate(w, r, "browse", data); err != nil {
@@ -437,6 +620,21 @@ func (h *Handlers) ServeMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only serve media from the user's own accounts or the watchlist
+	visible, err := storage.MediaVisibleTo(h.db, hash, h.visibleDIDs(session))
+	if err != nil {
+		h.logger.Printf("Error checking media access: %v", err)
+	}
+	if !visible {
+		http.Error(w, "Media not found", http.StatusNotFound)
+		return
+	}
+
+	h.serveMediaFile(w, r, media)
+}
+
+// serveMediaFile serves a media file once access has been checked
+func (h *Handlers) serveMediaFile(w http.ResponseWriter, r *http.Request, media *models.Media) {
 	// Validate the file path to prevent path traversal
 	// Get absolute path of the media file
 	absMediaPath, err := filepath.Abs(media.FilePath)
@@ -479,6 +677,60 @@ func (h *Handlers) ServeMedia(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, absMediaPath)
 }
 
+// visibleDIDs lists the archives a user may read: every identity linked to
+// their account plus watched accounts
+func (h *Handlers) visibleDIDs(session *models.Session) []string {
+	dids := []string{session.DID}
+
+	linked, err := storage.ListAccountSessions(h.db, session.AccountID)
+	if err != nil {
+		h.logger.Printf("Error fetching linked accounts: %v", err)
+	}
+	for _, account := range linked {
+		if account.DID != session.DID {
+			dids = append(dids, account.DID)
+		}
+	}
+
+	return append(dids, h.watchedDIDs()...)
+}
+
+// watchedDIDs lists the archives of the watchlist, which every signed-in user may read
+func (h *Handlers) watchedDIDs() []string {
+	watched, err := storage.ListWatchedAccounts(h.db)
+	if err != nil {
+		h.logger.Printf("Error fetching watchlist: %v", err)
+	}
+
+	var dids []string
+	for _, account := range watched {
+		if account.DID != "" {
+			dids = append(dids, account.DID)
+		}
+	}
+
+	return dids
+}
+
+// profileHandles maps the DIDs of posts to their latest archived handles
+func (h *Handlers) profileHandles(posts []models.Post) map[string]string {
+	seen := make(map[string]bool)
+	var dids []string
+	for _, post := range posts {
+		if !seen[post.DID] {
+			seen[post.DID] = true
+			dids = append(dids, post.DID)
+		}
+	}
+
+	handles, err := storage.LatestHandles(h.db, dids)
+	if err != nil {
+		h.logger.Printf("Warning: failed to fetch handles: %v", err)
+		return map[string]string{}
+	}
+	return handles
+}
+
 // ServeStatic serves static files with path traversal protection
 func (h *Handlers) ServeStatic(w http.ResponseWriter, r *http.Request) {
 	// Remove /static prefix
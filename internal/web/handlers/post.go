@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shindakun/bskyarchive/internal/auth"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// maxThreadDepth limits how many archived parents are shown above a reply
+const maxThreadDepth = 20
+
+// rkeyPattern matches AT Protocol record keys
+var rkeyPattern = regexp.MustCompile(`^[A-Za-z0-9._:~-]{1,512}$`)
+
+// Post renders the permalink page of one archived post
+func (h *Handlers) Post(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+		return
+	}
+
+	post, dids, ok := h.postParam(w, r, session)
+	if !ok {
+		return
+	}
+
+	ancestors, err := storage.ListThreadAncestors(h.db, post.URI, dids, maxThreadDepth)
+	if err != nil {
+		h.logger.Printf("Error listing thread for %s: %v", post.URI, err)
+	}
+	replies, err := storage.ListReplies(h.db, post.URI, dids, 0)
+	if err != nil {
+		h.logger.Printf("Error listing replies to %s: %v", post.URI, err)
+	}
+	engagement, err := storage.ListPostEngagement(h.db, post.URI)
+	if err != nil {
+		h.logger.Printf("Error listing engagement for %s: %v", post.URI, err)
+	}
+
+	// Media for the post and its thread, fetched at once
+	thread := append(append([]models.Post{*post}, ancestors...), replies...)
+	uris := make([]string, 0, len(thread))
+	for _, p := range thread {
+		uris = append(uris, p.URI)
+	}
+	mediaMap, err := storage.ListMediaForPosts(h.db, uris)
+	if err != nil {
+		h.logger.Printf("Warning: failed to fetch media for posts: %v", err)
+		mediaMap = map[string][]models.Media{}
+	}
+
+	// Link the quoted post to its own page when it is archived and visible
+	quote := post.Quote()
+	quoteInArchive := false
+	if quote != nil && containsDID(dids, quote.DID) {
+		existing, err := storage.PostsExist(h.db, []string{quote.URI})
+		if err != nil {
+			h.logger.Printf("Warning: failed to check quoted post: %v", err)
+		}
+		quoteInArchive = existing[quote.URI]
+	}
+
+	profiles := h.profileHandles(thread)
+	raw, err := json.MarshalIndent(post, "", "  ")
+	if err != nil {
+		h.logger.Printf("Error encoding post %s: %v", post.URI, err)
+	}
+
+	data := TemplateData{
+		Session:        session,
+		Post:           post,
+		Quote:          quote,
+		QuoteInArchive: quoteInArchive,
+		Thread:         ancestors,
+		Replies:        replies,
+		Engagement:     engagement,
+		Media:          mediaMap,
+		Profiles:       profiles,
+		PostMarkdown:   post.Markdown(profiles[post.DID], mediaMap[post.URI]),
+		PostJSON:       string(raw),
+	}
+
+	if err := h.renderTemplate(w, r, "post", data); err != nil {
+		h.logger.Printf("Error rendering post template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// PostJSON downloads one archived post with its media metadata and engagement history
+func (h *Handlers) PostJSON(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	post, _, ok := h.postParam(w, r, session)
+	if !ok {
+		return
+	}
+
+	download := models.PostDownload{Post: post}
+	var err error
+	if download.Media, err = storage.ListMediaForPost(h.db, post.URI); err != nil {
+		h.logger.Printf("Warning: failed to fetch media for post %s: %v", post.URI, err)
+	}
+	if download.Engagement, err = storage.ListPostEngagement(h.db, post.URI); err != nil {
+		h.logger.Printf("Warning: failed to fetch engagement for post %s: %v", post.URI, err)
+	}
+
+	filename := fmt.Sprintf("bsky-post-%s.json", post.RKey())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(download); err != nil {
+		h.logger.Printf("Error writing post JSON: %v", err)
+	}
+}
+
+// postParam loads the post named by the {did} and {rkey} URL parameters, with the
+// DIDs the user may read. Posts outside the user's archives are reported as not found
+func (h *Handlers) postParam(w http.ResponseWriter, r *http.Request, session *models.Session) (*models.Post, []string, bool) {
+	did := chi.URLParam(r, "did")
+	rkey := chi.URLParam(r, "rkey")
+	if !strings.HasPrefix(did, "did:") || !rkeyPattern.MatchString(rkey) {
+		h.NotFound(w, r)
+		return nil, nil, false
+	}
+
+	dids := h.visibleDIDs(session)
+	if !containsDID(dids, did) {
+		h.NotFound(w, r)
+		return nil, nil, false
+	}
+
+	post, err := storage.GetPost(h.db, "at://"+did+"/app.bsky.feed.post/"+rkey)
+	if err != nil {
+		h.NotFound(w, r)
+		return nil, nil, false
+	}
+
+	return post, dids, true
+}
+
+// containsDID reports whether did is one of dids
+func containsDID(dids []string, did string) bool {
+	for _, d := range dids {
+		if d == did {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shindakun/bskyarchive/internal/auth"
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// savedSearchPageSize is the number of matches shown for a saved search
+const savedSearchPageSize = 50
+
+// SavedSearches lists the account's saved searches with their new match counts
+func (h *Handlers) SavedSearches(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+		return
+	}
+
+	h.renderSavedSearches(w, r, session, "", "")
+}
+
+// renderSavedSearches renders the saved searches page with an optional error or message
+func (h *Handlers) renderSavedSearches(w http.ResponseWriter, r *http.Request, session *models.Session, errMessage, message string) {
+	searches, err := storage.ListSavedSearches(h.db, session.AccountID)
+	if err != nil {
+		h.logger.Printf("Error listing saved searches: %v", err)
+	}
+
+	data := TemplateData{
+		Session:       session,
+		Error:         errMessage,
+		Message:       message,
+		SavedSearches: searches,
+	}
+
+	if err := h.renderTemplate(w, r, "searches", data); err != nil {
+		h.logger.Printf("Error rendering searches template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// SaveSearch saves the query from the Browse page for new-match alerts
+func (h *Handlers) SaveSearch(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	search, err := storage.SaveSearch(h.db, session.AccountID, r.FormValue("name"), r.FormValue("q"))
+	var queryErr *storage.QueryError
+	if errors.As(err, &queryErr) {
+		h.renderSavedSearches(w, r, session, queryErr.Message, "")
+		return
+	}
+	if err != nil {
+		h.logger.Printf("Failed to save search for %s: %v", session.DID, err)
+		h.renderSavedSearches(w, r, session, "Could not save the search.", "")
+		return
+	}
+
+	h.renderSavedSearches(w, r, session, "", "Saved \""+search.DisplayName()+"\". New matches will show up after each archive run.")
+}
+
+// SavedSearch shows the latest matches of a saved search and marks new ones as seen
+func (h *Handlers) SavedSearch(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+		return
+	}
+
+	search, ok := h.savedSearchParam(w, r, session)
+	if !ok {
+		return
+	}
+
+	dids := h.visibleDIDs(session)
+	var searchError string
+	var posts []models.Post
+	result, err := storage.SearchPostsForDIDs(h.db, dids, search.Query, models.SearchSortNewest, savedSearchPageSize, 0)
+	var queryErr *storage.QueryError
+	if errors.As(err, &queryErr) {
+		searchError = queryErr.Message
+	} else if err != nil {
+		h.logger.Printf("Error running saved search %d: %v", search.ID, err)
+	} else {
+		posts = result.Posts
+	}
+
+	// Matches archived since the last visit are marked as new
+	newMatches := make(map[string]bool)
+	if search.CheckedRowID > search.SeenRowID {
+		unseen, _, err := storage.NewSavedSearchMatches(h.db, dids, search.Query, search.SeenRowID, search.CheckedRowID, savedSearchPageSize)
+		if err != nil {
+			h.logger.Printf("Error finding new matches for saved search %d: %v", search.ID, err)
+		}
+		for _, post := range unseen {
+			newMatches[post.URI] = true
+		}
+	}
+
+	if err := storage.MarkSavedSearchSeen(h.db, session.AccountID, search.ID); err != nil {
+		h.logger.Printf("Warning: %v", err)
+	}
+
+	data := TemplateData{
+		Session:     session,
+		Error:       searchError,
+		SavedSearch: search,
+		Posts:       posts,
+		NewMatches:  newMatches,
+		Profiles:    h.profileHandles(posts),
+	}
+	if result != nil {
+		data.Total = result.Total
+	}
+
+	if err := h.renderTemplate(w, r, "saved-search", data); err != nil {
+		h.logger.Printf("Error rendering saved search template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// DeleteSavedSearch removes a saved search
+func (h *Handlers) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	session, ok := auth.GetSessionFromContext(r.Context())
+	if !ok || session == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	search, ok := h.savedSearchParam(w, r, session)
+	if !ok {
+		return
+	}
+
+	if err := storage.DeleteSavedSearch(h.db, session.AccountID, search.ID); err != nil {
+		h.logger.Printf("Failed to delete saved search %d: %v", search.ID, err)
+		h.renderSavedSearches(w, r, session, "Could not delete the search.", "")
+		return
+	}
+
+	h.renderSavedSearches(w, r, session, "", "Deleted \""+search.DisplayName()+"\".")
+}
+
+// savedSearchParam loads the saved search named by the {id} URL parameter
+// Searches of other accounts are reported as not found
+func (h *Handlers) savedSearchParam(w http.ResponseWriter, r *http.Request, session *models.Session) (*models.SavedSearch, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.NotFound(w, r)
+		return nil, false
+	}
+
+	search, err := storage.GetSavedSearch(h.db, session.AccountID, id)
+	if err != nil {
+		h.NotFound(w, r)
+		return nil, false
+	}
+
+	return search, true
+}
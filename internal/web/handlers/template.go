@@ -7,7 +7,9 @@ import (
 	"strings"
 
 	"github.com/gorilla/csrf"
+	"github.com/shindakun/bskyarchive/internal/exporter"
 	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
 )
 
 // TemplateData holds common data passed to templates
@@ -17,18 +19,54 @@ type TemplateData struct {
 	Handle  string // For login form - repopulates handle after validation errors
 	Session interface{}
 	Status  *models.ArchiveStatus
+	Schedule *models.ScheduleStatus // Scheduled sync state for the dashboard
 	Posts   []models.Post
 	Media   map[string][]models.Media // Map of post URI to media items
 	ParentPostsInArchive map[string]bool // Map of parent URIs that exist in local archive
 	Profiles map[string]string // Map of DID to handle
 	Exports []models.ExportRecord // List of exports for export management page
 	Query   string
+	Sort    string // Search result order (newest or relevance)
+	Elapsed string // Search duration
+	Highlights map[string]template.HTML // Map of post URI to text with search matches marked
+	Snippets map[string]template.HTML // Map of post URI to an excerpt from the best matching field (alt text, link, quote)
+	SearchOperators []string // Search filters for the Browse help text
+	SavedSearches []models.SavedSearch // Saved searches of the signed-in account
+	SavedSearch *models.SavedSearch // Saved search whose matches are shown
+	NewMatches map[string]bool // Map of post URIs that are new matches of the saved search
+	NewSearchMatches int // Unseen saved search matches for the dashboard badge
+	FeedTokens []models.FeedToken // Feed tokens of the signed-in account
+	BaseURL string // Scheme and host of the request, for feed addresses
+	ExportTemplates []exporter.ExportTemplate // Custom export templates on the export page
+	CSVColumns []models.CSVColumn // Columns offered for CSV exports
+	Post *models.Post // Post shown on its permalink page
+	Quote *models.QuotedPost // Post quoted by Post
+	QuoteInArchive bool // Quoted post is archived and has its own page
+	Thread []models.Post // Archived posts above Post in its thread, root first
+	Replies []models.Post // Archived replies to Post
+	Engagement []models.EngagementSnapshot // Engagement history of Post
+	PostMarkdown string // Post formatted as Markdown for copying
+	PostJSON string // Archived record of Post
+	Filter models.PostFilter // Browse filters
+	FilterFrom string // Browse date range start (YYYY-MM-DD, inclusive)
+	FilterTo string // Browse date range end (YYYY-MM-DD, inclusive)
+	ArchiveYears []browseYear // Browse date navigator
+	ClearFiltersURL string // Browse link without filters
 	Page    int
 	Total   int
 	PageSize int
-	TotalPages int
+	TotalPages int // Set for search results only; post lists page by cursor
+	PrevPageURL string
+	NextPageURL string
 	HasActiveOperation bool
-	ShowAll bool // Show all posts from all users
+	Combined bool // Browse posts from every identity linked to the account
+	LinkedAccounts []models.Session // Identities linked to the signed-in account (account switcher)
+	AccountStatuses map[string]*models.ArchiveStatus // Map of linked DID to archive status
+	Watched []models.WatchedAccount // Public accounts archived through the watchlist
+	ViewAccount *models.WatchedAccount // Watched account shown in Browse instead of the user's own posts
+	IsAdmin bool // Signed-in identity has the admin role
+	InstanceStats *models.InstanceStats // Instance-wide statistics for the admin page
+	Users []models.UserSummary // Signed-in identities for the admin page
 	Version string // Application version
 	CSRFToken string // CSRF token for forms and HTMX requests
 }
@@ -51,6 +89,14 @@ func templateFuncs() template.FuncMap {
 			}
 			return ""
 		},
+		"permalink": func(uri string) string {
+			// Convert at://did/app.bsky.feed.post/rkey to the archive's /post/did/rkey page
+			parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+			if len(parts) != 3 {
+				return ""
+			}
+			return "/post/" + parts[0] + "/" + parts[2]
+		},
 		"extractDID": func(uri string) string {
 			// Extract DID from AT URI
 			// Format: at://did:plc:xxx/app.bsky.feed.post/xxxxx
@@ -100,6 +146,18 @@ func (h *Handlers) renderTemplate(w http.ResponseWriter, r *http.Request, templa
 	// Add CSRF token to template data
 	data.CSRFToken = csrf.Token(r)
 
+	// Linked identities feed the account switcher in the nav
+	if session, ok := data.Session.(*models.Session); ok && session != nil && data.LinkedAccounts == nil && h.db != nil {
+		linked, err := storage.ListAccountSessions(h.db, session.AccountID)
+		if err != nil {
+			h.logger.Printf("Warning: failed to list linked accounts: %v", err)
+		}
+		data.LinkedAccounts = linked
+	}
+	if session, ok := data.Session.(*models.Session); ok && session != nil {
+		data.IsAdmin = h.sessionManager.IsAdmin(session.DID)
+	}
+
 	// Parse templates with functions - include partials for templates that need them
 	files := []string{
 		filepath.Join("internal", "web", "templates", "layouts", "base.html"),
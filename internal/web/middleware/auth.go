@@ -33,3 +33,19 @@ func RequireAuth(sessionManager *auth.SessionManager) func(next http.Handler) ht
 		})
 	}
 }
+
+// RequireAdmin is a middleware that requires the admin role
+// Must run after RequireAuth; other users get 403 Forbidden
+func RequireAdmin(sessionManager *auth.SessionManager) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, ok := auth.GetSessionFromContext(r.Context())
+			if !ok || session == nil || !sessionManager.IsAdmin(session.DID) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
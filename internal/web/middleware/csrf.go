@@ -22,9 +22,10 @@ func CSRFProtection(secret []byte, secure bool) func(http.Handler) http.Handler
 	// The OAuth login form doesn't include CSRF tokens (simple HTML form)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Exempt /auth/login from CSRF validation entirely
+			// Exempt the OAuth login form submission from CSRF validation
 			// OAuth flow uses its own state parameter for protection
-			if r.URL.Path == "/auth/login" {
+			// GET still passes through so the login page gets a token for the app-password form
+			if r.URL.Path == "/auth/login" && r.Method == http.MethodPost {
 				next.ServeHTTP(w, r)
 				return
 			}
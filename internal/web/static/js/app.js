@@ -11,6 +11,25 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
+    // Copy the text of another element to the clipboard (e.g. "Copy as Markdown")
+    document.querySelectorAll('[data-copy-target]').forEach(function(btn) {
+        btn.addEventListener('click', function() {
+            const target = document.getElementById(btn.getAttribute('data-copy-target'));
+            if (!target) {
+                return;
+            }
+            const label = btn.textContent;
+            navigator.clipboard.writeText(target.value || target.textContent).then(function() {
+                btn.textContent = 'Copied!';
+            }, function() {
+                // Clipboard unavailable (e.g. plain HTTP): show the text to copy by hand
+                target.hidden = false;
+                target.select();
+            });
+            setTimeout(function() { btn.textContent = label; }, 2000);
+        });
+    });
+
     // Add confirmation to any future destructive actions
     document.querySelectorAll('[data-confirm]').forEach(function(el) {
         el.addEventListener('htmx:confirm', function(e) {
@@ -64,7 +64,9 @@ func initSchema(db *sql.DB) error {
 			embed_type TEXT,
 			embed_data JSON,
 			labels JSON,
-			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			langs JSON,
+			facets JSON
 		);
 
 		CREATE INDEX IF NOT EXISTS idx_posts_did ON posts(did);
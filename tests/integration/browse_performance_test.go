@@ -0,0 +1,100 @@
+package integration
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+	"github.com/shindakun/bskyarchive/internal/storage"
+)
+
+// BenchmarkBrowsePage measures the storage work behind one Browse page: a page of posts,
+// their media, reply parents and handles, with an archive of 10,000 posts and a long
+// profile snapshot history
+func BenchmarkBrowsePage(b *testing.B) {
+	db, err := storage.InitDB(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	testDID := "did:plc:browsebench"
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tx, err := db.Begin()
+	if err != nil {
+		b.Fatalf("Failed to begin transaction: %v", err)
+	}
+	for i := 0; i < 10000; i++ {
+		createdAt := baseTime.Add(time.Duration(i) * time.Minute)
+		uri := fmt.Sprintf("at://%s/app.bsky.feed.post/%06d", testDID, i)
+		isReply := i%3 == 0
+		replyParent := ""
+		if isReply {
+			replyParent = fmt.Sprintf("at://%s/app.bsky.feed.post/%06d", testDID, i-1)
+		}
+		_, err := tx.Exec(`INSERT INTO posts (uri, cid, did, text, created_at, indexed_at, has_media, is_reply, reply_parent, embed_type)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, '')`,
+			uri, fmt.Sprintf("cid%d", i), testDID, fmt.Sprintf("Benchmark post %d", i), createdAt, createdAt, i%4 == 0, isReply, replyParent)
+		if err != nil {
+			b.Fatalf("Failed to insert post: %v", err)
+		}
+		if i%4 == 0 {
+			_, err := tx.Exec(`INSERT INTO media (hash, post_uri, mime_type, file_path, size_bytes, width, height, alt_text, created_at)
+				VALUES (?, ?, 'image/jpeg', ?, 1024, 0, 0, '', ?)`,
+				fmt.Sprintf("%064d", i), uri, fmt.Sprintf("media/%d.jpg", i), createdAt)
+			if err != nil {
+				b.Fatalf("Failed to insert media: %v", err)
+			}
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		_, err := tx.Exec("INSERT INTO profiles (did, handle, snapshot_at) VALUES (?, ?, ?)",
+			fmt.Sprintf("did:plc:other%d", i%100), fmt.Sprintf("other%d.test", i), baseTime.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			b.Fatalf("Failed to insert profile: %v", err)
+		}
+	}
+	if _, err := tx.Exec("INSERT INTO profiles (did, handle, snapshot_at) VALUES (?, 'bench.test', ?)", testDID, baseTime); err != nil {
+		b.Fatalf("Failed to insert profile: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatalf("Failed to commit: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := storage.ListPostsForDIDs(db, []string{testDID}, models.PostListOptions{Limit: 20})
+		if err != nil {
+			b.Fatalf("ListPostsForDIDs failed: %v", err)
+		}
+
+		var uris, parents []string
+		for _, post := range result.Posts {
+			uris = append(uris, post.URI)
+			if post.IsReply {
+				parents = append(parents, post.ReplyParent)
+			}
+		}
+
+		media, err := storage.ListMediaForPosts(db, uris)
+		if err != nil {
+			b.Fatalf("ListMediaForPosts failed: %v", err)
+		}
+		existing, err := storage.PostsExist(db, parents)
+		if err != nil {
+			b.Fatalf("PostsExist failed: %v", err)
+		}
+		handles, err := storage.LatestHandles(db, []string{testDID})
+		if err != nil {
+			b.Fatalf("LatestHandles failed: %v", err)
+		}
+
+		if len(result.Posts) != 20 || len(media) != 5 || len(existing) != len(parents) || handles[testDID] != "bench.test" {
+			b.Fatalf("Unexpected page: %d posts, %d with media, %d of %d parents, handle %q",
+				len(result.Posts), len(media), len(existing), len(parents), handles[testDID])
+		}
+	}
+}
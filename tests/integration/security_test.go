@@ -539,7 +539,7 @@ func setupTestServerWithStaticFiles(t *testing.T) (*httptest.Server, func()) {
 	}
 
 	logger := log.New(os.Stdout, "", log.LstdFlags)
-	h := handlers.New(db, nil, nil, nil, logger)
+	h := handlers.New(db, nil, nil, nil, nil, logger)
 
 	// Setup router with static file serving
 	r := chi.NewRouter()
@@ -669,7 +669,7 @@ func TestPathTraversalLogging(t *testing.T) {
 	var logBuf bytes.Buffer
 	logger := log.New(&logBuf, "", 0)
 
-	h := handlers.New(db, nil, nil, nil, logger)
+	h := handlers.New(db, nil, nil, nil, nil, logger)
 
 	// Setup router
 	r := chi.NewRouter()
@@ -190,7 +190,7 @@ func TestExportRecordValidation(t *testing.T) {
 				DirectoryPath: "./exports/test",
 			},
 			wantError: true,
-			errorMsg:  "format must be 'json' or 'csv'",
+			errorMsg:  "format must be one of",
 		},
 		{
 			name: "negative post count",
@@ -0,0 +1,102 @@
+package unit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shindakun/bskyarchive/internal/models"
+)
+
+// TestPostQuoteAndLink reads quotes and link cards from stored embed views
+func TestPostQuoteAndLink(t *testing.T) {
+	quoted := `{"$type":"app.bsky.embed.record#viewRecord","uri":"at://did:plc:friend/app.bsky.feed.post/abc",
+		"author":{"did":"did:plc:friend","handle":"friend.test"},
+		"value":{"$type":"app.bsky.feed.post","text":"Original thought","createdAt":"2024-03-01T10:00:00Z"}}`
+
+	tests := []struct {
+		name      string
+		embedType string
+		embed     string
+		quoteText string
+		linkURI   string
+	}{
+		{"quote", "record", `{"record":` + quoted + `}`, "Original thought", ""},
+		{"quote with media", "record_with_media", `{"record":{"record":` + quoted + `},"media":{"images":[]}}`, "Original thought", ""},
+		{"link", "external", `{"external":{"uri":"https://example.com","title":"Example","description":"A site"}}`, "", "https://example.com"},
+		{"images", "images", `{"images":[{"alt":"A cat"}]}`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := models.Post{EmbedType: tt.embedType, EmbedData: json.RawMessage(tt.embed)}
+
+			quote := post.Quote()
+			if tt.quoteText == "" {
+				if quote != nil {
+					t.Errorf("Expected no quote, got %+v", quote)
+				}
+			} else if quote == nil || quote.Text != tt.quoteText || quote.Handle != "friend.test" {
+				t.Errorf("Unexpected quote: %+v", quote)
+			}
+
+			link := post.Link()
+			if tt.linkURI == "" {
+				if link != nil {
+					t.Errorf("Expected no link, got %+v", link)
+				}
+			} else if link == nil || link.URI != tt.linkURI || link.Title != "Example" {
+				t.Errorf("Unexpected link: %+v", link)
+			}
+		})
+	}
+}
+
+// TestPostMarkdown checks the "copy as Markdown" text
+func TestPostMarkdown(t *testing.T) {
+	post := models.Post{
+		URI:       "at://did:plc:me/app.bsky.feed.post/3k2a",
+		DID:       "did:plc:me",
+		Text:      "Look at this",
+		CreatedAt: time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+		Labels:    json.RawMessage(`[{"val":"nudity"},{"val":"spam","neg":true}]`),
+	}
+	media := []models.Media{{AltText: "A sunset"}}
+
+	markdown := post.Markdown("me.test", media)
+	for _, want := range []string{
+		"**@me.test** · Mar 1, 2024 09:30 UTC",
+		"Look at this",
+		"*Image: A sunset*",
+		"[View on Bluesky](https://bsky.app/profile/did:plc:me/post/3k2a)",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Markdown missing %q:\n%s", want, markdown)
+		}
+	}
+
+	if labels := post.LabelValues(); len(labels) != 1 || labels[0] != "nudity" {
+		t.Errorf("Unexpected labels: %v", labels)
+	}
+	if path := post.PermalinkPath(); path != "/post/did:plc:me/3k2a" {
+		t.Errorf("PermalinkPath() = %q", path)
+	}
+}
+
+// TestPostTags reads hashtags from stored facets, ignoring links and mentions
+func TestPostTags(t *testing.T) {
+	post := models.Post{Facets: json.RawMessage(`[
+		{"features":[{"$type":"app.bsky.richtext.facet#tag","tag":"garden"}]},
+		{"features":[{"$type":"app.bsky.richtext.facet#link","uri":"https://example.com"}]},
+		{"features":[{"$type":"app.bsky.richtext.facet#mention","did":"did:plc:friend"}]},
+		{"features":[{"$type":"app.bsky.richtext.facet#tag","tag":"garden"},{"$type":"app.bsky.richtext.facet#tag","tag":"spring"}]}
+	]`)}
+
+	if tags := post.Tags(); strings.Join(tags, ",") != "garden,spring" {
+		t.Errorf("Tags() = %v", tags)
+	}
+	if tags := (&models.Post{}).Tags(); tags != nil {
+		t.Errorf("Expected no tags, got %v", tags)
+	}
+}
@@ -112,7 +112,7 @@ func TestListPosts(t *testing.T) {
 	}
 
 	// Test ListPosts with pagination
-	resp, err := storage.ListPosts(db, testDID, 10, 0)
+	resp, err := storage.ListPosts(db, testDID, models.PostListOptions{Limit: 10})
 	if err != nil {
 		t.Fatalf("ListPosts failed: %v", err)
 	}
@@ -289,7 +289,7 @@ func TestPostUpsert(t *testing.T) {
 	}
 
 	// Verify we still only have one post (not duplicated)
-	resp, err := storage.ListPosts(db, "did:plc:test123", 10, 0)
+	resp, err := storage.ListPosts(db, "did:plc:test123", models.PostListOptions{Limit: 10})
 	if err != nil {
 		t.Fatalf("Failed to list posts: %v", err)
 	}